@@ -0,0 +1,68 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//+kubebuilder:object:generate=true
+
+// GeneratorPluginSpec defines how to reach an out-of-process generator plugin
+type GeneratorPluginSpec struct {
+	// Address of the plugin gRPC server, unix:///path/to/plugin.sock or host:port
+	// +kubebuilder:validation:MinLength=1
+	Address string `json:"address"`
+	// Timeout for each Generate call (defaults to 10s)
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// CAFile is a CA bundle in the operator pod used to verify the plugin TLS certificate.
+	// Without it the connection is plaintext.
+	CAFile string `json:"caFile,omitempty"`
+	// ServerName overrides the name used to verify the plugin TLS certificate
+	ServerName string `json:"serverName,omitempty"`
+	// Insecure allows plaintext connections to addresses other than unix sockets and loopback
+	// +kubebuilder:default=false
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+//+kubebuilder:object:generate=true
+
+// GeneratorPluginType describes a generator type registered by a plugin
+type GeneratorPluginType struct {
+	// Type is the generator type used in SecretSanta generators
+	Type string `json:"type"`
+	// Description of the generator type as reported by the plugin
+	Description string `json:"description,omitempty"`
+}
+
+// GeneratorPluginStatus defines the observed state of GeneratorPlugin
+type GeneratorPluginStatus struct {
+	// Types are the generator types registered from this plugin
+	Types []GeneratorPluginType `json:"types,omitempty"`
+	// LastProbeTime is the time of the last health check
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+	// Conditions represent the current state of the GeneratorPlugin resource
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,shortName=gp
+//+kubebuilder:printcolumn:name="Address",type=string,JSONPath=`.spec.address`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+
+// GeneratorPlugin registers the generator types of an out-of-process plugin
+type GeneratorPlugin struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GeneratorPluginSpec   `json:"spec,omitempty"`
+	Status GeneratorPluginStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GeneratorPluginList contains a list of GeneratorPlugin
+type GeneratorPluginList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GeneratorPlugin `json:"items"`
+}
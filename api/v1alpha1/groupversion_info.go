@@ -16,4 +16,5 @@ var (
 
 func init() {
 	SchemeBuilder.Register(&SecretSanta{}, &SecretSantaList{})
+	SchemeBuilder.Register(&GeneratorPlugin{}, &GeneratorPluginList{})
 }
@@ -19,10 +19,9 @@ type DryRunResult struct {
 // MediaConfig defines configuration for secret storage destinations
 type MediaConfig struct {
 	// Type specifies the storage backend
-	// Supported types: k8s, aws-secrets-manager, aws-parameter-store, azure-key-vault, gcp-secret-manager
+	// Supported types: k8s, aws-secrets-manager, aws-parameter-store, azure-key-vault, gcp-secret-manager, vault-kv, oci-vault, s3-object, file, git-sops, sealed-secret
 	// +kubebuilder:validation:MinLength=1
-	// amazonq-ignore-next-line
-	// +kubebuilder:validation:Enum=k8s;aws-secrets-manager;aws-parameter-store;azure-key-vault;gcp-secret-manager
+	// +kubebuilder:validation:Enum=k8s;aws-secrets-manager;aws-parameter-store;azure-key-vault;gcp-secret-manager;vault-kv;oci-vault;s3-object;file;git-sops;sealed-secret
 	Type string `json:"type"`
 	// Config contains storage backend specific configuration parameters
 	Config *runtime.RawExtension `json:"config,omitempty"`
@@ -39,11 +38,16 @@ type GeneratorConfig struct {
 	// Supported types: random_password, random_string, random_uuid, random_bytes,
 	// random_integer, random_id, tls_private_key, tls_self_signed_cert,
 	// tls_cert_request, tls_locally_signed_cert, crypto_aes_key, crypto_rsa_key,
-	// crypto_ed25519_key, crypto_hmac
+	// crypto_ed25519_key, crypto_hmac, wireguard_keypair, age_identity,
+	// openpgp_key, totp_seed, random_ulid, random_ksuid, random_nanoid, app_secret,
+	// db_credentials, crypto_hkdf, shamir_split, k8s_encryption_config,
+	// time_rotating, crypto_mlkem_key, crypto_hybrid_kem_key, ssh_host_keys,
+	// api_token, script
+	// Types registered by generator plugins are accepted as well, so unknown types are rejected by the
+	// controller rather than the API server
 	// +kubebuilder:validation:MinLength=1
-	// +kubebuilder:validation:Enum=random_password;random_string;random_uuid;random_bytes;random_integer;random_id;\
-	//   tls_private_key;tls_self_signed_cert;tls_cert_request;tls_locally_signed_cert;\
-	//   crypto_aes_key;crypto_rsa_key;crypto_ed25519_key;crypto_hmac
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z][a-z0-9_.-]*$`
 	Type string `json:"type"`
 	// Config contains generator-specific configuration parameters
 	Config *runtime.RawExtension `json:"config,omitempty"`
@@ -65,11 +69,9 @@ type SecretSantaSpec struct {
 	// +kubebuilder:validation:MinLength=1
 	// +kubebuilder:validation:MaxLength=253
 	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
-	// amazonq-ignore-next-line
 	SecretName string `json:"secretName,omitempty"`
 	// SecretType sets the Kubernetes secret type
 	// +kubebuilder:default="Opaque"
-	// amazonq-ignore-next-line
 	SecretType string `json:"secretType,omitempty"`
 	// Labels to apply to the generated secret
 	Labels map[string]string `json:"labels,omitempty"`
@@ -88,6 +90,9 @@ type SecretSantaStatus struct {
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 	// DryRunResult contains the masked output from dry-run executions
 	DryRunResult *DryRunResult `json:"dryRunResult,omitempty"`
+	// NextRotation is the earliest rotation time of the time_rotating generators and of the certificates
+	// with early_renewal_hours, when the secret is regenerated
+	NextRotation *metav1.Time `json:"nextRotation,omitempty"`
 }
 
 //+kubebuilder:object:root=true
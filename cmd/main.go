@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
@@ -20,6 +21,8 @@ import (
 	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
 	"github.com/logicIQ/secret-santa/internal/config"
 	"github.com/logicIQ/secret-santa/internal/controller"
+	"github.com/logicIQ/secret-santa/pkg/generators/plugin"
+	"github.com/logicIQ/secret-santa/pkg/media/k8s"
 )
 
 var (
@@ -58,10 +61,22 @@ func execute() error {
 	rootCmd.Flags().StringSlice("include-labels", []string{}, "Comma-separated list of labels to include.")
 	rootCmd.Flags().StringSlice("exclude-labels", []string{}, "Comma-separated list of labels to exclude.")
 	rootCmd.Flags().Bool("dry-run", false, "Enable dry-run mode (validate templates without creating secrets).")
+	rootCmd.Flags().String("insecure-deterministic", "", "Seed for reproducible dry-run output. INSECURE, requires --dry-run.")
+	rootCmd.Flags().String("generator-plugins-config", "", "Path to a YAML file listing out-of-process generator plugins.")
+	rootCmd.Flags().Bool("s3-ambient-credentials", false, "Let the s3-object media use the AWS credentials of the operator when a SecretSanta sets none.")
+	rootCmd.Flags().String("file-media-root", "", "Directory the file media writes below. The file media is disabled if empty.")
+	rootCmd.Flags().String("git-repository-root", "", "Directory local git-sops repositories must be in. Local repositories are disabled if empty.")
+	rootCmd.Flags().String("vault-token-audience", "vault", "Audience of the ServiceAccount tokens requested for vault-kv kubernetes auth.")
+	rootCmd.Flags().Float64("remote-cluster-qps", 5, "Requests per second from each namespace to each remote cluster of the k8s media.")
+	rootCmd.Flags().Int("remote-cluster-burst", 10, "Request burst from each namespace to each remote cluster of the k8s media.")
 	rootCmd.Flags().Bool("enable-metadata", true, "Enable metadata annotations/tags on generated secrets.")
 	rootCmd.Flags().String("log-format", "json", "Log format: json or console")
 	rootCmd.Flags().String("log-level", "info", "Log level: debug, info, warn, error")
 
+	rootCmd.AddCommand(newShamirCommand())
+	rootCmd.AddCommand(newTokenCommand())
+	rootCmd.AddCommand(newRenderCommand())
+
 	viper.AutomaticEnv()
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 
@@ -75,6 +90,9 @@ func execute() error {
 func runController(cmd *cobra.Command, args []string) error {
 	setupLogger()
 	cfg := loadConfig()
+	if cfg.InsecureDeterministic != "" && !cfg.DryRun {
+		return fmt.Errorf("--insecure-deterministic requires --dry-run")
+	}
 	mgr, err := createManager(cfg)
 	if err != nil {
 		return err
@@ -122,6 +140,9 @@ func setupLogger() {
 	if dryRun {
 		setupLog.Info("Starting in DRY RUN mode - no secrets will be created")
 	}
+	if viper.GetString("insecure-deterministic") != "" {
+		setupLog.Info("WARNING: insecure deterministic entropy enabled - generated values are predictable")
+	}
 }
 
 // sanitizeLogValue removes control characters to prevent log injection
@@ -163,15 +184,42 @@ func createManager(cfg *config.Config) (ctrl.Manager, error) {
 }
 
 func setupController(mgr ctrl.Manager, cfg *config.Config) error {
+	var pluginConfigs []plugin.Config
+	if cfg.GeneratorPluginsConfig != "" {
+		var err error
+		if pluginConfigs, err = plugin.LoadConfigFile(cfg.GeneratorPluginsConfig); err != nil {
+			return err
+		}
+		setupLog.Info("Loaded generator plugins config", "plugins", len(pluginConfigs))
+	}
+	plugins := plugin.NewManager(pluginConfigs)
+	if err := mgr.Add(plugins); err != nil {
+		return err
+	}
+	if err := (&controller.GeneratorPluginReconciler{
+		Client:  mgr.GetClient(),
+		Scheme:  mgr.GetScheme(),
+		Plugins: plugins,
+	}).SetupWithManager(mgr); err != nil {
+		return err
+	}
+
 	return (&controller.SecretSantaReconciler{
-		Client:             mgr.GetClient(),
-		Scheme:             mgr.GetScheme(),
-		IncludeAnnotations: cfg.IncludeAnnotations,
-		ExcludeAnnotations: cfg.ExcludeAnnotations,
-		IncludeLabels:      cfg.IncludeLabels,
-		ExcludeLabels:      cfg.ExcludeLabels,
-		DryRun:             cfg.DryRun,
-		EnableMetadata:     cfg.EnableMetadata,
+		Client:               mgr.GetClient(),
+		APIReader:            mgr.GetAPIReader(),
+		Scheme:               mgr.GetScheme(),
+		IncludeAnnotations:   cfg.IncludeAnnotations,
+		ExcludeAnnotations:   cfg.ExcludeAnnotations,
+		IncludeLabels:        cfg.IncludeLabels,
+		ExcludeLabels:        cfg.ExcludeLabels,
+		DryRun:               cfg.DryRun,
+		EnableMetadata:       cfg.EnableMetadata,
+		DeterministicSeed:    cfg.InsecureDeterministic,
+		RemoteClusters:       k8s.NewClusterManager(mgr.GetScheme(), float32(cfg.RemoteClusterQPS), cfg.RemoteClusterBurst),
+		S3AmbientCredentials: cfg.S3AmbientCredentials,
+		FileMediaRoot:        cfg.FileMediaRoot,
+		GitRepositoryRoot:    cfg.GitRepositoryRoot,
+		VaultTokenAudience:   cfg.VaultTokenAudience,
 	}).SetupWithManager(mgr, cfg.MaxConcurrentReconciles)
 }
 
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+	"github.com/logicIQ/secret-santa/internal/controller"
+	"github.com/logicIQ/secret-santa/pkg/media/file"
+)
+
+func newRenderCommand() *cobra.Command {
+	renderCmd := &cobra.Command{
+		Use:   "render",
+		Short: "Render a SecretSanta manifest offline",
+		Long: "Run the generators and template of a SecretSanta manifest locally and print the unmasked " +
+			"output. Nothing is stored and no cluster is contacted, so Secret references are not supported. " +
+			"--insecure-deterministic makes the output reproducible for golden-file tests; values rendered " +
+			"with it are predictable and must never be used as real secrets. With --output-dir the output is " +
+			"written with the file media instead, using the path, format and modes of the manifest if its " +
+			"media type is file.",
+		Args: cobra.NoArgs,
+		RunE: runRender,
+	}
+	renderCmd.Flags().StringP("filename", "f", "", "SecretSanta manifest to render, - for stdin (required)")
+	renderCmd.Flags().String("insecure-deterministic", "", "Seed for reproducible output. INSECURE, for tests only.")
+	renderCmd.Flags().String("output-dir", "", "Write the output to a file in this directory instead of stdout")
+	renderCmd.Flags().String("format", "", "File format with --output-dir: raw, env, json or yaml")
+	renderCmd.Flags().Bool("overwrite", false, "Replace an existing file with --output-dir")
+	_ = renderCmd.MarkFlagRequired("filename")
+	return renderCmd
+}
+
+func runRender(cmd *cobra.Command, args []string) error {
+	filename, err := cmd.Flags().GetString("filename")
+	if err != nil {
+		return err
+	}
+	seed, err := cmd.Flags().GetString("insecure-deterministic")
+	if err != nil {
+		return err
+	}
+	outputDir, err := cmd.Flags().GetString("output-dir")
+	if err != nil {
+		return err
+	}
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return err
+	}
+	overwrite, err := cmd.Flags().GetBool("overwrite")
+	if err != nil {
+		return err
+	}
+
+	var manifest []byte
+	if filename == "-" {
+		manifest, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		manifest, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var secretSanta secretsantav1alpha1.SecretSanta
+	if err := yaml.UnmarshalStrict(manifest, &secretSanta); err != nil {
+		return fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if secretSanta.Namespace == "" {
+		secretSanta.Namespace = "default"
+	}
+
+	cmd.SilenceUsage = true
+	if seed != "" {
+		fmt.Fprintln(cmd.ErrOrStderr(), "WARNING: insecure deterministic entropy enabled - rendered values are predictable")
+	}
+
+	reconciler := &controller.SecretSantaReconciler{DeterministicSeed: seed}
+	output, err := reconciler.Render(context.Background(), &secretSanta)
+	if err != nil {
+		return err
+	}
+	if outputDir == "" {
+		fmt.Fprint(cmd.OutOrStdout(), output)
+		return nil
+	}
+
+	fileMedia, err := newFileMedia(&secretSanta, outputDir, format)
+	if err != nil {
+		return err
+	}
+	if overwrite {
+		return fileMedia.Rotate(context.Background(), &secretSanta, output, false)
+	}
+	return fileMedia.Store(context.Background(), &secretSanta, output, false)
+}
+
+// newFileMedia creates the file media for --output-dir from the file media config of the manifest
+func newFileMedia(secretSanta *secretsantav1alpha1.SecretSanta, outputDir, format string) (*file.FileMedia, error) {
+	fileMedia := &file.FileMedia{Directory: outputDir, Format: format}
+	media := secretSanta.Spec.Media
+	if media == nil || media.Type != "file" || media.Config == nil || len(media.Config.Raw) == 0 {
+		return fileMedia, nil
+	}
+
+	var config struct {
+		Path     string `json:"path"`
+		Format   string `json:"format"`
+		FileMode string `json:"file_mode"`
+		DirMode  string `json:"dir_mode"`
+	}
+	if err := json.Unmarshal(media.Config.Raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse file media config: %w", err)
+	}
+	fileMedia.Path = config.Path
+	if fileMedia.Format == "" {
+		fileMedia.Format = config.Format
+	}
+	var err error
+	if config.FileMode != "" {
+		if fileMedia.FileMode, err = file.ParseMode(config.FileMode); err != nil {
+			return nil, err
+		}
+	}
+	if config.DirMode != "" {
+		if fileMedia.DirMode, err = file.ParseMode(config.DirMode); err != nil {
+			return nil, err
+		}
+	}
+	return fileMedia, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/logicIQ/secret-santa/pkg/shamir"
+)
+
+func newShamirCommand() *cobra.Command {
+	shamirCmd := &cobra.Command{
+		Use:   "shamir",
+		Short: "Shamir secret sharing tools",
+	}
+
+	combineCmd := &cobra.Command{
+		Use:   "combine [share...]",
+		Short: "Recombine shamir_split shares into the original secret",
+		Long: "Recombine base64 shares produced by the shamir_split generator. Shares are read from the " +
+			"arguments, or one per line from stdin when no arguments are given.",
+		RunE: runShamirCombine,
+	}
+	combineCmd.Flags().String("output", "raw", "Output encoding: raw, hex or base64")
+
+	shamirCmd.AddCommand(combineCmd)
+	return shamirCmd
+}
+
+func runShamirCombine(cmd *cobra.Command, args []string) error {
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+
+	encoded := args
+	if len(encoded) == 0 {
+		scanner := bufio.NewScanner(cmd.InOrStdin())
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				encoded = append(encoded, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read shares: %w", err)
+		}
+	}
+
+	shares := make([][]byte, len(encoded))
+	for i, value := range encoded {
+		share, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("share %d is not valid base64", i+1)
+		}
+		shares[i] = share
+	}
+
+	secret, err := shamir.Combine(shares)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	switch output {
+	case "raw":
+		_, err = out.Write(secret)
+	case "hex":
+		_, err = fmt.Fprintln(out, hex.EncodeToString(secret))
+	case "base64":
+		_, err = fmt.Fprintln(out, base64.StdEncoding.EncodeToString(secret))
+	default:
+		return fmt.Errorf("unsupported output encoding: %s (supported: raw, hex, base64)", output)
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/logicIQ/secret-santa/pkg/apitoken"
+)
+
+func newTokenCommand() *cobra.Command {
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "API token tools",
+	}
+
+	validateCmd := &cobra.Command{
+		Use:   "validate [token...]",
+		Short: "Validate the prefix, alphabet and checksum of api_token tokens",
+		Long: "Validate tokens produced by the api_token generator without a lookup. Tokens are read from the " +
+			"arguments, or one per line from stdin when no arguments are given. The flags must match the " +
+			"generator config.",
+		RunE: runTokenValidate,
+	}
+	validateCmd.Flags().String("prefix", "", "Token prefix (required)")
+	validateCmd.Flags().String("alphabet", "base62", "Alphabet: base62, base58, base32, hex or custom characters")
+	validateCmd.Flags().String("checksum", "crc32", "Checksum algorithm: crc32, crc32c or none")
+	validateCmd.Flags().Int("entropy-bytes", 0, "Expected random bytes, 0 accepts any length")
+	_ = validateCmd.MarkFlagRequired("prefix")
+
+	tokenCmd.AddCommand(validateCmd)
+	return tokenCmd
+}
+
+func runTokenValidate(cmd *cobra.Command, args []string) error {
+	var format apitoken.Format
+	var err error
+	if format.Prefix, err = cmd.Flags().GetString("prefix"); err != nil {
+		return err
+	}
+	if format.Alphabet, err = cmd.Flags().GetString("alphabet"); err != nil {
+		return err
+	}
+	if format.Checksum, err = cmd.Flags().GetString("checksum"); err != nil {
+		return err
+	}
+	if format.EntropyBytes, err = cmd.Flags().GetInt("entropy-bytes"); err != nil {
+		return err
+	}
+
+	// Invalid tokens are not usage errors
+	cmd.SilenceUsage = true
+
+	tokens := args
+	if len(tokens) == 0 {
+		scanner := bufio.NewScanner(cmd.InOrStdin())
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				tokens = append(tokens, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read tokens: %w", err)
+		}
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("no tokens to validate")
+	}
+
+	// Report every token, but never echo the token itself
+	invalid := 0
+	out := cmd.OutOrStdout()
+	for i, token := range tokens {
+		if err := format.Validate(strings.TrimSpace(token)); err != nil {
+			invalid++
+			fmt.Fprintf(out, "token %d: invalid: %v\n", i+1, err)
+			continue
+		}
+		fmt.Fprintf(out, "token %d: valid\n", i+1)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d tokens are invalid", invalid, len(tokens))
+	}
+	return nil
+}
@@ -18,6 +18,14 @@ type Config struct {
 	ExcludeLabels           []string
 	DryRun                  bool
 	EnableMetadata          bool
+	InsecureDeterministic   string
+	GeneratorPluginsConfig  string
+	S3AmbientCredentials    bool
+	FileMediaRoot           string
+	GitRepositoryRoot       string
+	VaultTokenAudience      string
+	RemoteClusterQPS        float64
+	RemoteClusterBurst      int
 	LogFormat               string
 	LogLevel                string
 }
@@ -34,6 +42,14 @@ func Load() *Config {
 	viper.SetDefault("exclude-labels", []string{})
 	viper.SetDefault("dry-run", false)
 	viper.SetDefault("enable-metadata", true)
+	viper.SetDefault("insecure-deterministic", "")
+	viper.SetDefault("generator-plugins-config", "")
+	viper.SetDefault("s3-ambient-credentials", false)
+	viper.SetDefault("file-media-root", "")
+	viper.SetDefault("git-repository-root", "")
+	viper.SetDefault("vault-token-audience", "vault")
+	viper.SetDefault("remote-cluster-qps", 5.0)
+	viper.SetDefault("remote-cluster-burst", 10)
 	viper.SetDefault("log-format", "json")
 	viper.SetDefault("log-level", "info")
 
@@ -53,6 +69,14 @@ func Load() *Config {
 		ExcludeLabels:           getCommaSeparatedStringSlice("exclude-labels"),
 		DryRun:                  viper.GetBool("dry-run"),
 		EnableMetadata:          viper.GetBool("enable-metadata"),
+		InsecureDeterministic:   viper.GetString("insecure-deterministic"),
+		GeneratorPluginsConfig:  viper.GetString("generator-plugins-config"),
+		S3AmbientCredentials:    viper.GetBool("s3-ambient-credentials"),
+		FileMediaRoot:           viper.GetString("file-media-root"),
+		GitRepositoryRoot:       viper.GetString("git-repository-root"),
+		VaultTokenAudience:      viper.GetString("vault-token-audience"),
+		RemoteClusterQPS:        viper.GetFloat64("remote-cluster-qps"),
+		RemoteClusterBurst:      viper.GetInt("remote-cluster-burst"),
 		LogFormat:               viper.GetString("log-format"),
 		LogLevel:                viper.GetString("log-level"),
 	}
@@ -26,6 +26,14 @@ func TestLoad_Defaults(t *testing.T) {
 	assert.Empty(t, cfg.IncludeLabels)
 	assert.Empty(t, cfg.ExcludeLabels)
 	assert.False(t, cfg.DryRun)
+	assert.Empty(t, cfg.InsecureDeterministic)
+	assert.Empty(t, cfg.GeneratorPluginsConfig)
+	assert.False(t, cfg.S3AmbientCredentials)
+	assert.Empty(t, cfg.FileMediaRoot)
+	assert.Empty(t, cfg.GitRepositoryRoot)
+	assert.Equal(t, "vault", cfg.VaultTokenAudience)
+	assert.Equal(t, 5.0, cfg.RemoteClusterQPS)
+	assert.Equal(t, 10, cfg.RemoteClusterBurst)
 	assert.Equal(t, "json", cfg.LogFormat)
 	assert.Equal(t, "info", cfg.LogLevel)
 }
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+	"github.com/logicIQ/secret-santa/pkg/generators/plugin"
+)
+
+// GeneratorPluginReconciler registers the generator types of GeneratorPlugin resources
+type GeneratorPluginReconciler struct {
+	client.Client
+	Scheme  *runtime.Scheme
+	Plugins *plugin.Manager
+}
+
+func (r *GeneratorPluginReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	var generatorPlugin secretsantav1alpha1.GeneratorPlugin
+	if err := r.Get(ctx, req.NamespacedName, &generatorPlugin); err != nil {
+		if errors.IsNotFound(err) {
+			if !r.Plugins.IsStatic(req.Name) {
+				log.Info("GeneratorPlugin deleted - unregistering generator types")
+				r.Plugins.Remove(req.Name)
+			}
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if !generatorPlugin.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if r.Plugins.IsStatic(generatorPlugin.Name) {
+		err := fmt.Errorf("plugin %s is already configured in the generator plugins config file", generatorPlugin.Name)
+		return ctrl.Result{}, r.updateStatus(ctx, &generatorPlugin, nil, "NameConflict", err)
+	}
+
+	config := plugin.Config{
+		Name:       generatorPlugin.Name,
+		Address:    generatorPlugin.Spec.Address,
+		CAFile:     generatorPlugin.Spec.CAFile,
+		ServerName: generatorPlugin.Spec.ServerName,
+		Insecure:   generatorPlugin.Spec.Insecure,
+	}
+	if generatorPlugin.Spec.Timeout != nil {
+		config.Timeout = *generatorPlugin.Spec.Timeout
+	}
+
+	types, err := r.Plugins.Sync(ctx, config)
+	reason := "Registered"
+	switch {
+	case err != nil && len(types) > 0:
+		reason = "TypeConflict"
+	case err != nil:
+		reason = "Unavailable"
+	}
+	if err != nil {
+		log.Error(err, "Failed to register generator plugin")
+	} else {
+		log.V(1).Info("Generator plugin registered", "types", len(types))
+	}
+
+	// Re-describe periodically so type changes and outages are reflected in the status
+	return ctrl.Result{RequeueAfter: r.Plugins.ProbeInterval()}, r.updateStatus(ctx, &generatorPlugin, types, reason, err)
+}
+
+func (r *GeneratorPluginReconciler) updateStatus(ctx context.Context, generatorPlugin *secretsantav1alpha1.GeneratorPlugin, types []plugin.TypeSchema, reason string, syncErr error) error {
+	now := metav1.Now()
+	generatorPlugin.Status.LastProbeTime = &now
+	generatorPlugin.Status.Types = nil
+	for _, schema := range types {
+		generatorPlugin.Status.Types = append(generatorPlugin.Status.Types, secretsantav1alpha1.GeneratorPluginType{
+			Type:        schema.Type,
+			Description: schema.Description,
+		})
+	}
+
+	condition := metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: fmt.Sprintf("%d generator types registered", len(types)),
+	}
+	if syncErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Message = syncErr.Error()
+	}
+	meta.SetStatusCondition(&generatorPlugin.Status.Conditions, condition)
+
+	err := r.Status().Update(ctx, generatorPlugin)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update GeneratorPlugin status: %w", err)
+	}
+	return nil
+}
+
+func (r *GeneratorPluginReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("manager cannot be nil")
+	}
+	if r.Plugins == nil {
+		return fmt.Errorf("plugin manager cannot be nil")
+	}
+	// Status updates do not change the generation, so they do not trigger another reconcile. The
+	// plugin is probed again after RequeueAfter instead.
+	err := ctrl.NewControllerManagedBy(mgr).
+		For(&secretsantav1alpha1.GeneratorPlugin{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+	if err != nil {
+		return fmt.Errorf("failed to setup generator plugin controller: %w", err)
+	}
+	return nil
+}
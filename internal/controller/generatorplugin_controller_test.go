@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+	"github.com/logicIQ/secret-santa/pkg/generators"
+	"github.com/logicIQ/secret-santa/pkg/generators/plugin"
+)
+
+type echoPlugin struct{}
+
+func (p *echoPlugin) Describe(ctx context.Context) (*plugin.Description, error) {
+	return &plugin.Description{Types: []plugin.TypeSchema{{Type: "echo_value", Description: "Echoes the value parameter"}}}, nil
+}
+
+func (p *echoPlugin) Generate(ctx context.Context, generatorType string, config map[string]interface{}) (map[string]string, error) {
+	value, _ := config["value"].(string)
+	return map[string]string{"value": value}, nil
+}
+
+func TestGeneratorPluginReconcile(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ssplugin")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "echo.sock")
+	lis, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	go func() { _ = plugin.Serve(lis, &echoPlugin{}) }()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, secretsantav1alpha1.AddToScheme(scheme))
+	newPlugin := func(name, address string) *secretsantav1alpha1.GeneratorPlugin {
+		return &secretsantav1alpha1.GeneratorPlugin{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: secretsantav1alpha1.GeneratorPluginSpec{
+				Address: address,
+				Timeout: &metav1.Duration{Duration: 200 * time.Millisecond},
+			},
+		}
+	}
+	objects := []*secretsantav1alpha1.GeneratorPlugin{
+		newPlugin("echo", "unix://"+socket),
+		newPlugin("missing", "unix://"+filepath.Join(dir, "missing.sock")),
+		newPlugin("static", "unix://"+socket),
+	}
+	builder := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&secretsantav1alpha1.GeneratorPlugin{})
+	for _, obj := range objects {
+		builder = builder.WithObjects(obj)
+	}
+	r := &GeneratorPluginReconciler{
+		Client:  builder.Build(),
+		Scheme:  scheme,
+		Plugins: plugin.NewManager([]plugin.Config{{Name: "static", Address: "unix://" + socket}}),
+	}
+
+	reconcile := func(name string) *secretsantav1alpha1.GeneratorPlugin {
+		_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: name}})
+		require.NoError(t, err)
+		var gp secretsantav1alpha1.GeneratorPlugin
+		require.NoError(t, r.Get(context.Background(), types.NamespacedName{Name: name}, &gp))
+		return &gp
+	}
+
+	t.Run("registered", func(t *testing.T) {
+		gp := reconcile("echo")
+		condition := meta.FindStatusCondition(gp.Status.Conditions, "Ready")
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, []secretsantav1alpha1.GeneratorPluginType{{Type: "echo_value", Description: "Echoes the value parameter"}}, gp.Status.Types)
+		assert.NotNil(t, gp.Status.LastProbeTime)
+
+		gen, err := generators.Get("echo_value")
+		require.NoError(t, err)
+		result, err := generators.GenerateContext(context.Background(), gen, map[string]interface{}{"value": "hi"})
+		require.NoError(t, err)
+		assert.Equal(t, "hi", result["value"])
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		gp := reconcile("missing")
+		condition := meta.FindStatusCondition(gp.Status.Conditions, "Ready")
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, "Unavailable", condition.Reason)
+	})
+
+	t.Run("name conflict with config file", func(t *testing.T) {
+		gp := reconcile("static")
+		condition := meta.FindStatusCondition(gp.Status.Conditions, "Ready")
+		require.NotNil(t, condition)
+		assert.Equal(t, "NameConflict", condition.Reason)
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		require.NoError(t, r.Delete(context.Background(), objects[0]))
+		_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "echo"}})
+		require.NoError(t, err)
+		assert.False(t, generators.IsSupported("echo_value"))
+	})
+}
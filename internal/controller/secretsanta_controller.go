@@ -8,23 +8,35 @@ import (
 	"strings"
 	"text/template"
 	"time"
+	"unicode"
 
+	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+	"github.com/logicIQ/secret-santa/pkg/entropy"
 	"github.com/logicIQ/secret-santa/pkg/generators"
 	"github.com/logicIQ/secret-santa/pkg/media"
 	"github.com/logicIQ/secret-santa/pkg/media/aws"
+	"github.com/logicIQ/secret-santa/pkg/media/file"
 	"github.com/logicIQ/secret-santa/pkg/media/gcp"
+	"github.com/logicIQ/secret-santa/pkg/media/gitsops"
 	"github.com/logicIQ/secret-santa/pkg/media/k8s"
+	"github.com/logicIQ/secret-santa/pkg/media/oci"
+	"github.com/logicIQ/secret-santa/pkg/media/s3"
+	"github.com/logicIQ/secret-santa/pkg/media/sealedsecret"
+	"github.com/logicIQ/secret-santa/pkg/media/vault"
 	tmplpkg "github.com/logicIQ/secret-santa/pkg/template"
 	"github.com/logicIQ/secret-santa/pkg/validation"
 )
@@ -32,10 +44,37 @@ import (
 const (
 	SecretSantaFinalizer   = "secrets.secret-santa.io/finalizer"
 	MaxGeneratorConfigSize = 1024 * 1024 // 1MB
+	// secretRefSuffix marks generator and media config keys that are resolved from a Secret
+	secretRefSuffix = "_secret_ref"
+	// serviceAccountTokenExpiration is the lifetime in seconds of tokens requested for media
+	// authentication, the minimum the API server accepts
+	serviceAccountTokenExpiration int64 = 600
+	// VaultAuthAnnotation opts a ServiceAccount in to tokens requested for vault-kv kubernetes auth
+	VaultAuthAnnotation = "secrets.secret-santa.io/vault-auth"
 )
 
+// generatorSecretFields lists the generator config fields holding key material. They are only
+// accepted through <field>_secret_ref so they never appear in the SecretSanta spec.
+var generatorSecretFields = map[string][]string{
+	"crypto_hkdf":           {"master_key"},
+	"k8s_encryption_config": {"previous_config"},
+}
+
+// mediaSecretFields lists the media config fields holding credentials, which are likewise only
+// accepted through <field>_secret_ref
+var mediaSecretFields = map[string][]string{
+	"vault-kv":  {"token", "secret_id"},
+	"oci-vault": {"private_key"},
+	"s3-object": {"secret_access_key", "session_token", "sse_customer_key"},
+	"git-sops":  {"ssh_private_key"},
+	"k8s":       {"kubeconfig"},
+}
+
 type SecretSantaReconciler struct {
 	client.Client
+	// APIReader reads objects the manager does not cache, such as ConfigMaps, from the API server.
+	// The Client is used if it is nil.
+	APIReader          client.Reader
 	Scheme             *runtime.Scheme
 	IncludeAnnotations []string
 	ExcludeAnnotations []string
@@ -43,6 +82,20 @@ type SecretSantaReconciler struct {
 	ExcludeLabels      []string
 	DryRun             bool
 	EnableMetadata     bool
+	// DeterministicSeed replaces crypto/rand with a seeded, insecure source during dry-runs and
+	// offline rendering so output is reproducible. It must never be set for real secrets.
+	DeterministicSeed string
+	// RemoteClusters caches the clients of remote clusters targeted by the k8s media
+	RemoteClusters *k8s.ClusterManager
+	// S3AmbientCredentials lets the s3-object media fall back to the AWS credentials of the operator
+	S3AmbientCredentials bool
+	// FileMediaRoot is the directory the file media writes below, the file media is disabled if empty
+	FileMediaRoot string
+	// GitRepositoryRoot is the directory local git-sops repositories must be in, they are rejected if empty
+	GitRepositoryRoot string
+	// VaultTokenAudience is the audience of the ServiceAccount tokens requested for vault-kv kubernetes
+	// auth, vault.DefaultAudience if empty. SecretSantas cannot choose it.
+	VaultTokenAudience string
 }
 
 func (r *SecretSantaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -90,6 +143,12 @@ func (r *SecretSantaReconciler) handleDeletion(ctx context.Context, secretSanta
 	log := log.FromContext(ctx)
 	log.Info("Handling SecretSanta deletion")
 
+	// Replicas in other namespaces cannot have owner references, so they are deleted here
+	if err := k8s.DeleteReplicas(ctx, r.Client, r.apiReader(), secretSanta); err != nil {
+		log.Error(err, "Failed to delete secret replicas")
+		return ctrl.Result{}, err
+	}
+
 	controllerutil.RemoveFinalizer(secretSanta, SecretSantaFinalizer)
 	return ctrl.Result{}, r.Update(ctx, secretSanta)
 }
@@ -104,10 +163,19 @@ func (r *SecretSantaReconciler) reconcileSecret(ctx context.Context, secretSanta
 	}
 
 	// Check if we already processed this SecretSanta successfully
+	rotating := false
 	for _, condition := range secretSanta.Status.Conditions {
 		if condition.Type == "Ready" && condition.Status == metav1.ConditionTrue {
-			log.V(1).Info("Secret already processed - create-once policy enforced")
-			return ctrl.Result{}, nil
+			if secretSanta.Status.NextRotation == nil {
+				log.V(1).Info("Secret already processed - create-once policy enforced")
+				return ctrl.Result{}, r.reconcileReplicas(ctx, secretSanta)
+			}
+			if wait := time.Until(secretSanta.Status.NextRotation.Time); wait > 0 {
+				log.V(1).Info("Secret already processed - waiting for rotation", "nextRotation", secretSanta.Status.NextRotation.Time)
+				return ctrl.Result{RequeueAfter: wait}, r.reconcileReplicas(ctx, secretSanta)
+			}
+			log.Info("Rotation window elapsed - regenerating secret")
+			rotating = true
 		}
 	}
 
@@ -117,18 +185,33 @@ func (r *SecretSantaReconciler) reconcileSecret(ctx context.Context, secretSanta
 		secretName = secretSanta.Name
 	}
 
-	// Check if secret already exists (created outside this controller)
-	var existingSecret corev1.Secret
-	err := r.Get(ctx, client.ObjectKey{Name: secretName, Namespace: secretSanta.Namespace}, &existingSecret)
-	if err == nil {
-		log.Info("Secret already exists - create-once policy enforced")
-		RecordSecretSkipped(secretSanta.Name, secretSanta.Namespace)
-		if updateErr := r.updateStatus(ctx, secretSanta, "Ready", "True", "Secret already exists"); updateErr != nil {
-			log.Error(updateErr, "Failed to update status")
+	// Check if secret already exists, unless it is being rotated. A secret of the k8s media that was
+	// created outside this controller is a conflict. Generators that schedule a rotation still run so
+	// the next rotation is recorded in the status. Secrets on remote clusters are not looked up here,
+	// a local secret of the same name is unrelated.
+	remote, _ := remoteClusterTarget(secretSanta)
+	if !rotating && !remote {
+		var existingSecret corev1.Secret
+		err := r.Get(ctx, client.ObjectKey{Name: secretName, Namespace: secretSanta.Namespace}, &existingSecret)
+		if err == nil && usesK8sMedia(secretSanta) && !media.IsOwnedBy(existingSecret.Annotations, secretSanta) {
+			err := media.NotOwnedError("secret", secretSanta.Namespace, secretName, secretSanta)
+			log.Error(err, "Secret already exists - refusing to take it over")
+			RecordReconcileError(secretSanta.Name, secretSanta.Namespace)
+			if updateErr := r.updateStatus(ctx, secretSanta, "SecretStorageFailed", "False", err.Error()); updateErr != nil {
+				log.Error(updateErr, "Failed to update status")
+			}
+			return ctrl.Result{}, nil
+		}
+		if err == nil && !schedulesRotation(secretSanta.Spec.Generators) {
+			log.Info("Secret already exists - create-once policy enforced")
+			RecordSecretSkipped(secretSanta.Name, secretSanta.Namespace)
+			if updateErr := r.updateStatus(ctx, secretSanta, "Ready", "True", "Secret already exists"); updateErr != nil {
+				log.Error(updateErr, "Failed to update status")
+			}
+			return ctrl.Result{}, r.reconcileReplicas(ctx, secretSanta)
+		} else if err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
 		}
-		return ctrl.Result{}, nil
-	} else if !errors.IsNotFound(err) {
-		return ctrl.Result{}, err
 	}
 
 	// Validate generators first
@@ -141,7 +224,7 @@ func (r *SecretSantaReconciler) reconcileSecret(ctx context.Context, secretSanta
 		return ctrl.Result{}, nil
 	}
 
-	templateData, err := r.generateTemplateData(secretSanta.Spec.Generators)
+	templateData, err := r.generateTemplateData(ctx, secretSanta.Namespace, secretSanta.Spec.Generators)
 	if err != nil {
 		log.Error(err, "Failed to generate template data")
 		RecordReconcileError(secretSanta.Name, secretSanta.Namespace)
@@ -171,21 +254,92 @@ func (r *SecretSantaReconciler) reconcileSecret(ctx context.Context, secretSanta
 	}
 	log.V(1).Info("Template executed successfully", "dataSize", len(secretData))
 
-	return r.storeSecret(ctx, secretSanta, secretData)
+	nextRotation := nextRotationTime(secretSanta.Spec.Generators, templateData, time.Now())
+	return r.storeSecret(ctx, secretSanta, secretData, rotating, nextRotation)
+}
+
+// reconcileReplicas updates the replicas of a k8s media in this cluster when the secret itself is
+// not written, so replicas follow namespace and configuration changes under the create-once policy
+func (r *SecretSantaReconciler) reconcileReplicas(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta) error {
+	if !usesK8sMedia(secretSanta) {
+		return nil
+	}
+	if remote, err := remoteClusterTarget(secretSanta); err != nil || remote {
+		return err
+	}
+	mediaInstance, err := r.createMedia(ctx, secretSanta)
+	if err != nil {
+		return err
+	}
+	k8sMedia, ok := mediaInstance.(*k8s.K8sSecretsMedia)
+	if !ok {
+		return nil
+	}
+	return k8sMedia.ReconcileReplicas(ctx, secretSanta)
+}
+
+// usesK8sMedia reports whether a SecretSanta stores its secret with the k8s media, the default
+func usesK8sMedia(secretSanta *secretsantav1alpha1.SecretSanta) bool {
+	return secretSanta.Spec.Media == nil || secretSanta.Spec.Media.Type == "k8s" || secretSanta.Spec.Media.Type == ""
 }
 
-func (r *SecretSantaReconciler) storeSecret(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string) (ctrl.Result, error) {
+// mediaConfig returns the parsed media config of a SecretSanta, empty if it has none
+func mediaConfig(secretSanta *secretsantav1alpha1.SecretSanta) (map[string]interface{}, error) {
+	config := map[string]interface{}{}
+	if secretSanta.Spec.Media == nil || secretSanta.Spec.Media.Config == nil || len(secretSanta.Spec.Media.Config.Raw) == 0 {
+		return config, nil
+	}
+	if err := json.Unmarshal(secretSanta.Spec.Media.Config.Raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal media config: %s", sanitizeLogValue(err.Error()))
+	}
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	return config, nil
+}
+
+// remoteClusterTarget reports whether the k8s media of a SecretSanta writes to a remote cluster
+func remoteClusterTarget(secretSanta *secretsantav1alpha1.SecretSanta) (bool, error) {
+	if secretSanta.Spec.Media == nil || !usesK8sMedia(secretSanta) {
+		return false, nil
+	}
+	config, err := mediaConfig(secretSanta)
+	if err != nil {
+		return false, err
+	}
+	for _, key := range []string{"kubeconfig", "kubeconfig" + secretRefSuffix, "cluster_name"} {
+		if config[key] != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *SecretSantaReconciler) storeSecret(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, rotating bool, nextRotation *metav1.Time) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
 	// Create media instance based on configuration
-	mediaInstance, err := r.createMedia(secretSanta)
+	mediaInstance, err := r.createMedia(ctx, secretSanta)
 	if err != nil {
 		log.Error(err, "Failed to create media instance")
 		return ctrl.Result{}, err
 	}
 
-	// Store the secret using the media
-	if err := mediaInstance.Store(ctx, secretSanta, data, r.EnableMetadata); err != nil {
+	// Store the secret using the media, replacing the stored data when rotating
+	store := mediaInstance.Store
+	if rotating {
+		rotator, ok := mediaInstance.(media.Rotator)
+		if !ok {
+			err := fmt.Errorf("media type %s does not support rotation", sanitizeLogValue(mediaInstance.GetType()))
+			log.Error(err, "Failed to rotate secret")
+			if updateErr := r.updateStatus(ctx, secretSanta, "SecretStorageFailed", "False", err.Error()); updateErr != nil {
+				log.Error(updateErr, "Failed to update status")
+			}
+			return ctrl.Result{}, nil
+		}
+		store = rotator.Rotate
+	}
+	if err := store(ctx, secretSanta, data, r.EnableMetadata); err != nil {
 		log.Error(err, "Failed to store secret", "mediaType", sanitizeLogValue(mediaInstance.GetType()))
 		if updateErr := r.updateStatus(ctx, secretSanta, "SecretStorageFailed", "False", err.Error()); updateErr != nil {
 			log.Error(updateErr, "Failed to update status")
@@ -195,37 +349,157 @@ func (r *SecretSantaReconciler) storeSecret(ctx context.Context, secretSanta *se
 
 	RecordSuccessfulGeneration(secretSanta.Name, secretSanta.Namespace)
 	UpdateSecretInstances(secretSanta.Name, secretSanta.Namespace, 1)
+	secretSanta.Status.NextRotation = nextRotation
 	if updateErr := r.updateStatus(ctx, secretSanta, "Ready", "True", "Secret stored successfully"); updateErr != nil {
 		log.Error(updateErr, "Failed to update status")
 	}
 
-	log.Info("Secret stored successfully", "mediaType", sanitizeLogValue(mediaInstance.GetType()))
+	log.Info("Secret stored successfully", "mediaType", sanitizeLogValue(mediaInstance.GetType()), "rotated", rotating)
+	if nextRotation != nil {
+		return ctrl.Result{RequeueAfter: time.Until(nextRotation.Time)}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
-func (r *SecretSantaReconciler) createMedia(secretSanta *secretsantav1alpha1.SecretSanta) (media.Media, error) {
+// rotationOutputs maps the generator types that schedule a rotation to the output holding its time
+var rotationOutputs = map[string]string{
+	"time_rotating":           "rotation_rfc3339",
+	"tls_self_signed_cert":    "renewal_time",
+	"tls_locally_signed_cert": "renewal_time",
+}
+
+// schedulesRotation reports whether any generator produces a rotation time. Certificates only do
+// when early_renewal_hours is set.
+func schedulesRotation(generatorConfigs []secretsantav1alpha1.GeneratorConfig) bool {
+	for _, config := range generatorConfigs {
+		if _, ok := rotationOutputs[config.Type]; !ok {
+			continue
+		}
+		if config.Type == "time_rotating" {
+			return true
+		}
+		var values map[string]interface{}
+		if config.Config == nil || json.Unmarshal(config.Config.Raw, &values) != nil {
+			continue
+		}
+		// The tls generators only read numbers and truncate them to whole hours
+		if hours, ok := values["early_renewal_hours"].(float64); ok && hours >= 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// nextRotationTime returns the earliest future rotation time of the generators listed in
+// rotationOutputs, or nil when no rotation is scheduled. Rotation times that already passed (for
+// example a fixed rfc3339 base in the past) are ignored so they do not cause a requeue loop.
+func nextRotationTime(generatorConfigs []secretsantav1alpha1.GeneratorConfig, data map[string]interface{}, now time.Time) *metav1.Time {
+	var next *metav1.Time
+	for _, config := range generatorConfigs {
+		output, ok := rotationOutputs[config.Type]
+		if !ok {
+			continue
+		}
+		result, ok := data[config.Name].(map[string]string)
+		if !ok {
+			continue
+		}
+		rotation, err := time.Parse(time.RFC3339, result[output])
+		if err != nil || !rotation.After(now) {
+			continue
+		}
+		if next == nil || rotation.Before(next.Time) {
+			t := metav1.NewTime(rotation)
+			next = &t
+		}
+	}
+	return next
+}
+
+func (r *SecretSantaReconciler) createMedia(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta) (media.Media, error) {
 	// Default to K8s secrets if no media is specified
 	if secretSanta.Spec.Media == nil {
-		return &k8s.K8sSecretsMedia{Client: r.Client}, nil
+		return &k8s.K8sSecretsMedia{Client: r.Client, Reader: r.apiReader()}, nil
 	}
 
-	// Parse media config
-	var config map[string]interface{}
-	if secretSanta.Spec.Media.Config != nil && len(secretSanta.Spec.Media.Config.Raw) > 0 {
-		if err := json.Unmarshal(secretSanta.Spec.Media.Config.Raw, &config); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal media config: %s", sanitizeLogValue(err.Error()))
-		}
+	config, err := mediaConfig(secretSanta)
+	if err != nil {
+		return nil, err
 	}
-	if config == nil {
-		config = make(map[string]interface{})
+	// Credentials are read from Secrets in the namespace of the SecretSanta
+	mediaType := secretSanta.Spec.Media.Type
+	if mediaType == "" {
+		mediaType = "k8s"
+	}
+	if err := rejectInlineSecrets(config, mediaSecretFields[mediaType]); err != nil {
+		return nil, fmt.Errorf("invalid media config: %w", err)
+	}
+	if err := r.resolveSecretRefs(ctx, secretSanta.Namespace, config); err != nil {
+		return nil, fmt.Errorf("failed to resolve secret references for media: %w", err)
 	}
 
 	switch secretSanta.Spec.Media.Type {
 	case "k8s", "":
 		secretName, _ := config["secret_name"].(string)
+		splitKeys, _ := config["split_keys"].(bool)
+		var binaryKeys []string
+		if rawKeys, ok := config["binary_keys"].([]interface{}); ok {
+			for _, rawKey := range rawKeys {
+				key, ok := rawKey.(string)
+				if !ok || key == "" {
+					return nil, fmt.Errorf("binary_keys must be a list of non-empty strings")
+				}
+				binaryKeys = append(binaryKeys, key)
+			}
+		}
+		var namespaces []string
+		if rawNamespaces, ok := config["namespaces"].([]interface{}); ok {
+			for _, rawNamespace := range rawNamespaces {
+				namespace, ok := rawNamespace.(string)
+				if !ok || namespace == "" {
+					return nil, fmt.Errorf("namespaces must be a list of non-empty strings")
+				}
+				namespaces = append(namespaces, namespace)
+			}
+		}
+		var namespaceSelector labels.Selector
+		if rawSelector, ok := config["namespace_selector"]; ok {
+			encoded, err := json.Marshal(rawSelector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid namespace_selector: %w", err)
+			}
+			var labelSelector metav1.LabelSelector
+			if err := json.Unmarshal(encoded, &labelSelector); err != nil {
+				return nil, fmt.Errorf("invalid namespace_selector: %w", err)
+			}
+			if namespaceSelector, err = metav1.LabelSelectorAsSelector(&labelSelector); err != nil {
+				return nil, fmt.Errorf("invalid namespace_selector: %w", err)
+			}
+		}
+		namespace, _ := config["namespace"].(string)
+		mediaClient, mediaReader := r.Client, r.apiReader()
+		if config["kubeconfig"] != nil || config["cluster_name"] != nil {
+			if len(namespaces) > 0 || namespaceSelector != nil {
+				return nil, fmt.Errorf("namespaces and namespace_selector are not supported on remote clusters")
+			}
+			remoteClient, err := r.remoteClusterClient(ctx, secretSanta.Namespace, config)
+			if err != nil {
+				return nil, err
+			}
+			mediaClient, mediaReader = remoteClient, remoteClient
+		} else if namespace != "" {
+			// Secrets in other namespaces of this cluster are replicas that need an opt-in
+			return nil, fmt.Errorf("namespace requires kubeconfig_secret_ref or cluster_name, use namespaces for replicas in this cluster")
+		}
 		return &k8s.K8sSecretsMedia{
-			Client:     r.Client,
-			SecretName: secretName,
+			Client:            mediaClient,
+			Reader:            mediaReader,
+			SecretName:        secretName,
+			Namespace:         namespace,
+			SplitKeys:         splitKeys,
+			BinaryKeys:        binaryKeys,
+			Namespaces:        namespaces,
+			NamespaceSelector: namespaceSelector,
 		}, nil
 	case "aws-secrets-manager":
 		region, _ := config["region"].(string)
@@ -257,6 +531,148 @@ func (r *SecretSantaReconciler) createMedia(secretSanta *secretsantav1alpha1.Sec
 			SecretName:      secretName,
 			CredentialsFile: credentialsFile,
 		}, nil
+	case "vault-kv":
+		vaultMedia := &vault.VaultKVMedia{}
+		vaultMedia.Address, _ = config["address"].(string)
+		vaultMedia.Namespace, _ = config["namespace"].(string)
+		vaultMedia.Mount, _ = config["mount"].(string)
+		vaultMedia.Path, _ = config["path"].(string)
+		vaultMedia.SplitKeys, _ = config["split_keys"].(bool)
+		vaultMedia.AuthMethod, _ = config["auth_method"].(string)
+		vaultMedia.AuthMount, _ = config["auth_mount"].(string)
+		vaultMedia.Token, _ = config["token"].(string)
+		vaultMedia.RoleID, _ = config["role_id"].(string)
+		vaultMedia.SecretID, _ = config["secret_id"].(string)
+		vaultMedia.Role, _ = config["role"].(string)
+		vaultMedia.CACert, _ = config["ca_cert"].(string)
+		if kvVersion, ok := config["kv_version"].(float64); ok {
+			vaultMedia.KVVersion = int(kvVersion)
+		}
+		if vaultMedia.AuthMethod == "kubernetes" {
+			if _, exists := config["audience"]; exists {
+				return nil, fmt.Errorf("audience is set by the operator with --vault-token-audience")
+			}
+			serviceAccount, _ := config["service_account"].(string)
+			token, err := r.serviceAccountToken(ctx, secretSanta.Namespace, serviceAccount)
+			if err != nil {
+				return nil, err
+			}
+			vaultMedia.ServiceAccountToken = token
+		}
+		return vaultMedia, nil
+	case "oci-vault":
+		ociMedia := &oci.OCIVaultMedia{}
+		ociMedia.Region, _ = config["region"].(string)
+		ociMedia.CompartmentID, _ = config["compartment_id"].(string)
+		ociMedia.VaultID, _ = config["vault_id"].(string)
+		ociMedia.KeyID, _ = config["key_id"].(string)
+		ociMedia.SecretName, _ = config["secret_name"].(string)
+		ociMedia.AuthMethod, _ = config["auth_method"].(string)
+		ociMedia.TenancyID, _ = config["tenancy_id"].(string)
+		ociMedia.UserID, _ = config["user_id"].(string)
+		ociMedia.Fingerprint, _ = config["fingerprint"].(string)
+		ociMedia.PrivateKey, _ = config["private_key"].(string)
+		return ociMedia, nil
+	case "s3-object":
+		s3Media := &s3.S3ObjectMedia{}
+		s3Media.Endpoint, _ = config["endpoint"].(string)
+		s3Media.Region, _ = config["region"].(string)
+		s3Media.Bucket, _ = config["bucket"].(string)
+		s3Media.Key, _ = config["key"].(string)
+		s3Media.ForcePathStyle, _ = config["force_path_style"].(bool)
+		s3Media.ContentType, _ = config["content_type"].(string)
+		s3Media.AccessKeyID, _ = config["access_key_id"].(string)
+		s3Media.SecretAccessKey, _ = config["secret_access_key"].(string)
+		s3Media.SessionToken, _ = config["session_token"].(string)
+		s3Media.SSE, _ = config["sse"].(string)
+		s3Media.KMSKeyID, _ = config["kms_key_id"].(string)
+		s3Media.CustomerKey, _ = config["sse_customer_key"].(string)
+		s3Media.AmbientCredentials = r.S3AmbientCredentials
+		return s3Media, nil
+	case "file":
+		// Each namespace writes below its own directory in the root chosen by the operator
+		if r.FileMediaRoot == "" {
+			return nil, fmt.Errorf("file media is disabled, the operator must be started with --file-media-root")
+		}
+		directory, _ := config["directory"].(string)
+		directory, err := file.ResolveDirectory(r.FileMediaRoot, secretSanta.Namespace, directory)
+		if err != nil {
+			return nil, err
+		}
+		fileMedia := &file.FileMedia{Directory: directory, Root: r.FileMediaRoot}
+		fileMedia.Path, _ = config["path"].(string)
+		fileMedia.Format, _ = config["format"].(string)
+		// Modes are octal strings since JSON has no octal numbers
+		if fileMode, ok := config["file_mode"].(string); ok {
+			mode, err := file.ParseMode(fileMode)
+			if err != nil {
+				return nil, err
+			}
+			fileMedia.FileMode = mode
+		}
+		if dirMode, ok := config["dir_mode"].(string); ok {
+			mode, err := file.ParseMode(dirMode)
+			if err != nil {
+				return nil, err
+			}
+			fileMedia.DirMode = mode
+		}
+		return fileMedia, nil
+	case "git-sops":
+		gitMedia := &gitsops.GitSopsMedia{}
+		gitMedia.Repository, _ = config["repository"].(string)
+		gitMedia.LocalRoot = r.GitRepositoryRoot
+		gitMedia.Branch, _ = config["branch"].(string)
+		gitMedia.Path, _ = config["path"].(string)
+		gitMedia.CommitMessage, _ = config["commit_message"].(string)
+		gitMedia.AuthorName, _ = config["author_name"].(string)
+		gitMedia.AuthorEmail, _ = config["author_email"].(string)
+		gitMedia.SSHPrivateKey, _ = config["ssh_private_key"].(string)
+		gitMedia.KnownHosts, _ = config["known_hosts"].(string)
+		gitMedia.InsecureIgnoreHostKey, _ = config["insecure_ignore_host_key"].(bool)
+		gitMedia.PGPPublicKeys, _ = config["pgp_public_keys"].(string)
+		gitMedia.SplitKeys, _ = config["split_keys"].(bool)
+		gitMedia.Manifest, _ = config["manifest"].(bool)
+		// Recipients are a list, or a separated string when read from a Secret
+		switch recipients := config["age_recipients"].(type) {
+		case string:
+			gitMedia.AgeRecipients = strings.FieldsFunc(recipients, func(r rune) bool {
+				return r == ',' || unicode.IsSpace(r)
+			})
+		case []interface{}:
+			for _, rawRecipient := range recipients {
+				recipient, ok := rawRecipient.(string)
+				if !ok || recipient == "" {
+					return nil, fmt.Errorf("age_recipients must be a list of non-empty strings")
+				}
+				gitMedia.AgeRecipients = append(gitMedia.AgeRecipients, recipient)
+			}
+		}
+		return gitMedia, nil
+	case "sealed-secret":
+		sealedMedia := &sealedsecret.SealedSecretMedia{Client: r.Client}
+		sealedMedia.Certificate, _ = config["certificate"].(string)
+		sealedMedia.Scope, _ = config["scope"].(string)
+		sealedMedia.SecretName, _ = config["secret_name"].(string)
+		sealedMedia.SplitKeys, _ = config["split_keys"].(bool)
+		if ref, ok := config["certificate_config_map_ref"]; ok {
+			if sealedMedia.Certificate != "" {
+				return nil, fmt.Errorf("certificate and certificate_config_map_ref are mutually exclusive")
+			}
+			certificate, err := r.resolveConfigMapRef(ctx, secretSanta.Namespace, "certificate_config_map_ref", ref)
+			if err != nil {
+				return nil, err
+			}
+			sealedMedia.Certificate = certificate
+		}
+		if output, ok := config["output"]; ok {
+			outputMedia, err := r.createOutputMedia(ctx, secretSanta, output)
+			if err != nil {
+				return nil, err
+			}
+			sealedMedia.Output = outputMedia
+		}
+		return sealedMedia, nil
 	default:
 		return nil, fmt.Errorf("unsupported media type: %s", sanitizeLogValue(secretSanta.Spec.Media.Type))
 	}
@@ -281,8 +697,10 @@ func (r *SecretSantaReconciler) executeTemplate(tmplStr string, data map[string]
 	return buf.String(), nil
 }
 
-func (r *SecretSantaReconciler) generateTemplateData(generatorConfigs []secretsantav1alpha1.GeneratorConfig) (map[string]interface{}, error) {
+func (r *SecretSantaReconciler) generateTemplateData(ctx context.Context, namespace string, generatorConfigs []secretsantav1alpha1.GeneratorConfig) (map[string]interface{}, error) {
 	data := make(map[string]interface{})
+	// Script generators read the outputs of earlier generators, data is filled as the loop runs
+	ctx = generators.WithInputs(ctx, map[string]interface{}{"outputs": data, "namespace": namespace})
 
 	for _, config := range generatorConfigs {
 		if err := r.validateGeneratorConfig(config); err != nil {
@@ -311,9 +729,25 @@ func (r *SecretSantaReconciler) generateTemplateData(generatorConfigs []secretsa
 			configMap = make(map[string]interface{})
 		}
 
+		if err := rejectInlineSecrets(configMap, generatorSecretFields[config.Type]); err != nil {
+			return nil, fmt.Errorf("invalid config for generator %s: %w", sanitizeLogValue(config.Name), err)
+		}
+
+		// Resolve references to outputs of earlier generators
+		rendered, err := r.renderGeneratorConfig(configMap, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render config for generator %s: %w", sanitizeLogValue(config.Name), err)
+		}
+		configMap = rendered.(map[string]interface{})
+
+		// Resolve Secret references after rendering so Secret values are never executed as templates
+		if err := r.resolveSecretRefs(ctx, namespace, configMap); err != nil {
+			return nil, fmt.Errorf("failed to resolve secret references for generator %s: %w", sanitizeLogValue(config.Name), err)
+		}
+
 		log.V(1).Info("Executing generator")
 		timer := NewGeneratorTimer(sanitizeLogValue(config.Type))
-		result, err := gen.Generate(configMap)
+		result, err := generators.GenerateContext(ctx, gen, configMap)
 		timer.ObserveDuration()
 		if err != nil {
 			log.Error(err, "Generator failed")
@@ -333,6 +767,227 @@ func (r *SecretSantaReconciler) generateTemplateData(generatorConfigs []secretsa
 	return data, nil
 }
 
+// renderGeneratorConfig executes string config values containing template actions against the
+// outputs of the generators that ran before, recursing into nested maps and lists
+func (r *SecretSantaReconciler) renderGeneratorConfig(value interface{}, data map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		return r.executeTemplate(v, data)
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			rendered, err := r.renderGeneratorConfig(item, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			result[key] = rendered
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			rendered, err := r.renderGeneratorConfig(item, data)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			result[i] = rendered
+		}
+		return result, nil
+	default:
+		return v, nil
+	}
+}
+
+// serviceAccountToken requests a short-lived token of a ServiceAccount in the namespace of the
+// SecretSanta. The operator's own token is never sent to a server chosen in a SecretSanta, and
+// only ServiceAccounts annotated with VaultAuthAnnotation get tokens, for the audience configured
+// by the operator, so a SecretSanta cannot obtain tokens accepted by the API server.
+func (r *SecretSantaReconciler) serviceAccountToken(ctx context.Context, namespace, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("service_account is required for kubernetes auth")
+	}
+	if r.Client == nil {
+		return "", fmt.Errorf("service account tokens cannot be requested without a cluster connection")
+	}
+	audience := r.VaultTokenAudience
+	if audience == "" {
+		audience = vault.DefaultAudience
+	}
+	serviceAccount := &corev1.ServiceAccount{}
+	if err := r.apiReader().Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, serviceAccount); err != nil {
+		return "", fmt.Errorf("failed to get service account %s: %w", sanitizeLogValue(name), err)
+	}
+	if serviceAccount.Annotations[VaultAuthAnnotation] != "true" {
+		return "", fmt.Errorf("service account %s is not annotated with %s: \"true\"", sanitizeLogValue(name), VaultAuthAnnotation)
+	}
+	expiration := serviceAccountTokenExpiration
+	request := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         []string{audience},
+			ExpirationSeconds: &expiration,
+		},
+	}
+	if err := r.SubResource("token").Create(ctx, serviceAccount, request); err != nil {
+		return "", fmt.Errorf("failed to request token for service account %s: %w", sanitizeLogValue(name), err)
+	}
+	return request.Status.Token, nil
+}
+
+// rejectInlineSecrets fails when any of the given fields is set directly instead of through its
+// _secret_ref key
+func rejectInlineSecrets(config map[string]interface{}, fields []string) error {
+	for _, field := range fields {
+		if _, exists := config[field]; exists {
+			return fmt.Errorf("%s must not be set inline, use %s%s to read it from a Secret", field, field, secretRefSuffix)
+		}
+	}
+	return nil
+}
+
+// resolveSecretRefs replaces each <field>_secret_ref: {name, key} config entry with <field> set to the
+// value of that key in a Secret in the SecretSanta namespace
+func (r *SecretSantaReconciler) resolveSecretRefs(ctx context.Context, namespace string, config map[string]interface{}) error {
+	var refKeys []string
+	for key := range config {
+		if strings.HasSuffix(key, secretRefSuffix) {
+			refKeys = append(refKeys, key)
+		}
+	}
+
+	for _, refKey := range refKeys {
+		field := strings.TrimSuffix(refKey, secretRefSuffix)
+		if _, exists := config[field]; exists {
+			return fmt.Errorf("%s and %s are mutually exclusive", sanitizeLogValue(field), sanitizeLogValue(refKey))
+		}
+		ref, ok := config[refKey].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object with name and key", sanitizeLogValue(refKey))
+		}
+		name, _ := ref["name"].(string)
+		key, _ := ref["key"].(string)
+		if name == "" || key == "" {
+			return fmt.Errorf("%s requires name and key", sanitizeLogValue(refKey))
+		}
+
+		if r.Client == nil {
+			return fmt.Errorf("%s cannot be resolved without a cluster connection", sanitizeLogValue(refKey))
+		}
+		var secret corev1.Secret
+		if err := r.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, &secret); err != nil {
+			return fmt.Errorf("failed to get secret %s for %s: %w", sanitizeLogValue(name), sanitizeLogValue(refKey), err)
+		}
+		value, ok := secret.Data[key]
+		if !ok {
+			return fmt.Errorf("key %s not found in secret %s", sanitizeLogValue(key), sanitizeLogValue(name))
+		}
+
+		config[field] = string(value)
+		delete(config, refKey)
+	}
+	return nil
+}
+
+// remoteClusterClient returns the client of the remote cluster of a k8s media config, described by a
+// kubeconfig or the name of a Cluster API cluster in the namespace of the SecretSanta
+func (r *SecretSantaReconciler) remoteClusterClient(ctx context.Context, namespace string, config map[string]interface{}) (client.Client, error) {
+	kubeconfig, _ := config["kubeconfig"].(string)
+	clusterName, _ := config["cluster_name"].(string)
+	if kubeconfig != "" && clusterName != "" {
+		return nil, fmt.Errorf("kubeconfig and cluster_name are mutually exclusive")
+	}
+	if kubeconfig == "" && clusterName == "" {
+		return nil, fmt.Errorf("kubeconfig or cluster_name must be a non-empty string")
+	}
+	if r.RemoteClusters == nil {
+		return nil, fmt.Errorf("remote clusters are not supported without a cluster manager")
+	}
+
+	if clusterName != "" {
+		// Cluster API stores the kubeconfig of a cluster in the value key of the <cluster>-kubeconfig Secret
+		var secret corev1.Secret
+		name := clusterName + "-kubeconfig"
+		if err := r.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, &secret); err != nil {
+			return nil, fmt.Errorf("failed to get kubeconfig secret %s of cluster %s: %w", sanitizeLogValue(name), sanitizeLogValue(clusterName), err)
+		}
+		value, ok := secret.Data["value"]
+		if !ok {
+			return nil, fmt.Errorf("key value not found in kubeconfig secret %s", sanitizeLogValue(name))
+		}
+		kubeconfig = string(value)
+	}
+
+	c, err := r.RemoteClusters.Client(k8s.RemoteCluster{Namespace: namespace, Kubeconfig: []byte(kubeconfig)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote cluster: %w", err)
+	}
+	return c, nil
+}
+
+// resolveConfigMapRef reads a {name, key} reference from a ConfigMap in the namespace of the SecretSanta
+func (r *SecretSantaReconciler) resolveConfigMapRef(ctx context.Context, namespace, refKey string, rawRef interface{}) (string, error) {
+	ref, ok := rawRef.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("%s must be an object with name and key", refKey)
+	}
+	name, _ := ref["name"].(string)
+	key, _ := ref["key"].(string)
+	if name == "" || key == "" {
+		return "", fmt.Errorf("%s requires name and key", refKey)
+	}
+	if r.Client == nil {
+		return "", fmt.Errorf("%s cannot be resolved without a cluster connection", refKey)
+	}
+
+	var configMap corev1.ConfigMap
+	if err := r.apiReader().Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, &configMap); err != nil {
+		return "", fmt.Errorf("failed to get config map %s for %s: %w", sanitizeLogValue(name), refKey, err)
+	}
+	value, ok := configMap.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in config map %s", sanitizeLogValue(key), sanitizeLogValue(name))
+	}
+	return value, nil
+}
+
+// apiReader returns the reader for objects that are not watched, so reading them does not start an
+// informer that needs list and watch permissions
+func (r *SecretSantaReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+// createOutputMedia creates the media that receives the output of a media such as sealed-secret
+func (r *SecretSantaReconciler) createOutputMedia(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, rawOutput interface{}) (media.Media, error) {
+	output, ok := rawOutput.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("output must be an object with type and config")
+	}
+	outputType, _ := output["type"].(string)
+	if outputType == "" || outputType == "sealed-secret" {
+		return nil, fmt.Errorf("output requires a type other than sealed-secret")
+	}
+	outputConfig, err := json.Marshal(output["config"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output config: %w", err)
+	}
+
+	outputSecretSanta := secretSanta.DeepCopy()
+	outputSecretSanta.Spec.Media = &secretsantav1alpha1.MediaConfig{
+		Type:   outputType,
+		Config: &runtime.RawExtension{Raw: outputConfig},
+	}
+	outputMedia, err := r.createMedia(ctx, outputSecretSanta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output media: %w", err)
+	}
+	return outputMedia, nil
+}
+
 func (r *SecretSantaReconciler) validateTemplate(tmplStr string) error {
 	return validation.ValidateTemplate(tmplStr)
 }
@@ -429,6 +1084,31 @@ func (r *SecretSantaReconciler) updateStatus(ctx context.Context, secretSanta *s
 	return nil
 }
 
+// entropyContext returns ctx with the insecure deterministic source for secretSanta when a seed is
+// configured. The seed is combined with the namespace and name so every resource gets its own stream.
+func (r *SecretSantaReconciler) entropyContext(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta) context.Context {
+	if r.DeterministicSeed == "" {
+		return ctx
+	}
+	return entropy.WithReader(ctx, entropy.NewInsecureDeterministic(r.DeterministicSeed+"/"+secretSanta.Namespace+"/"+secretSanta.Name))
+}
+
+// Render validates secretSanta and returns the unmasked template output without a cluster. It is used
+// by the offline render command, so generators that read Secret references fail.
+func (r *SecretSantaReconciler) Render(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta) (string, error) {
+	if err := validation.ValidateTemplate(secretSanta.Spec.Template); err != nil {
+		return "", fmt.Errorf("template validation failed: %w", err)
+	}
+	if err := validation.ValidateGeneratorConfigs(secretSanta.Spec.Generators); err != nil {
+		return "", fmt.Errorf("generator validation failed: %w", err)
+	}
+	templateData, err := r.generateTemplateData(r.entropyContext(ctx, secretSanta), secretSanta.Namespace, secretSanta.Spec.Generators)
+	if err != nil {
+		return "", err
+	}
+	return r.executeTemplate(secretSanta.Spec.Template, templateData)
+}
+
 func (r *SecretSantaReconciler) handleDryRun(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 	log.Info("Running dry-run with masked output")
@@ -452,7 +1132,8 @@ func (r *SecretSantaReconciler) handleDryRun(ctx context.Context, secretSanta *s
 	}
 
 	// Generate template data
-	templateData, err := r.generateTemplateData(secretSanta.Spec.Generators)
+	ctx = r.entropyContext(ctx, secretSanta)
+	templateData, err := r.generateTemplateData(ctx, secretSanta.Namespace, secretSanta.Spec.Generators)
 	if err != nil {
 		log.Error(err, "Failed to generate template data for dry-run")
 		if updateErr := r.updateStatus(ctx, secretSanta, "DryRunFailed", "False", err.Error()); updateErr != nil {
@@ -522,6 +1203,8 @@ func (r *SecretSantaReconciler) SetupWithManager(mgr ctrl.Manager, maxConcurrent
 	}
 	err := ctrl.NewControllerManagedBy(mgr).
 		For(&secretsantav1alpha1.SecretSanta{}).
+		// Namespaces that start or stop accepting replicas update the replicas of the SecretSantas targeting them
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.secretSantasForNamespace)).
 		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles}).
 		Complete(r)
 	if err != nil {
@@ -530,6 +1213,39 @@ func (r *SecretSantaReconciler) SetupWithManager(mgr ctrl.Manager, maxConcurrent
 	return nil
 }
 
+// secretSantasForNamespace maps a namespace to the SecretSantas that may replicate into it: those
+// listing it in namespaces and all with a namespace_selector, as its labels may have just changed
+func (r *SecretSantaReconciler) secretSantasForNamespace(ctx context.Context, namespace client.Object) []reconcile.Request {
+	var secretSantas secretsantav1alpha1.SecretSantaList
+	if err := r.List(ctx, &secretSantas); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list SecretSantas for namespace", "namespace", namespace.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range secretSantas.Items {
+		secretSanta := &secretSantas.Items[i]
+		if secretSanta.Spec.Media == nil || (secretSanta.Spec.Media.Type != "k8s" && secretSanta.Spec.Media.Type != "") {
+			continue
+		}
+		config, err := mediaConfig(secretSanta)
+		if err != nil {
+			continue
+		}
+		targeted := config["namespace_selector"] != nil
+		if namespaces, ok := config["namespaces"].([]interface{}); ok {
+			for _, name := range namespaces {
+				if name == namespace.GetName() {
+					targeted = true
+				}
+			}
+		}
+		if targeted {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secretSanta)})
+		}
+	}
+	return requests
+}
+
 // sanitizeLogValue removes potentially dangerous characters from log values to prevent log injection
 func sanitizeLogValue(value string) string {
 	// Remove all control characters (ASCII < 32) to prevent log injection
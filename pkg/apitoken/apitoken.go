@@ -0,0 +1,172 @@
+// Package apitoken implements prefixed API tokens with an embedded checksum.
+//
+// A token is the prefix, followed by the random part and the checksum of the random part, both
+// encoded in the token alphabet with a fixed width. With the defaults (base62, crc32) this is the
+// layout GitHub uses for ghp_ tokens, so scanners can reject random strings without a lookup.
+package apitoken
+
+import (
+	"crypto/rand"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MinEntropyBytes is the smallest random part, 128 bits
+	MinEntropyBytes = 16
+	// MaxEntropyBytes is the largest random part
+	MaxEntropyBytes = 64
+)
+
+// Alphabets are the named token alphabets
+var Alphabets = map[string]string{
+	"base62": "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+	"base58": "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz",
+	"base32": "abcdefghijklmnopqrstuvwxyz234567",
+	"hex":    "0123456789abcdef",
+}
+
+// Checksums are the supported checksum algorithms, none disables the checksum
+var Checksums = map[string]func([]byte) uint32{
+	"crc32":  crc32.ChecksumIEEE,
+	"crc32c": func(data []byte) uint32 { return crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)) },
+	"none":   nil,
+}
+
+var (
+	prefixPattern   = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+	alphabetPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{2,64}$`)
+)
+
+// Format describes the layout of a token
+type Format struct {
+	// Prefix identifies the token type, for example "ghp_"
+	Prefix string
+	// Alphabet is a named alphabet or a custom set of characters
+	Alphabet string
+	// Checksum is the checksum algorithm
+	Checksum string
+	// EntropyBytes is the size of the random part. Validate accepts any size when zero.
+	EntropyBytes int
+}
+
+// Generate creates a token, reading the random part from random or crypto/rand when random is nil
+func (f Format) Generate(random io.Reader) (string, error) {
+	alphabet, checksum, err := f.resolve()
+	if err != nil {
+		return "", err
+	}
+	if f.EntropyBytes < MinEntropyBytes || f.EntropyBytes > MaxEntropyBytes {
+		return "", fmt.Errorf("entropy_bytes must be between %d and %d, got: %d", MinEntropyBytes, MaxEntropyBytes, f.EntropyBytes)
+	}
+	if random == nil {
+		random = rand.Reader
+	}
+
+	entropy := make([]byte, f.EntropyBytes)
+	if _, err := io.ReadFull(random, entropy); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+
+	body := encode(new(big.Int).SetBytes(entropy), width(f.EntropyBytes*8, len(alphabet)), alphabet)
+	return f.Prefix + body + checksumSuffix(body, alphabet, checksum), nil
+}
+
+// Validate checks the prefix, alphabet, length and checksum of token
+func (f Format) Validate(token string) error {
+	alphabet, checksum, err := f.resolve()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(token, f.Prefix) {
+		return fmt.Errorf("token does not start with prefix %s", f.Prefix)
+	}
+	body := strings.TrimPrefix(token, f.Prefix)
+
+	for _, c := range body {
+		if !strings.ContainsRune(alphabet, c) {
+			return fmt.Errorf("token contains a character outside the %s alphabet", f.Alphabet)
+		}
+	}
+
+	checksumWidth := 0
+	if checksum != nil {
+		checksumWidth = width(32, len(alphabet))
+	}
+	randomWidth := len(body) - checksumWidth
+	if f.EntropyBytes > 0 {
+		if want := width(f.EntropyBytes*8, len(alphabet)); randomWidth != want {
+			return fmt.Errorf("token has the wrong length, want %d characters after the prefix", want+checksumWidth)
+		}
+	} else if randomWidth < width(MinEntropyBytes*8, len(alphabet)) {
+		return fmt.Errorf("token is too short")
+	}
+
+	if checksum != nil && checksumSuffix(body[:randomWidth], alphabet, checksum) != body[randomWidth:] {
+		return fmt.Errorf("token checksum does not match")
+	}
+	return nil
+}
+
+// resolve returns the alphabet characters and checksum function of the format
+func (f Format) resolve() (string, func([]byte) uint32, error) {
+	if !prefixPattern.MatchString(f.Prefix) {
+		return "", nil, fmt.Errorf("prefix must be 1-32 characters of A-Z, a-z, 0-9, _ or -, got: %q", f.Prefix)
+	}
+
+	alphabet, ok := Alphabets[f.Alphabet]
+	if !ok {
+		alphabet = f.Alphabet
+		if !alphabetPattern.MatchString(alphabet) {
+			return "", nil, fmt.Errorf("alphabet must be base62, base58, base32, hex or 2-64 characters of A-Z, a-z, 0-9, _ or -")
+		}
+		for i := range alphabet {
+			if strings.IndexByte(alphabet, alphabet[i]) != i {
+				return "", nil, fmt.Errorf("alphabet contains duplicate character %q", alphabet[i])
+			}
+		}
+	}
+
+	checksum, ok := Checksums[f.Checksum]
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported checksum: %s (supported: crc32, crc32c, none)", f.Checksum)
+	}
+	return alphabet, checksum, nil
+}
+
+// checksumSuffix encodes the checksum of body, or returns an empty string without checksum
+func checksumSuffix(body, alphabet string, checksum func([]byte) uint32) string {
+	if checksum == nil {
+		return ""
+	}
+	return encode(big.NewInt(int64(checksum([]byte(body)))), width(32, len(alphabet)), alphabet)
+}
+
+// width returns the number of characters needed to encode any value of bits bits in base
+func width(bits, base int) int {
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	power := big.NewInt(1)
+	b := big.NewInt(int64(base))
+	n := 0
+	for power.Cmp(limit) < 0 {
+		power.Mul(power, b)
+		n++
+	}
+	return n
+}
+
+// encode writes value in the alphabet, left-padded to width characters
+func encode(value *big.Int, width int, alphabet string) string {
+	out := make([]byte, width)
+	base := big.NewInt(int64(len(alphabet)))
+	mod := new(big.Int)
+	for i := width - 1; i >= 0; i-- {
+		value.DivMod(value, base, mod)
+		out[i] = alphabet[mod.Int64()]
+	}
+	return string(out)
+}
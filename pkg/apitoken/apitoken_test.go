@@ -0,0 +1,142 @@
+package apitoken
+
+import (
+	"bytes"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func TestGenerateValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		length int
+	}{
+		{
+			name:   "github style",
+			format: Format{Prefix: "ghp_", Alphabet: "base62", Checksum: "crc32", EntropyBytes: 22},
+			length: 4 + 30 + 6,
+		},
+		{
+			name:   "base58 crc32c",
+			format: Format{Prefix: "sk_live_", Alphabet: "base58", Checksum: "crc32c", EntropyBytes: 32},
+			length: 8 + 44 + 6,
+		},
+		{
+			name:   "hex without checksum",
+			format: Format{Prefix: "tok-", Alphabet: "hex", Checksum: "none", EntropyBytes: 16},
+			length: 4 + 32,
+		},
+		{
+			name:   "custom alphabet",
+			format: Format{Prefix: "x_", Alphabet: "abcdefgh", Checksum: "crc32", EntropyBytes: 18},
+			length: 2 + 48 + 11,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.format.Generate(nil)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if len(token) != tt.length {
+				t.Errorf("Generate() length = %d, want %d (%s)", len(token), tt.length, token)
+			}
+			if err := tt.format.Validate(token); err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+
+			// Validation without a known entropy size still checks the checksum
+			lenient := tt.format
+			lenient.EntropyBytes = 0
+			if err := lenient.Validate(token); err != nil {
+				t.Errorf("Validate() without entropy_bytes error = %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateDeterministic(t *testing.T) {
+	format := Format{Prefix: "ghp_", Alphabet: "base62", Checksum: "crc32", EntropyBytes: 16}
+	token, err := format.Generate(bytes.NewReader(make([]byte, 16)))
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	body := strings.Repeat("0", 22)
+	want := "ghp_" + body + encodeUint32(crc32.ChecksumIEEE([]byte(body)))
+	if token != want {
+		t.Errorf("Generate() = %s, want %s", token, want)
+	}
+}
+
+func encodeUint32(v uint32) string {
+	const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	out := make([]byte, 6)
+	for i := 5; i >= 0; i-- {
+		out[i] = alphabet[v%62]
+		v /= 62
+	}
+	return string(out)
+}
+
+func TestValidateErrors(t *testing.T) {
+	format := Format{Prefix: "ghp_", Alphabet: "base62", Checksum: "crc32", EntropyBytes: 22}
+	token, err := format.Generate(nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	// Flip one character of the random part
+	flipped := []byte(token)
+	if flipped[5] == 'a' {
+		flipped[5] = 'b'
+	} else {
+		flipped[5] = 'a'
+	}
+
+	tests := []struct {
+		name   string
+		format Format
+		token  string
+	}{
+		{name: "wrong prefix", format: format, token: "gho_" + strings.TrimPrefix(token, "ghp_")},
+		{name: "bad checksum", format: format, token: string(flipped)},
+		{name: "truncated", format: format, token: token[:len(token)-1]},
+		{name: "invalid character", format: format, token: token[:10] + "!" + token[11:]},
+		{name: "too short without length", format: Format{Prefix: "ghp_", Alphabet: "base62", Checksum: "crc32"}, token: "ghp_abc"},
+		{name: "invalid prefix", format: Format{Prefix: "a b", Alphabet: "base62", Checksum: "crc32"}, token: token},
+		{name: "duplicate alphabet", format: Format{Prefix: "ghp_", Alphabet: "aab", Checksum: "crc32"}, token: token},
+		{name: "unknown checksum", format: Format{Prefix: "ghp_", Alphabet: "base62", Checksum: "md5"}, token: token},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.format.Validate(tt.token); err == nil {
+				t.Errorf("Validate(%s) expected error", tt.token)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+	}{
+		{name: "missing prefix", format: Format{Alphabet: "base62", Checksum: "crc32", EntropyBytes: 24}},
+		{name: "too little entropy", format: Format{Prefix: "t_", Alphabet: "base62", Checksum: "crc32", EntropyBytes: 8}},
+		{name: "too much entropy", format: Format{Prefix: "t_", Alphabet: "base62", Checksum: "crc32", EntropyBytes: 65}},
+		{name: "invalid alphabet", format: Format{Prefix: "t_", Alphabet: "a", Checksum: "crc32", EntropyBytes: 24}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.format.Generate(nil); err == nil {
+				t.Error("Generate() expected error")
+			}
+		})
+	}
+}
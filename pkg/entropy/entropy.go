@@ -0,0 +1,136 @@
+// Package entropy provides the randomness source of the generators.
+//
+// Production code always reads from crypto/rand. The seeded source returned by
+// NewInsecureDeterministic exists for golden tests, the offline render command and the
+// --insecure-deterministic dry-run flag. Anyone who knows the seed can recreate every value, so it
+// must never be used for secrets that are stored.
+package entropy
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+type contextKey struct{}
+
+// WithReader returns a context whose generators read randomness from r
+func WithReader(ctx context.Context, r io.Reader) context.Context {
+	return context.WithValue(ctx, contextKey{}, r)
+}
+
+// FromContext returns the randomness source of ctx, crypto/rand when none is set
+func FromContext(ctx context.Context) io.Reader {
+	if r, ok := ctx.Value(contextKey{}).(io.Reader); ok && r != nil {
+		return r
+	}
+	return rand.Reader
+}
+
+// deterministicReader is a ChaCha20 keystream, safe for concurrent use
+type deterministicReader struct {
+	mu     sync.Mutex
+	cipher *chacha20.Cipher
+}
+
+// NewInsecureDeterministic returns a reader that produces the same byte stream for the same seed.
+// The stream is the ChaCha20 keystream keyed by the SHA-256 of the seed.
+func NewInsecureDeterministic(seed string) io.Reader {
+	key := sha256.Sum256([]byte(seed))
+	cipher, err := chacha20.NewUnauthenticatedCipher(key[:], make([]byte, chacha20.NonceSize))
+	if err != nil {
+		// Key and nonce sizes are fixed, so this cannot happen
+		panic(err)
+	}
+	return &deterministicReader{cipher: cipher}
+}
+
+func (r *deterministicReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(p)
+	r.cipher.XORKeyStream(p, p)
+	return len(p), nil
+}
+
+// RSAKey generates an RSA key from random. For readers other than crypto/rand, crypto/rsa reads one
+// extra byte with 50% probability so callers cannot depend on its output. Single byte reads are
+// answered without consuming random, which keeps deterministic readers reproducible.
+func RSAKey(random io.Reader, bits int) (*rsa.PrivateKey, error) {
+	if random == rand.Reader {
+		return rsa.GenerateKey(random, bits)
+	}
+	return rsa.GenerateKey(singleByteSink{random}, bits)
+}
+
+type singleByteSink struct {
+	io.Reader
+}
+
+func (s singleByteSink) Read(p []byte) (int, error) {
+	if len(p) == 1 {
+		p[0] = 0
+		return 1, nil
+	}
+	return s.Reader.Read(p)
+}
+
+// ECDSAKey generates an ECDSA key from random. crypto/ecdsa adds its own randomness to readers other
+// than crypto/rand, so the scalar is read directly to keep deterministic readers reproducible.
+func ECDSAKey(curve elliptic.Curve, random io.Reader) (*ecdsa.PrivateKey, error) {
+	if random == rand.Reader {
+		return ecdsa.GenerateKey(curve, random)
+	}
+	bits := curve.Params().BitSize
+	scalar := make([]byte, (bits+7)/8)
+	for range 100 {
+		if _, err := io.ReadFull(random, scalar); err != nil {
+			return nil, err
+		}
+		scalar[0] &= 0xff >> (len(scalar)*8 - bits)
+		// Reject scalars outside [1, n-1] and retry, like the standard library
+		if key, err := ecdsa.ParseRawPrivateKey(curve, scalar); err == nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to generate ECDSA key: too many rejected scalars")
+}
+
+// ECDHKey generates an ECDH key from random, see ECDSAKey
+func ECDHKey(curve ecdh.Curve, random io.Reader) (*ecdh.PrivateKey, error) {
+	if random == rand.Reader {
+		return curve.GenerateKey(random)
+	}
+	size := 32
+	switch curve {
+	case ecdh.P256():
+		size = 32
+	case ecdh.P384():
+		size = 48
+	case ecdh.P521():
+		size = 66
+	}
+	scalar := make([]byte, size)
+	for range 100 {
+		if _, err := io.ReadFull(random, scalar); err != nil {
+			return nil, err
+		}
+		if curve == ecdh.P521() {
+			// P-521 scalars are 521 bits, clear the unused top bits
+			scalar[0] &= 0x01
+		}
+		if key, err := curve.NewPrivateKey(scalar); err == nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to generate ECDH key: too many rejected scalars")
+}
@@ -0,0 +1,82 @@
+package entropy
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != rand.Reader {
+		t.Error("FromContext() without a reader should return crypto/rand")
+	}
+	r := NewInsecureDeterministic("seed")
+	if FromContext(WithReader(context.Background(), r)) != r {
+		t.Error("FromContext() did not return the injected reader")
+	}
+	if FromContext(WithReader(context.Background(), nil)) != rand.Reader {
+		t.Error("FromContext() with a nil reader should return crypto/rand")
+	}
+}
+
+func TestNewInsecureDeterministic(t *testing.T) {
+	read := func(seed string) []byte {
+		r := NewInsecureDeterministic(seed)
+		out := make([]byte, 96)
+		// Split reads must continue the same stream
+		if _, err := io.ReadFull(r, out[:33]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadFull(r, out[33:]); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	if !bytes.Equal(read("a"), read("a")) {
+		t.Error("same seed produced different output")
+	}
+	if bytes.Equal(read("a"), read("b")) {
+		t.Error("different seeds produced the same output")
+	}
+}
+
+func TestKeys(t *testing.T) {
+	rsa1, err := RSAKey(NewInsecureDeterministic("k"), 2048)
+	if err != nil {
+		t.Fatalf("RSAKey() error = %v", err)
+	}
+	rsa2, _ := RSAKey(NewInsecureDeterministic("k"), 2048)
+	if !rsa1.Equal(rsa2) {
+		t.Error("RSAKey() differs for the same seed")
+	}
+
+	for _, curve := range []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		key1, err := ECDSAKey(curve, NewInsecureDeterministic("k"))
+		if err != nil {
+			t.Fatalf("ECDSAKey(%s) error = %v", curve.Params().Name, err)
+		}
+		key2, _ := ECDSAKey(curve, NewInsecureDeterministic("k"))
+		if !key1.Equal(key2) {
+			t.Errorf("ECDSAKey(%s) differs for the same seed", curve.Params().Name)
+		}
+		if _, err := ECDSAKey(curve, rand.Reader); err != nil {
+			t.Errorf("ECDSAKey(%s) with crypto/rand error = %v", curve.Params().Name, err)
+		}
+	}
+
+	for _, curve := range []ecdh.Curve{ecdh.X25519(), ecdh.P256(), ecdh.P384(), ecdh.P521()} {
+		key1, err := ECDHKey(curve, NewInsecureDeterministic("k"))
+		if err != nil {
+			t.Fatalf("ECDHKey(%v) error = %v", curve, err)
+		}
+		key2, _ := ECDHKey(curve, NewInsecureDeterministic("k"))
+		if !key1.Equal(key2) {
+			t.Errorf("ECDHKey(%v) differs for the same seed", curve)
+		}
+	}
+}
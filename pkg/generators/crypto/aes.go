@@ -5,11 +5,16 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"io"
 )
 
 type AESKeyGenerator struct{}
 
 func (g *AESKeyGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *AESKeyGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	keySize := getIntConfig(config, "key_size", 256)
 
 	// Validate key size
@@ -27,7 +32,7 @@ func (g *AESKeyGenerator) Generate(config map[string]interface{}) (map[string]st
 
 	// Generate random key
 	key := make([]byte, keyBytes)
-	if _, err := rand.Read(key); err != nil {
+	if _, err := io.ReadFull(random, key); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,108 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+type AgeIdentityGenerator struct{}
+
+func (g *AgeIdentityGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *AgeIdentityGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	// age X25519 identities have no parameters, config parameter reserved for future use
+	_ = config
+
+	// age only generates identities from crypto/rand, so encode the scalar and parse it instead
+	scalar := make([]byte, 32)
+	if _, err := io.ReadFull(random, scalar); err != nil {
+		return nil, fmt.Errorf("failed to generate age identity: %w", err)
+	}
+	identity, err := age.ParseX25519Identity(strings.ToUpper(bech32Encode("age-secret-key-", scalar)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate age identity: %w", err)
+	}
+	recipient := identity.Recipient().String()
+
+	// age has no native fingerprint, so use the SHA-256 of the recipient string
+	fingerprint := sha256.Sum256([]byte(recipient))
+
+	// Same layout as age-keygen output, usable as SOPS_AGE_KEY_FILE
+	identityFile := fmt.Sprintf("# created: %s\n# public key: %s\n%s\n",
+		time.Now().UTC().Format(time.RFC3339), recipient, identity.String())
+
+	return map[string]string{
+		"identity":      identity.String(),
+		"identity_file": identityFile,
+		"recipient":     recipient,
+		"fingerprint":   hex.EncodeToString(fingerprint[:]),
+		"algorithm":     "X25519",
+	}, nil
+}
+
+// bech32Encode encodes data with the BIP 173 bech32 checksum, as used by age keys
+func bech32Encode(hrp string, data []byte) string {
+	// Regroup 8-bit bytes into 5-bit values, padding the last group with zero bits
+	var values []byte
+	acc, bits := 0, 0
+	for _, b := range data {
+		acc = acc<<8 | int(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			values = append(values, byte(acc>>bits&31))
+		}
+	}
+	if bits > 0 {
+		values = append(values, byte(acc<<(5-bits)&31))
+	}
+
+	checksumInput := make([]byte, 0, 2*len(hrp)+1+len(values)+6)
+	for i := 0; i < len(hrp); i++ {
+		checksumInput = append(checksumInput, hrp[i]>>5)
+	}
+	checksumInput = append(checksumInput, 0)
+	for i := 0; i < len(hrp); i++ {
+		checksumInput = append(checksumInput, hrp[i]&31)
+	}
+	checksumInput = append(checksumInput, values...)
+	checksumInput = append(checksumInput, 0, 0, 0, 0, 0, 0)
+	polymod := bech32Polymod(checksumInput) ^ 1
+
+	var out strings.Builder
+	out.WriteString(hrp)
+	out.WriteByte('1')
+	for _, v := range values {
+		out.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		out.WriteByte(bech32Charset[polymod>>uint(5*(5-i))&31])
+	}
+	return out.String()
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
@@ -0,0 +1,50 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestAgeIdentityGenerator_Generate(t *testing.T) {
+	gen := &AgeIdentityGenerator{}
+
+	result, err := gen.Generate(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if !strings.HasPrefix(result["identity"], "AGE-SECRET-KEY-1") {
+		t.Errorf("Generate() identity = %q, want AGE-SECRET-KEY-1 prefix", result["identity"])
+	}
+	if !strings.HasPrefix(result["recipient"], "age1") {
+		t.Errorf("Generate() recipient = %q, want age1 prefix", result["recipient"])
+	}
+
+	identity, err := age.ParseX25519Identity(result["identity"])
+	if err != nil {
+		t.Fatalf("Failed to parse identity: %v", err)
+	}
+	if identity.Recipient().String() != result["recipient"] {
+		t.Error("Generate() recipient does not match identity")
+	}
+
+	identities, err := age.ParseIdentities(strings.NewReader(result["identity_file"]))
+	if err != nil || len(identities) != 1 {
+		t.Errorf("Generate() identity_file is not parseable: %v", err)
+	}
+	if !strings.Contains(result["identity_file"], "# public key: "+result["recipient"]) {
+		t.Error("Generate() identity_file missing public key comment")
+	}
+
+	sum := sha256.Sum256([]byte(result["recipient"]))
+	if result["fingerprint"] != hex.EncodeToString(sum[:]) {
+		t.Errorf("Generate() fingerprint = %q", result["fingerprint"])
+	}
+	if result["algorithm"] != "X25519" {
+		t.Errorf("Generate() algorithm = %q, want X25519", result["algorithm"])
+	}
+}
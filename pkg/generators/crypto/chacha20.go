@@ -4,13 +4,14 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
+	"io"
 )
 
 type ChaCha20KeyGenerator struct{}
 
-func generateStreamCipherKey(algorithm string) (map[string]string, error) {
+func generateStreamCipherKey(algorithm string, random io.Reader) (map[string]string, error) {
 	key := make([]byte, 32)
-	if _, err := rand.Read(key); err != nil {
+	if _, err := io.ReadFull(random, key); err != nil {
 		return nil, err
 	}
 
@@ -23,13 +24,21 @@ func generateStreamCipherKey(algorithm string) (map[string]string, error) {
 }
 
 func (g *ChaCha20KeyGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *ChaCha20KeyGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	// config is unused but required by Generator interface
-	return generateStreamCipherKey("ChaCha20")
+	return generateStreamCipherKey("ChaCha20", random)
 }
 
 type XChaCha20KeyGenerator struct{}
 
 func (g *XChaCha20KeyGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *XChaCha20KeyGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	// config is unused but required by Generator interface
-	return generateStreamCipherKey("XChaCha20")
+	return generateStreamCipherKey("XChaCha20", random)
 }
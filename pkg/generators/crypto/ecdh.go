@@ -7,11 +7,18 @@ import (
 	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"io"
+
+	"github.com/logicIQ/secret-santa/pkg/entropy"
 )
 
 type ECDHKeyGenerator struct{}
 
 func (g *ECDHKeyGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *ECDHKeyGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	curve := getStringConfig(config, "curve", "P256")
 
 	var ecdhCurve ecdh.Curve
@@ -29,7 +36,7 @@ func (g *ECDHKeyGenerator) Generate(config map[string]interface{}) (map[string]s
 	}
 
 	// Generate ECDH key pair
-	privateKey, err := ecdhCurve.GenerateKey(rand.Reader)
+	privateKey, err := entropy.ECDHKey(ecdhCurve, random)
 	if err != nil {
 		return nil, err
 	}
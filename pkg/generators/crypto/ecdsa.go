@@ -1,18 +1,24 @@
 package crypto
 
 import (
-	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"io"
+
+	"github.com/logicIQ/secret-santa/pkg/entropy"
 )
 
 type ECDSAKeyGenerator struct{}
 
 func (g *ECDSAKeyGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *ECDSAKeyGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	curve := getStringConfig(config, "curve", "P256")
 
 	var ellipticCurve elliptic.Curve
@@ -30,7 +36,7 @@ func (g *ECDSAKeyGenerator) Generate(config map[string]interface{}) (map[string]
 	}
 
 	// Generate ECDSA key pair
-	privateKey, err := ecdsa.GenerateKey(ellipticCurve, rand.Reader)
+	privateKey, err := entropy.ECDSAKey(ellipticCurve, random)
 	if err != nil {
 		return nil, err
 	}
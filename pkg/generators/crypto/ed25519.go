@@ -6,16 +6,21 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
+	"io"
 )
 
 type ED25519KeyGenerator struct{}
 
 func (g *ED25519KeyGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *ED25519KeyGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	// ED25519 has fixed key size, config parameter reserved for future use
 	_ = config
 
 	// Generate ED25519 key pair
-	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	publicKey, privateKey, err := ed25519.GenerateKey(random)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,17 @@
 package crypto
 
-import "math"
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"math"
+)
+
+// hmacHashes are the hash algorithms supported by the HMAC based generators
+var hmacHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
 
 func getStringConfig(config map[string]interface{}, key, defaultValue string) string {
 	if config == nil {
@@ -33,3 +44,35 @@ func getIntConfig(config map[string]interface{}, key string, defaultValue int) i
 	}
 	return defaultValue
 }
+
+func getBoolConfig(config map[string]interface{}, key string, defaultValue bool) bool {
+	if config == nil {
+		return defaultValue
+	}
+	if val, ok := config[key].(bool); ok {
+		return val
+	}
+	return defaultValue
+}
+
+// getStringListConfig accepts a single string or a list of strings. Returns nil if the key doesn't exist.
+func getStringListConfig(config map[string]interface{}, key string) []string {
+	if config == nil {
+		return nil
+	}
+	switch val := config[key].(type) {
+	case string:
+		return []string{val}
+	case []string:
+		return val
+	case []interface{}:
+		result := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
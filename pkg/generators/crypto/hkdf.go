@@ -0,0 +1,73 @@
+package crypto
+
+import (
+	"crypto/hkdf"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HKDFGenerator derives keys from a master secret with HKDF (RFC 5869). The same master key, salt,
+// info and length always derive the same key.
+type HKDFGenerator struct{}
+
+func (g *HKDFGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	algorithm := strings.ToLower(strings.TrimSpace(getStringConfig(config, "algorithm", "sha256")))
+	masterKey := getStringConfig(config, "master_key", "")
+	encoding := strings.ToLower(getStringConfig(config, "master_key_encoding", "raw"))
+	salt := getStringConfig(config, "salt", "")
+	info := getStringConfig(config, "info", "")
+	length := getIntConfig(config, "length", 32)
+
+	hashFunc, ok := hmacHashes[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm: %q (supported: sha256, sha512)", algorithm)
+	}
+
+	// Errors never include the master key material, they end up in status conditions
+	if masterKey == "" {
+		return nil, fmt.Errorf("master_key is required, set master_key_secret_ref to a Secret key")
+	}
+	var secret []byte
+	switch encoding {
+	case "raw":
+		secret = []byte(masterKey)
+	case "hex":
+		decoded, err := hex.DecodeString(strings.TrimSpace(masterKey))
+		if err != nil {
+			return nil, fmt.Errorf("master_key is not valid hex")
+		}
+		secret = decoded
+	case "base64":
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(masterKey))
+		if err != nil {
+			return nil, fmt.Errorf("master_key is not valid base64")
+		}
+		secret = decoded
+	default:
+		return nil, fmt.Errorf("unsupported master_key_encoding: %q (supported: raw, hex, base64)", encoding)
+	}
+	if len(secret) < 16 {
+		return nil, fmt.Errorf("master_key too short, minimum 16 bytes")
+	}
+
+	maxLength := 255 * hashFunc().Size()
+	if length < 16 || length > maxLength {
+		return nil, fmt.Errorf("length must be between 16 and %d bytes for %s, got: %d", maxLength, algorithm, length)
+	}
+
+	key, err := hkdf.Key(hashFunc, secret, []byte(salt), info, length)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	return map[string]string{
+		"key_hex":    hex.EncodeToString(key),
+		"key_base64": base64.StdEncoding.EncodeToString(key),
+		"algorithm":  algorithm,
+		"length":     strconv.Itoa(length),
+		"info":       info,
+	}, nil
+}
@@ -0,0 +1,110 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestHKDFGenerator_Generate(t *testing.T) {
+	gen := &HKDFGenerator{}
+
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		wantHex   string
+		length    int
+		wantError bool
+	}{
+		{
+			// RFC 5869 Appendix A.1
+			name: "rfc5869 test case 1",
+			config: map[string]interface{}{
+				"master_key":          "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+				"master_key_encoding": "hex",
+				"salt":                "\x00\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b\x0c",
+				"info":                "\xf0\xf1\xf2\xf3\xf4\xf5\xf6\xf7\xf8\xf9",
+				"length":              42,
+			},
+			wantHex: "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865",
+			length:  42,
+		},
+		{
+			name: "sha512 base64 master key",
+			config: map[string]interface{}{
+				"algorithm":           "sha512",
+				"master_key":          base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")),
+				"master_key_encoding": "base64",
+				"info":                "payments/signing",
+				"length":              64,
+			},
+			length: 64,
+		},
+		{
+			name:      "missing master key",
+			config:    map[string]interface{}{"info": "svc"},
+			wantError: true,
+		},
+		{
+			name:      "master key too short",
+			config:    map[string]interface{}{"master_key": "tiny-key"},
+			wantError: true,
+		},
+		{
+			name:      "invalid hex master key",
+			config:    map[string]interface{}{"master_key": "zz-secret-material-zz", "master_key_encoding": "hex"},
+			wantError: true,
+		},
+		{
+			name:      "unsupported algorithm",
+			config:    map[string]interface{}{"master_key": "0123456789abcdef", "algorithm": "md5"},
+			wantError: true,
+		},
+		{
+			name:      "length too large",
+			config:    map[string]interface{}{"master_key": "0123456789abcdef", "length": 255*32 + 1},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(tt.config)
+			if tt.wantError {
+				if err == nil {
+					t.Fatal("Generate() expected error")
+				}
+				if master, ok := tt.config["master_key"].(string); ok && strings.Contains(err.Error(), master) {
+					t.Errorf("Generate() error leaks master key: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			key, err := hex.DecodeString(result["key_hex"])
+			if err != nil || len(key) != tt.length {
+				t.Fatalf("Generate() key_hex length = %d, want %d", len(key), tt.length)
+			}
+			if tt.wantHex != "" && result["key_hex"] != tt.wantHex {
+				t.Errorf("Generate() key_hex = %s, want %s", result["key_hex"], tt.wantHex)
+			}
+			if result["key_base64"] != base64.StdEncoding.EncodeToString(key) {
+				t.Error("Generate() key_base64 does not match key_hex")
+			}
+			for key, value := range result {
+				if strings.Contains(value, tt.config["master_key"].(string)) {
+					t.Errorf("Generate() output %s echoes master key", key)
+				}
+			}
+
+			// Derivation is deterministic
+			again, err := gen.Generate(tt.config)
+			if err != nil || again["key_hex"] != result["key_hex"] {
+				t.Error("Generate() derivation is not deterministic")
+			}
+		})
+	}
+}
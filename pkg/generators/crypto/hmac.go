@@ -3,18 +3,20 @@ package crypto
 import (
 	"crypto/hmac"
 	"crypto/rand"
-	"crypto/sha256"
-	"crypto/sha512"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
-	"hash"
+	"io"
 	"strings"
 )
 
 type HMACGenerator struct{}
 
 func (g *HMACGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *HMACGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	algorithm := strings.ToLower(strings.TrimSpace(getStringConfig(config, "algorithm", "sha256")))
 	keySize := getIntConfig(config, "key_size", 32)
 	message := getStringConfig(config, "message", "")
@@ -43,18 +45,13 @@ func (g *HMACGenerator) Generate(config map[string]interface{}) (map[string]stri
 
 	// Generate random key if not provided
 	key := make([]byte, keySize)
-	if _, err := rand.Read(key); err != nil {
+	if _, err := io.ReadFull(random, key); err != nil {
 		return nil, fmt.Errorf("failed to generate random key: %w", err)
 	}
 
 	// Select hash function
-	var hashFunc func() hash.Hash
-	switch algorithm {
-	case "sha256":
-		hashFunc = sha256.New
-	case "sha512":
-		hashFunc = sha512.New
-	default:
+	hashFunc, ok := hmacHashes[algorithm]
+	if !ok {
 		return nil, fmt.Errorf("unsupported algorithm: %s (supported: sha256, sha512)", algorithm)
 	}
 
@@ -0,0 +1,106 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/mlkem"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/logicIQ/secret-santa/pkg/entropy"
+)
+
+// x25519MLKEM768GroupID is the TLS NamedGroup codepoint of the X25519MLKEM768 hybrid key exchange
+const x25519MLKEM768GroupID = "0x11EC"
+
+type MLKEMKeyGenerator struct{}
+
+func (g *MLKEMKeyGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *MLKEMKeyGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	parameterSet := strings.ToUpper(strings.TrimSpace(getStringConfig(config, "parameter_set", "ML-KEM-768")))
+
+	// The decapsulation key is stored in the 64-byte seed form of FIPS 203
+	seed := make([]byte, mlkem.SeedSize)
+	if _, err := io.ReadFull(random, seed); err != nil {
+		return nil, fmt.Errorf("failed to generate ML-KEM seed: %w", err)
+	}
+	var decapsulationKey, encapsulationKey []byte
+	switch strings.TrimPrefix(parameterSet, "ML-KEM-") {
+	case "768":
+		key, err := mlkem.NewDecapsulationKey768(seed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ML-KEM-768 key: %w", err)
+		}
+		decapsulationKey, encapsulationKey = key.Bytes(), key.EncapsulationKey().Bytes()
+	case "1024":
+		key, err := mlkem.NewDecapsulationKey1024(seed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ML-KEM-1024 key: %w", err)
+		}
+		decapsulationKey, encapsulationKey = key.Bytes(), key.EncapsulationKey().Bytes()
+	default:
+		return nil, fmt.Errorf("unsupported parameter_set: %s (supported: ML-KEM-768, ML-KEM-1024)", parameterSet)
+	}
+
+	return map[string]string{
+		"decapsulation_key_hex":    hex.EncodeToString(decapsulationKey),
+		"decapsulation_key_base64": base64.StdEncoding.EncodeToString(decapsulationKey),
+		"encapsulation_key_hex":    hex.EncodeToString(encapsulationKey),
+		"encapsulation_key_base64": base64.StdEncoding.EncodeToString(encapsulationKey),
+		"parameter_set":            "ML-KEM-" + strings.TrimPrefix(parameterSet, "ML-KEM-"),
+		"algorithm":                "ML-KEM",
+	}, nil
+}
+
+// HybridKEMKeyGenerator generates an X25519MLKEM768 key pair. The combined keys use the TLS key
+// share layout, ML-KEM-768 first and X25519 second.
+type HybridKEMKeyGenerator struct{}
+
+func (g *HybridKEMKeyGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *HybridKEMKeyGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	// X25519MLKEM768 has fixed parameters, config parameter reserved for future use
+	_ = config
+
+	seed := make([]byte, mlkem.SeedSize)
+	if _, err := io.ReadFull(random, seed); err != nil {
+		return nil, fmt.Errorf("failed to generate ML-KEM seed: %w", err)
+	}
+	mlkemKey, err := mlkem.NewDecapsulationKey768(seed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ML-KEM-768 key: %w", err)
+	}
+	x25519Key, err := entropy.ECDHKey(ecdh.X25519(), random)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate X25519 key: %w", err)
+	}
+
+	mlkemPrivate := mlkemKey.Bytes()
+	mlkemPublic := mlkemKey.EncapsulationKey().Bytes()
+	x25519Private := x25519Key.Bytes()
+	x25519Public := x25519Key.PublicKey().Bytes()
+
+	privateKey := append(append([]byte{}, mlkemPrivate...), x25519Private...)
+	publicKey := append(append([]byte{}, mlkemPublic...), x25519Public...)
+
+	return map[string]string{
+		"private_key_hex":                hex.EncodeToString(privateKey),
+		"private_key_base64":             base64.StdEncoding.EncodeToString(privateKey),
+		"public_key_hex":                 hex.EncodeToString(publicKey),
+		"public_key_base64":              base64.StdEncoding.EncodeToString(publicKey),
+		"mlkem_decapsulation_key_base64": base64.StdEncoding.EncodeToString(mlkemPrivate),
+		"mlkem_encapsulation_key_base64": base64.StdEncoding.EncodeToString(mlkemPublic),
+		"x25519_private_key_base64":      base64.StdEncoding.EncodeToString(x25519Private),
+		"x25519_public_key_base64":       base64.StdEncoding.EncodeToString(x25519Public),
+		"tls_group_id":                   x25519MLKEM768GroupID,
+		"algorithm":                      "X25519MLKEM768",
+	}, nil
+}
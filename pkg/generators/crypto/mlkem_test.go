@@ -0,0 +1,178 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/mlkem"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func TestMLKEMKeyGenerator_Generate(t *testing.T) {
+	gen := &MLKEMKeyGenerator{}
+
+	tests := []struct {
+		name             string
+		config           map[string]interface{}
+		parameterSet     string
+		encapsulationLen int
+		wantErr          bool
+	}{
+		{
+			name:             "default ML-KEM-768",
+			config:           map[string]interface{}{},
+			parameterSet:     "ML-KEM-768",
+			encapsulationLen: mlkem.EncapsulationKeySize768,
+		},
+		{
+			name:             "ML-KEM-1024",
+			config:           map[string]interface{}{"parameter_set": "ML-KEM-1024"},
+			parameterSet:     "ML-KEM-1024",
+			encapsulationLen: mlkem.EncapsulationKeySize1024,
+		},
+		{
+			name:             "short form",
+			config:           map[string]interface{}{"parameter_set": "1024"},
+			parameterSet:     "ML-KEM-1024",
+			encapsulationLen: mlkem.EncapsulationKeySize1024,
+		},
+		{
+			name:    "unsupported parameter set",
+			config:  map[string]interface{}{"parameter_set": "ML-KEM-512"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if result["parameter_set"] != tt.parameterSet {
+				t.Errorf("Generate() parameter_set = %s, want %s", result["parameter_set"], tt.parameterSet)
+			}
+			if result["algorithm"] != "ML-KEM" {
+				t.Errorf("Generate() algorithm = %s, want ML-KEM", result["algorithm"])
+			}
+
+			seed, err := base64.StdEncoding.DecodeString(result["decapsulation_key_base64"])
+			if err != nil {
+				t.Fatalf("Generate() invalid decapsulation key base64: %v", err)
+			}
+			if hex.EncodeToString(seed) != result["decapsulation_key_hex"] {
+				t.Error("Generate() decapsulation key hex and base64 differ")
+			}
+			if len(seed) != mlkem.SeedSize {
+				t.Errorf("Generate() decapsulation key length = %d, want %d", len(seed), mlkem.SeedSize)
+			}
+			encapsulationKey, err := base64.StdEncoding.DecodeString(result["encapsulation_key_base64"])
+			if err != nil {
+				t.Fatalf("Generate() invalid encapsulation key base64: %v", err)
+			}
+			if len(encapsulationKey) != tt.encapsulationLen {
+				t.Errorf("Generate() encapsulation key length = %d, want %d", len(encapsulationKey), tt.encapsulationLen)
+			}
+
+			// Round trip: encapsulate to the public key and decapsulate with the private key
+			var sharedKey, ciphertext, decapsulated []byte
+			if tt.parameterSet == "ML-KEM-768" {
+				ek, err := mlkem.NewEncapsulationKey768(encapsulationKey)
+				if err != nil {
+					t.Fatalf("NewEncapsulationKey768() error = %v", err)
+				}
+				dk, err := mlkem.NewDecapsulationKey768(seed)
+				if err != nil {
+					t.Fatalf("NewDecapsulationKey768() error = %v", err)
+				}
+				sharedKey, ciphertext = ek.Encapsulate()
+				decapsulated, err = dk.Decapsulate(ciphertext)
+				if err != nil {
+					t.Fatalf("Decapsulate() error = %v", err)
+				}
+			} else {
+				ek, err := mlkem.NewEncapsulationKey1024(encapsulationKey)
+				if err != nil {
+					t.Fatalf("NewEncapsulationKey1024() error = %v", err)
+				}
+				dk, err := mlkem.NewDecapsulationKey1024(seed)
+				if err != nil {
+					t.Fatalf("NewDecapsulationKey1024() error = %v", err)
+				}
+				sharedKey, ciphertext = ek.Encapsulate()
+				decapsulated, err = dk.Decapsulate(ciphertext)
+				if err != nil {
+					t.Fatalf("Decapsulate() error = %v", err)
+				}
+			}
+			if !bytes.Equal(sharedKey, decapsulated) {
+				t.Error("Generate() decapsulated shared key does not match")
+			}
+		})
+	}
+}
+
+func TestHybridKEMKeyGenerator_Generate(t *testing.T) {
+	gen := &HybridKEMKeyGenerator{}
+
+	result, err := gen.Generate(nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if result["algorithm"] != "X25519MLKEM768" {
+		t.Errorf("Generate() algorithm = %s, want X25519MLKEM768", result["algorithm"])
+	}
+	if result["tls_group_id"] != "0x11EC" {
+		t.Errorf("Generate() tls_group_id = %s, want 0x11EC", result["tls_group_id"])
+	}
+
+	privateKey, err := base64.StdEncoding.DecodeString(result["private_key_base64"])
+	if err != nil {
+		t.Fatalf("Generate() invalid private key base64: %v", err)
+	}
+	publicKey, err := base64.StdEncoding.DecodeString(result["public_key_base64"])
+	if err != nil {
+		t.Fatalf("Generate() invalid public key base64: %v", err)
+	}
+	if hex.EncodeToString(publicKey) != result["public_key_hex"] {
+		t.Error("Generate() public key hex and base64 differ")
+	}
+	if len(privateKey) != mlkem.SeedSize+32 {
+		t.Fatalf("Generate() private key length = %d, want %d", len(privateKey), mlkem.SeedSize+32)
+	}
+	if len(publicKey) != mlkem.EncapsulationKeySize768+32 {
+		t.Fatalf("Generate() public key length = %d, want %d", len(publicKey), mlkem.EncapsulationKeySize768+32)
+	}
+
+	// The combined keys are ML-KEM-768 followed by X25519
+	dk, err := mlkem.NewDecapsulationKey768(privateKey[:mlkem.SeedSize])
+	if err != nil {
+		t.Fatalf("NewDecapsulationKey768() error = %v", err)
+	}
+	if !bytes.Equal(dk.EncapsulationKey().Bytes(), publicKey[:mlkem.EncapsulationKeySize768]) {
+		t.Error("Generate() ML-KEM encapsulation key does not match decapsulation key")
+	}
+	x25519Key, err := ecdh.X25519().NewPrivateKey(privateKey[mlkem.SeedSize:])
+	if err != nil {
+		t.Fatalf("NewPrivateKey() error = %v", err)
+	}
+	if !bytes.Equal(x25519Key.PublicKey().Bytes(), publicKey[mlkem.EncapsulationKeySize768:]) {
+		t.Error("Generate() X25519 public key does not match private key")
+	}
+
+	x25519Public, _ := base64.StdEncoding.DecodeString(result["x25519_public_key_base64"])
+	if !bytes.Equal(x25519Public, publicKey[mlkem.EncapsulationKeySize768:]) {
+		t.Error("Generate() x25519_public_key_base64 does not match combined public key")
+	}
+	mlkemPublic, _ := base64.StdEncoding.DecodeString(result["mlkem_encapsulation_key_base64"])
+	if !bytes.Equal(mlkemPublic, publicKey[:mlkem.EncapsulationKeySize768]) {
+		t.Error("Generate() mlkem_encapsulation_key_base64 does not match combined public key")
+	}
+}
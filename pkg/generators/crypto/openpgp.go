@@ -0,0 +1,113 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+type OpenPGPKeyGenerator struct{}
+
+func (g *OpenPGPKeyGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *OpenPGPKeyGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	name := getStringConfig(config, "name", "")
+	comment := getStringConfig(config, "comment", "")
+	email := getStringConfig(config, "email", "")
+	algorithm := strings.ToLower(strings.TrimSpace(getStringConfig(config, "algorithm", "ed25519")))
+	rsaBits := getIntConfig(config, "rsa_bits", 3072)
+	expiryDays := getIntConfig(config, "expiry_days", 0)
+	passphrase := getStringConfig(config, "passphrase", "")
+
+	if name == "" && email == "" {
+		return nil, fmt.Errorf("name or email is required for the OpenPGP user ID")
+	}
+	if expiryDays < 0 {
+		return nil, fmt.Errorf("expiry_days must not be negative, got: %d", expiryDays)
+	}
+	if expiryDays > 36500 {
+		return nil, fmt.Errorf("expiry_days too large, maximum 36500, got: %d", expiryDays)
+	}
+
+	pgpConfig := &packet.Config{
+		Rand:            random,
+		KeyLifetimeSecs: uint32(expiryDays) * 24 * 60 * 60,
+	}
+	switch algorithm {
+	case "ed25519":
+		pgpConfig.Algorithm = packet.PubKeyAlgoEdDSA
+		pgpConfig.Curve = packet.Curve25519
+	case "rsa":
+		if rsaBits < 2048 {
+			return nil, fmt.Errorf("RSA key size too small, minimum 2048 bits, got: %d", rsaBits)
+		}
+		if rsaBits > 8192 {
+			return nil, fmt.Errorf("RSA key size too large, maximum 8192 bits, got: %d", rsaBits)
+		}
+		pgpConfig.Algorithm = packet.PubKeyAlgoRSA
+		pgpConfig.RSABits = rsaBits
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s (supported: ed25519, rsa)", algorithm)
+	}
+
+	entity, err := openpgp.NewEntity(name, comment, email, pgpConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OpenPGP key: %w", err)
+	}
+
+	// Encrypt private key material after the self-signatures have been created
+	if passphrase != "" {
+		if err := entity.EncryptPrivateKeys([]byte(passphrase), pgpConfig); err != nil {
+			return nil, fmt.Errorf("failed to encrypt private key: %w", err)
+		}
+	}
+
+	var privateKey bytes.Buffer
+	privateWriter, err := armor.Encode(&privateKey, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to armor private key: %w", err)
+	}
+	if err := entity.SerializePrivateWithoutSigning(privateWriter, pgpConfig); err != nil {
+		return nil, fmt.Errorf("failed to serialize private key: %w", err)
+	}
+	if err := privateWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to armor private key: %w", err)
+	}
+
+	var publicKey bytes.Buffer
+	publicWriter, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to armor public key: %w", err)
+	}
+	if err := entity.Serialize(publicWriter); err != nil {
+		return nil, fmt.Errorf("failed to serialize public key: %w", err)
+	}
+	if err := publicWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to armor public key: %w", err)
+	}
+
+	var userID string
+	for id := range entity.Identities {
+		userID = id
+	}
+
+	return map[string]string{
+		"private_key_armored": privateKey.String() + "\n",
+		"public_key_armored":  publicKey.String() + "\n",
+		"fingerprint":         strings.ToUpper(fmt.Sprintf("%x", entity.PrimaryKey.Fingerprint)),
+		"key_id":              entity.PrimaryKey.KeyIdString(),
+		"user_id":             userID,
+		"algorithm":           strings.ToUpper(algorithm),
+		"expiry_days":         strconv.Itoa(expiryDays),
+		"encrypted":           strconv.FormatBool(passphrase != ""),
+	}, nil
+}
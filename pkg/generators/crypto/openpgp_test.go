@@ -0,0 +1,125 @@
+package crypto
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+func TestOpenPGPKeyGenerator_Generate(t *testing.T) {
+	gen := &OpenPGPKeyGenerator{}
+
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		wantError bool
+		algorithm string
+		userID    string
+		encrypted bool
+	}{
+		{
+			name:      "default ed25519",
+			config:    map[string]interface{}{"name": "Backup", "email": "backup@example.com"},
+			algorithm: "ED25519",
+			userID:    "Backup <backup@example.com>",
+		},
+		{
+			name: "rsa with comment and expiry",
+			config: map[string]interface{}{
+				"name":        "Ops",
+				"comment":     "ci",
+				"email":       "ops@example.com",
+				"algorithm":   "rsa",
+				"rsa_bits":    2048,
+				"expiry_days": 365,
+			},
+			algorithm: "RSA",
+			userID:    "Ops (ci) <ops@example.com>",
+		},
+		{
+			name: "with passphrase",
+			config: map[string]interface{}{
+				"email":      "sops@example.com",
+				"passphrase": "correct horse battery staple",
+			},
+			algorithm: "ED25519",
+			userID:    "<sops@example.com>",
+			encrypted: true,
+		},
+		{
+			name:      "missing user id",
+			config:    map[string]interface{}{},
+			wantError: true,
+		},
+		{
+			name:      "rsa too small",
+			config:    map[string]interface{}{"name": "x", "algorithm": "rsa", "rsa_bits": 1024},
+			wantError: true,
+		},
+		{
+			name:      "unsupported algorithm",
+			config:    map[string]interface{}{"name": "x", "algorithm": "dsa"},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(tt.config)
+			if tt.wantError {
+				if err == nil {
+					t.Error("Generate() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			if result["algorithm"] != tt.algorithm {
+				t.Errorf("Generate() algorithm = %q, want %q", result["algorithm"], tt.algorithm)
+			}
+			if result["user_id"] != tt.userID {
+				t.Errorf("Generate() user_id = %q, want %q", result["user_id"], tt.userID)
+			}
+			if result["encrypted"] != fmt.Sprint(tt.encrypted) {
+				t.Errorf("Generate() encrypted = %q, want %v", result["encrypted"], tt.encrypted)
+			}
+
+			keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(result["private_key_armored"]))
+			if err != nil || len(keyring) != 1 {
+				t.Fatalf("Failed to read private key: %v", err)
+			}
+			entity := keyring[0]
+			if entity.PrivateKey == nil {
+				t.Fatal("Generate() private_key_armored has no private key")
+			}
+			if entity.PrivateKey.Encrypted != tt.encrypted {
+				t.Errorf("private key encrypted = %v, want %v", entity.PrivateKey.Encrypted, tt.encrypted)
+			}
+			if tt.encrypted {
+				if err := entity.DecryptPrivateKeys([]byte(tt.config["passphrase"].(string))); err != nil {
+					t.Errorf("Failed to decrypt private key: %v", err)
+				}
+			}
+
+			publicRing, err := openpgp.ReadArmoredKeyRing(strings.NewReader(result["public_key_armored"]))
+			if err != nil || len(publicRing) != 1 {
+				t.Fatalf("Failed to read public key: %v", err)
+			}
+			if publicRing[0].PrivateKey != nil {
+				t.Error("Generate() public_key_armored contains private key material")
+			}
+
+			fingerprint := strings.ToUpper(fmt.Sprintf("%x", publicRing[0].PrimaryKey.Fingerprint))
+			if result["fingerprint"] != fingerprint {
+				t.Errorf("Generate() fingerprint = %q, want %q", result["fingerprint"], fingerprint)
+			}
+			if !strings.HasSuffix(result["fingerprint"], result["key_id"]) {
+				t.Errorf("Generate() key_id %q is not the fingerprint suffix", result["key_id"])
+			}
+		})
+	}
+}
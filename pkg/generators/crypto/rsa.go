@@ -2,16 +2,22 @@ package crypto
 
 import (
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"io"
+
+	"github.com/logicIQ/secret-santa/pkg/entropy"
 )
 
 type RSAKeyGenerator struct{}
 
 func (g *RSAKeyGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *RSAKeyGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	keySize := getIntConfig(config, "key_size", 2048)
 
 	// Validate key size
@@ -26,7 +32,7 @@ func (g *RSAKeyGenerator) Generate(config map[string]interface{}) (map[string]st
 	}
 
 	// Generate RSA key pair
-	privateKey, err := rsa.GenerateKey(rand.Reader, keySize)
+	privateKey, err := entropy.RSAKey(random, keySize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate RSA key: %w", err)
 	}
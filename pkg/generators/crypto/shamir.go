@@ -0,0 +1,68 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/logicIQ/secret-santa/pkg/shamir"
+)
+
+// ShamirSplitGenerator splits a secret into N-of-M Shamir shares, output as share_1..share_M
+type ShamirSplitGenerator struct{}
+
+func (g *ShamirSplitGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *ShamirSplitGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	secretValue := getStringConfig(config, "secret", "")
+	encoding := strings.ToLower(getStringConfig(config, "secret_encoding", "raw"))
+	parts := getIntConfig(config, "shares", 5)
+	threshold := getIntConfig(config, "threshold", 3)
+
+	// Errors never include the secret, they end up in status conditions
+	if secretValue == "" {
+		return nil, fmt.Errorf("secret is required, reference another generator or set secret_secret_ref")
+	}
+	var secret []byte
+	switch encoding {
+	case "raw":
+		secret = []byte(secretValue)
+	case "hex":
+		decoded, err := hex.DecodeString(strings.TrimSpace(secretValue))
+		if err != nil {
+			return nil, fmt.Errorf("secret is not valid hex")
+		}
+		secret = decoded
+	case "base64":
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(secretValue))
+		if err != nil {
+			return nil, fmt.Errorf("secret is not valid base64")
+		}
+		secret = decoded
+	default:
+		return nil, fmt.Errorf("unsupported secret_encoding: %q (supported: raw, hex, base64)", encoding)
+	}
+	if len(secret) > 65536 {
+		return nil, fmt.Errorf("secret too large, maximum 65536 bytes")
+	}
+
+	shares, err := shamir.Split(secret, parts, threshold, random)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]string{
+		"shares":    strconv.Itoa(parts),
+		"threshold": strconv.Itoa(threshold),
+	}
+	for i, share := range shares {
+		result["share_"+strconv.Itoa(i+1)] = base64.StdEncoding.EncodeToString(share)
+	}
+	return result, nil
+}
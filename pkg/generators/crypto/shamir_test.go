@@ -0,0 +1,97 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strconv"
+	"testing"
+
+	"github.com/logicIQ/secret-santa/pkg/shamir"
+)
+
+func TestShamirSplitGenerator_Generate(t *testing.T) {
+	gen := &ShamirSplitGenerator{}
+
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		secret    []byte
+		shares    int
+		threshold int
+		wantError bool
+	}{
+		{
+			name:      "defaults",
+			config:    map[string]interface{}{"secret": "unseal-key-material"},
+			secret:    []byte("unseal-key-material"),
+			shares:    5,
+			threshold: 3,
+		},
+		{
+			name: "hex secret 2 of 3",
+			config: map[string]interface{}{
+				"secret":          "00ff10",
+				"secret_encoding": "hex",
+				"shares":          3,
+				"threshold":       2,
+			},
+			secret:    []byte{0x00, 0xff, 0x10},
+			shares:    3,
+			threshold: 2,
+		},
+		{
+			name:      "missing secret",
+			config:    map[string]interface{}{},
+			wantError: true,
+		},
+		{
+			name:      "threshold above shares",
+			config:    map[string]interface{}{"secret": "s", "shares": 2, "threshold": 3},
+			wantError: true,
+		},
+		{
+			name:      "invalid base64 secret",
+			config:    map[string]interface{}{"secret": "!!", "secret_encoding": "base64"},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(tt.config)
+			if tt.wantError {
+				if err == nil {
+					t.Error("Generate() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			if result["shares"] != strconv.Itoa(tt.shares) || result["threshold"] != strconv.Itoa(tt.threshold) {
+				t.Errorf("Generate() shares/threshold = %s/%s", result["shares"], result["threshold"])
+			}
+
+			var shares [][]byte
+			for i := 1; i <= tt.shares; i++ {
+				share, err := base64.StdEncoding.DecodeString(result["share_"+strconv.Itoa(i)])
+				if err != nil {
+					t.Fatalf("share_%d is not base64: %v", i, err)
+				}
+				shares = append(shares, share)
+			}
+			if _, ok := result["share_"+strconv.Itoa(tt.shares+1)]; ok {
+				t.Error("Generate() returned too many shares")
+			}
+
+			recovered, err := shamir.Combine(shares[len(shares)-tt.threshold:])
+			if err != nil {
+				t.Fatalf("Combine() error = %v", err)
+			}
+			if !bytes.Equal(recovered, tt.secret) {
+				t.Errorf("Combine() = %x, want %x", recovered, tt.secret)
+			}
+		})
+	}
+}
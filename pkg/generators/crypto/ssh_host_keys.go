@@ -0,0 +1,161 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/logicIQ/secret-santa/pkg/entropy"
+)
+
+// sshHostKeyAlgorithms lists the supported host key types in the order sshd loads them
+var sshHostKeyAlgorithms = []string{"rsa", "ecdsa", "ed25519"}
+
+// SSHHostKeysGenerator generates a set of sshd host keys and the matching known_hosts entries
+type SSHHostKeysGenerator struct{}
+
+func (g *SSHHostKeysGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *SSHHostKeysGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	algorithms := getStringListConfig(config, "algorithms")
+	if len(algorithms) == 0 {
+		algorithms = sshHostKeyAlgorithms
+	}
+	rsaBits := getIntConfig(config, "rsa_bits", 3072)
+	comment := getStringConfig(config, "comment", "")
+	hostnames := getStringListConfig(config, "hostnames")
+	port := getIntConfig(config, "port", 22)
+	hashKnownHosts := getBoolConfig(config, "hash_known_hosts", false)
+
+	if rsaBits < 2048 {
+		return nil, fmt.Errorf("RSA key size too small, minimum 2048 bits, got: %d", rsaBits)
+	}
+	if rsaBits > 8192 {
+		return nil, fmt.Errorf("RSA key size too large, maximum 8192 bits, got: %d", rsaBits)
+	}
+	if port < 1 || port > 65535 {
+		return nil, fmt.Errorf("port must be between 1 and 65535, got: %d", port)
+	}
+
+	// known_hosts stores non-default ports as [host]:port
+	var hosts []string
+	for _, hostname := range hostnames {
+		hostname = strings.TrimSpace(hostname)
+		if hostname == "" || strings.ContainsAny(hostname, ", \t") {
+			return nil, fmt.Errorf("invalid hostname: %q", hostname)
+		}
+		hosts = append(hosts, knownhosts.Normalize(net.JoinHostPort(hostname, strconv.Itoa(port))))
+	}
+
+	result := map[string]string{}
+	var generated, knownHosts []string
+	seen := map[string]bool{}
+	for _, algorithm := range algorithms {
+		algorithm = strings.ToLower(strings.TrimSpace(algorithm))
+		if seen[algorithm] {
+			continue
+		}
+		seen[algorithm] = true
+
+		privateKey, err := generateSSHHostKey(algorithm, rsaBits, random)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := ssh.NewSignerFromKey(privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s host key signer: %w", algorithm, err)
+		}
+		publicKey := signer.PublicKey()
+
+		block, err := ssh.MarshalPrivateKey(privateKey, comment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s host key: %w", algorithm, err)
+		}
+
+		authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey)))
+		publicLine := authorizedKey
+		if comment != "" {
+			publicLine += " " + comment
+		}
+
+		name := "ssh_host_" + algorithm + "_key"
+		result[name] = string(pem.EncodeToMemory(block))
+		result[name+".pub"] = publicLine + "\n"
+		result[algorithm+"_fingerprint"] = ssh.FingerprintSHA256(publicKey)
+		generated = append(generated, algorithm)
+
+		if len(hosts) == 0 {
+			continue
+		}
+		if hashKnownHosts {
+			// Hashed entries cannot share a line, so each host gets its own salted entry
+			for _, host := range hosts {
+				hashed, err := hashKnownHost(host, random)
+				if err != nil {
+					return nil, err
+				}
+				knownHosts = append(knownHosts, hashed+" "+authorizedKey)
+			}
+		} else {
+			knownHosts = append(knownHosts, strings.Join(hosts, ",")+" "+authorizedKey)
+		}
+	}
+
+	result["algorithms"] = strings.Join(generated, ",")
+	if len(knownHosts) > 0 {
+		result["known_hosts"] = strings.Join(knownHosts, "\n") + "\n"
+	}
+	return result, nil
+}
+
+// generateSSHHostKey generates a private key for a host key algorithm
+func generateSSHHostKey(algorithm string, rsaBits int, random io.Reader) (crypto.PrivateKey, error) {
+	switch algorithm {
+	case "rsa":
+		key, err := entropy.RSAKey(random, rsaBits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate RSA host key: %w", err)
+		}
+		return key, nil
+	case "ecdsa":
+		key, err := entropy.ECDSAKey(elliptic.P256(), random)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ECDSA host key: %w", err)
+		}
+		return key, nil
+	case "ed25519":
+		_, key, err := ed25519.GenerateKey(random)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Ed25519 host key: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported host key algorithm: %s (supported: %s)", algorithm, strings.Join(sshHostKeyAlgorithms, ", "))
+	}
+}
+
+// hashKnownHost hashes a known_hosts host like ssh-keygen -H, reading the salt from random
+func hashKnownHost(host string, random io.Reader) (string, error) {
+	salt := make([]byte, sha1.Size)
+	if _, err := io.ReadFull(random, salt); err != nil {
+		return "", fmt.Errorf("failed to generate known_hosts salt: %w", err)
+	}
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(host))
+	return "|1|" + base64.StdEncoding.EncodeToString(salt) + "|" + base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,146 @@
+package crypto
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestSSHHostKeysGenerator_Generate(t *testing.T) {
+	gen := &SSHHostKeysGenerator{}
+
+	tests := []struct {
+		name       string
+		config     map[string]interface{}
+		algorithms []string
+		wantHosts  bool
+		wantErr    bool
+	}{
+		{
+			name:       "default algorithms without hostnames",
+			config:     map[string]interface{}{"rsa_bits": 2048},
+			algorithms: []string{"rsa", "ecdsa", "ed25519"},
+		},
+		{
+			name: "ed25519 with known_hosts",
+			config: map[string]interface{}{
+				"algorithms": []interface{}{"ed25519"},
+				"hostnames":  []interface{}{"git.example.com", "10.0.0.5"},
+				"comment":    "root@git",
+			},
+			algorithms: []string{"ed25519"},
+			wantHosts:  true,
+		},
+		{
+			name: "hashed known_hosts on custom port",
+			config: map[string]interface{}{
+				"algorithms":       []interface{}{"ecdsa", "ed25519"},
+				"hostnames":        "bastion.example.com",
+				"port":             2222,
+				"hash_known_hosts": true,
+			},
+			algorithms: []string{"ecdsa", "ed25519"},
+			wantHosts:  true,
+		},
+		{
+			name:    "unsupported algorithm",
+			config:  map[string]interface{}{"algorithms": []interface{}{"dsa"}},
+			wantErr: true,
+		},
+		{
+			name:    "rsa too small",
+			config:  map[string]interface{}{"rsa_bits": 1024},
+			wantErr: true,
+		},
+		{
+			name:    "invalid hostname",
+			config:  map[string]interface{}{"algorithms": "ed25519", "hostnames": []interface{}{"a,b"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid port",
+			config:  map[string]interface{}{"algorithms": "ed25519", "port": 70000},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if result["algorithms"] != strings.Join(tt.algorithms, ",") {
+				t.Errorf("Generate() algorithms = %s, want %s", result["algorithms"], strings.Join(tt.algorithms, ","))
+			}
+
+			var publicKeys []ssh.PublicKey
+			for _, algorithm := range tt.algorithms {
+				name := "ssh_host_" + algorithm + "_key"
+				signer, err := ssh.ParsePrivateKey([]byte(result[name]))
+				if err != nil {
+					t.Fatalf("Generate() %s is not an OpenSSH private key: %v", name, err)
+				}
+				publicKey, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(result[name+".pub"]))
+				if err != nil {
+					t.Fatalf("Generate() %s.pub is not a public key: %v", name, err)
+				}
+				if string(publicKey.Marshal()) != string(signer.PublicKey().Marshal()) {
+					t.Errorf("Generate() %s.pub does not match private key", name)
+				}
+				if want, _ := tt.config["comment"].(string); comment != want {
+					t.Errorf("Generate() %s.pub comment = %q, want %q", name, comment, want)
+				}
+				if result[algorithm+"_fingerprint"] != ssh.FingerprintSHA256(publicKey) {
+					t.Errorf("Generate() %s_fingerprint mismatch", algorithm)
+				}
+				publicKeys = append(publicKeys, publicKey)
+			}
+
+			knownHostsData, ok := result["known_hosts"]
+			if !tt.wantHosts {
+				if ok {
+					t.Error("Generate() known_hosts set without hostnames")
+				}
+				return
+			}
+			if hashed, _ := tt.config["hash_known_hosts"].(bool); hashed && strings.Contains(knownHostsData, "example.com") {
+				t.Error("Generate() hashed known_hosts contains a plain hostname")
+			}
+
+			// Every host must be accepted with every generated key
+			path := filepath.Join(t.TempDir(), "known_hosts")
+			if err := os.WriteFile(path, []byte(knownHostsData), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			callback, err := knownhosts.New(path)
+			if err != nil {
+				t.Fatalf("known_hosts is not parseable: %v", err)
+			}
+			port := "22"
+			if p, ok := tt.config["port"].(int); ok {
+				port = strconv.Itoa(p)
+			}
+			for _, hostname := range getStringListConfig(tt.config, "hostnames") {
+				address := net.JoinHostPort(hostname, port)
+				remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 22}
+				for _, publicKey := range publicKeys {
+					if err := callback(address, remote, publicKey); err != nil {
+						t.Errorf("known_hosts rejects %s key for %s: %v", publicKey.Type(), address, err)
+					}
+				}
+			}
+		})
+	}
+}
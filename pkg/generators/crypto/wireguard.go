@@ -0,0 +1,48 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+type WireGuardKeyPairGenerator struct{}
+
+func (g *WireGuardKeyPairGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *WireGuardKeyPairGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	withPresharedKey := getBoolConfig(config, "preshared_key", false)
+
+	// Generate and clamp the X25519 private key the same way `wg genkey` does
+	privateKeyBytes := make([]byte, 32)
+	if _, err := io.ReadFull(random, privateKeyBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %w", err)
+	}
+	privateKeyBytes[0] &= 248
+	privateKeyBytes[31] = (privateKeyBytes[31] & 127) | 64
+
+	privateKey, err := ecdh.X25519().NewPrivateKey(privateKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create X25519 private key: %w", err)
+	}
+
+	result := map[string]string{
+		"private_key": base64.StdEncoding.EncodeToString(privateKey.Bytes()),
+		"public_key":  base64.StdEncoding.EncodeToString(privateKey.PublicKey().Bytes()),
+		"algorithm":   "X25519",
+	}
+
+	if withPresharedKey {
+		psk := make([]byte, 32)
+		if _, err := io.ReadFull(random, psk); err != nil {
+			return nil, fmt.Errorf("failed to generate preshared key: %w", err)
+		}
+		result["preshared_key"] = base64.StdEncoding.EncodeToString(psk)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestWireGuardKeyPairGenerator_Generate(t *testing.T) {
+	gen := &WireGuardKeyPairGenerator{}
+
+	tests := []struct {
+		name         string
+		config       map[string]interface{}
+		presharedKey bool
+	}{
+		{
+			name:   "default",
+			config: map[string]interface{}{},
+		},
+		{
+			name: "with preshared key",
+			config: map[string]interface{}{
+				"preshared_key": true,
+			},
+			presharedKey: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(tt.config)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			privateKey, err := base64.StdEncoding.DecodeString(result["private_key"])
+			if err != nil || len(privateKey) != 32 {
+				t.Fatalf("Generate() invalid private_key %q", result["private_key"])
+			}
+			if privateKey[0]&7 != 0 || privateKey[31]&128 != 0 || privateKey[31]&64 == 0 {
+				t.Error("Generate() private_key is not clamped")
+			}
+
+			publicKey, err := base64.StdEncoding.DecodeString(result["public_key"])
+			if err != nil {
+				t.Fatalf("Generate() invalid public_key base64: %v", err)
+			}
+			expected, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+			if err != nil {
+				t.Fatalf("Failed to derive public key: %v", err)
+			}
+			if !bytes.Equal(publicKey, expected) {
+				t.Error("Generate() public_key does not match private_key")
+			}
+
+			psk, ok := result["preshared_key"]
+			if ok != tt.presharedKey {
+				t.Fatalf("Generate() preshared_key present = %v, want %v", ok, tt.presharedKey)
+			}
+			if ok {
+				decoded, err := base64.StdEncoding.DecodeString(psk)
+				if err != nil || len(decoded) != 32 {
+					t.Errorf("Generate() invalid preshared_key %q", psk)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package database
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/logicIQ/secret-santa/pkg/generators/random"
+)
+
+type engine struct {
+	scheme      string
+	tlsScheme   string
+	port        int
+	tlsPort     int
+	database    string
+	requireUser bool
+}
+
+var engines = map[string]engine{
+	"postgres": {scheme: "postgresql", port: 5432, requireUser: true},
+	"mysql":    {scheme: "mysql", port: 3306, requireUser: true},
+	"mongodb":  {scheme: "mongodb", port: 27017, requireUser: true},
+	"redis":    {scheme: "redis", tlsScheme: "rediss", port: 6379, database: "0"},
+	"amqp":     {scheme: "amqp", tlsScheme: "amqps", port: 5672, tlsPort: 5671, database: "/", requireUser: true},
+}
+
+// CredentialsGenerator generates a database password and the connection strings that embed it
+type CredentialsGenerator struct{}
+
+func (g *CredentialsGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *CredentialsGenerator) GenerateWithEntropy(config map[string]interface{}, randomSource io.Reader) (map[string]string, error) {
+	engineName := strings.ToLower(getStringConfig(config, "engine", ""))
+	eng, ok := engines[engineName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported engine: %q (supported: postgres, mysql, mongodb, redis, amqp)", engineName)
+	}
+
+	useTLS := getBoolConfig(config, "tls", false)
+	if useTLS && eng.tlsScheme == "" && engineName != "mongodb" {
+		return nil, fmt.Errorf("tls is not supported for %s, use sslmode or params instead", engineName)
+	}
+
+	host := getStringConfig(config, "host", "localhost")
+	defaultPort := eng.port
+	if useTLS && eng.tlsPort != 0 {
+		defaultPort = eng.tlsPort
+	}
+	port := getIntConfig(config, "port", defaultPort)
+	database := getStringConfig(config, "database", eng.database)
+	username := getStringConfig(config, "username", "")
+	password := getStringConfig(config, "password", "")
+	sslMode := getStringConfig(config, "sslmode", "")
+	params := getStringMapConfig(config, "params")
+
+	if host == "" || strings.ContainsAny(host, "/?#@ ") {
+		return nil, fmt.Errorf("invalid host: %q", host)
+	}
+	if port < 1 || port > 65535 {
+		return nil, fmt.Errorf("port must be between 1 and 65535, got: %d", port)
+	}
+	if eng.requireUser && username == "" {
+		return nil, fmt.Errorf("username is required for %s", engineName)
+	}
+	if sslMode != "" && engineName != "postgres" {
+		return nil, fmt.Errorf("sslmode is only supported for postgres")
+	}
+	if engineName == "redis" {
+		if _, err := strconv.Atoi(database); err != nil {
+			return nil, fmt.Errorf("redis database must be a number, got: %q", database)
+		}
+	}
+
+	if password == "" {
+		generated, err := (&random.PasswordGenerator{}).GenerateWithEntropy(map[string]interface{}{
+			"length":  getIntConfig(config, "password_length", 32),
+			"special": getBoolConfig(config, "password_special", true),
+		}, randomSource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate password: %w", err)
+		}
+		password = generated["value"]
+	}
+
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	if sslMode != "" {
+		query.Set("sslmode", sslMode)
+	}
+	if useTLS && engineName == "mongodb" {
+		query.Set("tls", "true")
+	}
+
+	scheme := eng.scheme
+	if useTLS && eng.tlsScheme != "" {
+		scheme = eng.tlsScheme
+	}
+	hostPort := net.JoinHostPort(host, strconv.Itoa(port))
+	dsn := url.URL{
+		Scheme:   scheme,
+		User:     url.UserPassword(username, password),
+		Host:     hostPort,
+		RawQuery: query.Encode(),
+	}
+	if database != "" {
+		// AMQP vhosts are a single path segment, so the default "/" vhost becomes %2F
+		dsn.Path = "/" + database
+		dsn.RawPath = "/" + url.PathEscape(database)
+	}
+
+	result := map[string]string{
+		"engine":   engineName,
+		"username": username,
+		"password": password,
+		"host":     host,
+		"port":     strconv.Itoa(port),
+		"database": database,
+		"dsn":      dsn.String(),
+	}
+
+	switch engineName {
+	case "postgres":
+		result["jdbc_url"] = jdbcURL("postgresql", hostPort, database, query)
+		result["libpq"] = libpqString(host, port, database, username, password, sslMode)
+		result["pgpass"] = pgpassLine(host, port, database, username, password)
+	case "mysql":
+		result["jdbc_url"] = jdbcURL("mysql", hostPort, database, query)
+		result["my_cnf"] = myCnf(host, port, database, username, password)
+	}
+
+	return result, nil
+}
+
+// jdbcURL builds a JDBC URL without credentials, which JDBC drivers take as separate properties
+func jdbcURL(subprotocol, hostPort, database string, query url.Values) string {
+	u := "jdbc:" + subprotocol + "://" + hostPort + "/" + url.PathEscape(database)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// libpqString builds a libpq keyword/value connection string
+func libpqString(host string, port int, database, username, password, sslMode string) string {
+	parts := []string{
+		"host=" + libpqQuote(host),
+		"port=" + strconv.Itoa(port),
+	}
+	if database != "" {
+		parts = append(parts, "dbname="+libpqQuote(database))
+	}
+	parts = append(parts, "user="+libpqQuote(username), "password="+libpqQuote(password))
+	if sslMode != "" {
+		parts = append(parts, "sslmode="+libpqQuote(sslMode))
+	}
+	return strings.Join(parts, " ")
+}
+
+// libpqQuote single-quotes values that are empty or contain spaces, quotes or backslashes
+func libpqQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n'\\") {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// pgpassLine builds a .pgpass entry, escaping ':' and '\' in each field
+func pgpassLine(host string, port int, database, username, password string) string {
+	escape := strings.NewReplacer(`\`, `\\`, `:`, `\:`).Replace
+	if database == "" {
+		database = "*"
+	} else {
+		database = escape(database)
+	}
+	return strings.Join([]string{escape(host), strconv.Itoa(port), database, escape(username), escape(password)}, ":") + "\n"
+}
+
+// myCnf builds a [client] section for a MySQL option file
+func myCnf(host string, port int, database, username, password string) string {
+	quote := func(value string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	}
+	var b strings.Builder
+	b.WriteString("[client]\n")
+	b.WriteString("host=" + quote(host) + "\n")
+	b.WriteString("port=" + strconv.Itoa(port) + "\n")
+	b.WriteString("user=" + quote(username) + "\n")
+	b.WriteString("password=" + quote(password) + "\n")
+	if database != "" {
+		b.WriteString("database=" + quote(database) + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package database
+
+import "math"
+
+func getStringConfig(config map[string]interface{}, key, defaultValue string) string {
+	if config == nil {
+		return defaultValue
+	}
+	if val, ok := config[key].(string); ok {
+		return val
+	}
+	return defaultValue
+}
+
+func getIntConfig(config map[string]interface{}, key string, defaultValue int) int {
+	if config == nil {
+		return defaultValue
+	}
+	switch val := config[key].(type) {
+	case int:
+		return val
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return defaultValue
+		}
+		if val < math.MinInt || val > math.MaxInt {
+			return defaultValue
+		}
+		if val != math.Trunc(val) {
+			return defaultValue
+		}
+		return int(val)
+	}
+	return defaultValue
+}
+
+func getBoolConfig(config map[string]interface{}, key string, defaultValue bool) bool {
+	if config == nil {
+		return defaultValue
+	}
+	if val, ok := config[key].(bool); ok {
+		return val
+	}
+	return defaultValue
+}
+
+func getStringMapConfig(config map[string]interface{}, key string) map[string]string {
+	result := map[string]string{}
+	if config == nil {
+		return result
+	}
+	if val, ok := config[key].(map[string]interface{}); ok {
+		for k, v := range val {
+			if s, ok := v.(string); ok {
+				result[k] = s
+			}
+		}
+	}
+	return result
+}
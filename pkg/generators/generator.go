@@ -1,12 +1,58 @@
 package generators
 
-import "strings"
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/logicIQ/secret-santa/pkg/entropy"
+)
 
 // Generator interface for all secret generators
 type Generator interface {
 	Generate(config map[string]interface{}) (map[string]string, error)
 }
 
+// EntropyGenerator is implemented by generators that read all randomness from an injectable source.
+// Their Generate method uses crypto/rand.
+type EntropyGenerator interface {
+	GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error)
+}
+
+// ContextGenerator is implemented by generators that call out of process and honour the deadline of ctx
+type ContextGenerator interface {
+	GenerateWithContext(ctx context.Context, config map[string]interface{}) (map[string]string, error)
+}
+
+// InputGenerator is implemented by generators that read the outputs of earlier generators. inputs
+// holds "outputs", the outputs of earlier generators by name, and "namespace" of the SecretSanta.
+type InputGenerator interface {
+	GenerateWithInputs(config map[string]interface{}, inputs map[string]interface{}, random io.Reader) (map[string]string, error)
+}
+
+type inputsKey struct{}
+
+// WithInputs returns a context whose input generators receive inputs
+func WithInputs(ctx context.Context, inputs map[string]interface{}) context.Context {
+	return context.WithValue(ctx, inputsKey{}, inputs)
+}
+
+// GenerateContext runs the generator with the randomness source of ctx, see entropy.WithReader.
+// Context generators are cancelled with ctx and do not use its randomness source.
+func GenerateContext(ctx context.Context, generator Generator, config map[string]interface{}) (map[string]string, error) {
+	if cg, ok := generator.(ContextGenerator); ok {
+		return cg.GenerateWithContext(ctx, config)
+	}
+	if ig, ok := generator.(InputGenerator); ok {
+		inputs, _ := ctx.Value(inputsKey{}).(map[string]interface{})
+		return ig.GenerateWithInputs(config, inputs, entropy.FromContext(ctx))
+	}
+	if eg, ok := generator.(EntropyGenerator); ok {
+		return eg.GenerateWithEntropy(config, entropy.FromContext(ctx))
+	}
+	return generator.Generate(config)
+}
+
 func getStringConfig(config map[string]interface{}, key, defaultValue string) string {
 	if config == nil {
 		return defaultValue
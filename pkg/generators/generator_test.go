@@ -0,0 +1,101 @@
+package generators
+
+import (
+	"context"
+	"testing"
+
+	"github.com/logicIQ/secret-santa/pkg/entropy"
+	"github.com/logicIQ/secret-santa/pkg/generators/crypto"
+	"github.com/logicIQ/secret-santa/pkg/generators/database"
+	"github.com/logicIQ/secret-santa/pkg/generators/otp"
+	"github.com/logicIQ/secret-santa/pkg/generators/random"
+	"github.com/logicIQ/secret-santa/pkg/generators/script"
+	"github.com/logicIQ/secret-santa/pkg/generators/tls"
+)
+
+func TestGenerateContextDeterministic(t *testing.T) {
+	tests := []struct {
+		name      string
+		generator Generator
+		config    map[string]interface{}
+		keys      []string
+	}{
+		{name: "random_password", generator: &random.PasswordGenerator{}, keys: []string{"value"}},
+		{name: "random_string", generator: &random.StringGenerator{}, keys: []string{"value"}},
+		{name: "random_uuid", generator: &random.UUIDGenerator{}, keys: []string{"value"}},
+		{name: "random_integer", generator: &random.IntegerGenerator{}, config: map[string]interface{}{"max": 1000000}, keys: []string{"value"}},
+		{name: "random_bytes", generator: &random.BytesGenerator{}, keys: []string{"value"}},
+		{name: "random_id", generator: &random.IDGenerator{}, keys: []string{"value"}},
+		{name: "random_nanoid", generator: &random.NanoIDGenerator{}, keys: []string{"value"}},
+		{name: "app_secret", generator: &random.AppSecretGenerator{}, config: map[string]interface{}{"preset": "django"}, keys: []string{"value"}},
+		{name: "api_token", generator: &random.APITokenGenerator{}, config: map[string]interface{}{"prefix": "t_"}, keys: []string{"token", "sha256"}},
+		{name: "crypto_aes_key", generator: &crypto.AESKeyGenerator{}, keys: []string{"key_base64"}},
+		{name: "crypto_rsa_key", generator: &crypto.RSAKeyGenerator{}, keys: []string{"private_key_pem"}},
+		{name: "crypto_ecdsa_key", generator: &crypto.ECDSAKeyGenerator{}, keys: []string{"private_key_pem"}},
+		{name: "crypto_ecdh_key", generator: &crypto.ECDHKeyGenerator{}, config: map[string]interface{}{"curve": "P521"}, keys: []string{"private_key_pem"}},
+		{name: "crypto_ed25519_key", generator: &crypto.ED25519KeyGenerator{}, keys: []string{"private_key_pem"}},
+		{name: "crypto_xchacha20_key", generator: &crypto.XChaCha20KeyGenerator{}, keys: []string{"key_base64"}},
+		{name: "crypto_hmac", generator: &crypto.HMACGenerator{}, config: map[string]interface{}{"message": "m"}, keys: []string{"signature_hex"}},
+		{name: "crypto_mlkem_key", generator: &crypto.MLKEMKeyGenerator{}, keys: []string{"decapsulation_key_base64"}},
+		{name: "crypto_hybrid_kem_key", generator: &crypto.HybridKEMKeyGenerator{}, keys: []string{"private_key_base64"}},
+		{name: "wireguard_keypair", generator: &crypto.WireGuardKeyPairGenerator{}, keys: []string{"private_key"}},
+		{name: "age_identity", generator: &crypto.AgeIdentityGenerator{}, keys: []string{"identity", "recipient"}},
+		{name: "ssh_host_keys", generator: &crypto.SSHHostKeysGenerator{}, config: map[string]interface{}{"rsa_bits": 2048, "hostnames": "h", "hash_known_hosts": true}, keys: []string{"ssh_host_rsa_key.pub", "ssh_host_ecdsa_key.pub", "ssh_host_ed25519_key.pub", "known_hosts"}},
+		{name: "shamir_split", generator: &crypto.ShamirSplitGenerator{}, config: map[string]interface{}{"secret": "s"}, keys: []string{"share_1"}},
+		{name: "totp_seed", generator: &otp.TOTPSeedGenerator{}, config: map[string]interface{}{"issuer": "i", "account_name": "a"}, keys: []string{"secret"}},
+		{name: "db_credentials", generator: &database.CredentialsGenerator{}, config: map[string]interface{}{"engine": "redis", "host": "h"}, keys: []string{"dsn"}},
+		{name: "tls_private_key", generator: &tls.PrivateKeyGenerator{}, config: map[string]interface{}{"algorithm": "ECDSA", "ecdsa_curve": "P256"}, keys: []string{"private_key_pem"}},
+		{name: "script", generator: &script.ScriptGenerator{}, config: map[string]interface{}{"expression": `{"value": random.string(16), "id": random.uuid()}`}, keys: []string{"value", "id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := tt.generator.(EntropyGenerator); !ok {
+				t.Fatalf("%T does not implement EntropyGenerator", tt.generator)
+			}
+
+			run := func(seed string) map[string]string {
+				ctx := entropy.WithReader(context.Background(), entropy.NewInsecureDeterministic(seed))
+				result, err := GenerateContext(ctx, tt.generator, tt.config)
+				if err != nil {
+					t.Fatalf("GenerateContext() error = %v", err)
+				}
+				return result
+			}
+
+			first, second, other := run("seed"), run("seed"), run("other")
+			for _, key := range tt.keys {
+				if first[key] == "" {
+					t.Fatalf("GenerateContext() missing key %s", key)
+				}
+				if first[key] != second[key] {
+					t.Errorf("GenerateContext() %s differs for the same seed", key)
+				}
+				if first[key] == other[key] {
+					t.Errorf("GenerateContext() %s is the same for different seeds", key)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateContextDefault(t *testing.T) {
+	generator := &random.BytesGenerator{}
+	first, err := GenerateContext(context.Background(), generator, nil)
+	if err != nil {
+		t.Fatalf("GenerateContext() error = %v", err)
+	}
+	second, err := GenerateContext(context.Background(), generator, nil)
+	if err != nil {
+		t.Fatalf("GenerateContext() error = %v", err)
+	}
+	if first["value"] == second["value"] {
+		t.Error("GenerateContext() without an entropy source returned the same value twice")
+	}
+
+	// Generators without injectable entropy are run as-is
+	result, err := GenerateContext(context.Background(), &MockGenerator{}, nil)
+	if err != nil || result["value"] != "test" {
+		t.Errorf("GenerateContext() = %v, %v, want mock result", result, err)
+	}
+}
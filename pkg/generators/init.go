@@ -2,7 +2,11 @@ package generators
 
 import (
 	"github.com/logicIQ/secret-santa/pkg/generators/crypto"
+	"github.com/logicIQ/secret-santa/pkg/generators/database"
+	"github.com/logicIQ/secret-santa/pkg/generators/kubernetes"
+	"github.com/logicIQ/secret-santa/pkg/generators/otp"
 	"github.com/logicIQ/secret-santa/pkg/generators/random"
+	"github.com/logicIQ/secret-santa/pkg/generators/script"
 	timegens "github.com/logicIQ/secret-santa/pkg/generators/time"
 	"github.com/logicIQ/secret-santa/pkg/generators/tls"
 )
@@ -19,10 +23,18 @@ func init() {
 	Register("random_integer", &random.IntegerGenerator{})
 	Register("random_bytes", &random.BytesGenerator{})
 	Register("random_id", &random.IDGenerator{})
+	Register("random_ulid", &random.ULIDGenerator{})
+	Register("random_ksuid", &random.KSUIDGenerator{})
+	Register("random_nanoid", &random.NanoIDGenerator{})
+	Register("app_secret", &random.AppSecretGenerator{})
+	Register("api_token", &random.APITokenGenerator{})
 
 	Register("time_static", &timegens.StaticGenerator{})
+	Register("time_rotating", &timegens.RotatingGenerator{})
 
 	Register("crypto_hmac", &crypto.HMACGenerator{})
+	Register("crypto_hkdf", &crypto.HKDFGenerator{})
+	Register("shamir_split", &crypto.ShamirSplitGenerator{})
 	Register("crypto_aes_key", &crypto.AESKeyGenerator{})
 	Register("crypto_rsa_key", &crypto.RSAKeyGenerator{})
 	Register("crypto_ed25519_key", &crypto.ED25519KeyGenerator{})
@@ -30,4 +42,18 @@ func init() {
 	Register("crypto_xchacha20_key", &crypto.XChaCha20KeyGenerator{})
 	Register("crypto_ecdsa_key", &crypto.ECDSAKeyGenerator{})
 	Register("crypto_ecdh_key", &crypto.ECDHKeyGenerator{})
+	Register("crypto_mlkem_key", &crypto.MLKEMKeyGenerator{})
+	Register("crypto_hybrid_kem_key", &crypto.HybridKEMKeyGenerator{})
+	Register("ssh_host_keys", &crypto.SSHHostKeysGenerator{})
+	Register("wireguard_keypair", &crypto.WireGuardKeyPairGenerator{})
+	Register("age_identity", &crypto.AgeIdentityGenerator{})
+	Register("openpgp_key", &crypto.OpenPGPKeyGenerator{})
+
+	Register("totp_seed", &otp.TOTPSeedGenerator{})
+
+	Register("db_credentials", &database.CredentialsGenerator{})
+
+	Register("k8s_encryption_config", &kubernetes.EncryptionConfigGenerator{})
+
+	Register("script", &script.ScriptGenerator{})
 }
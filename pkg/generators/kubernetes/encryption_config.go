@@ -0,0 +1,218 @@
+package kubernetes
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/logicIQ/secret-santa/pkg/generators/crypto"
+)
+
+const (
+	encryptionConfigAPIVersion = "apiserver.config.k8s.io/v1"
+	encryptionConfigKind       = "EncryptionConfiguration"
+)
+
+type encryptionConfiguration struct {
+	APIVersion string                   `json:"apiVersion"`
+	Kind       string                   `json:"kind"`
+	Resources  []encryptionResourceRule `json:"resources"`
+}
+
+type encryptionResourceRule struct {
+	Resources []string                      `json:"resources"`
+	Providers []map[string]encryptionKeySet `json:"providers"`
+}
+
+type encryptionKeySet struct {
+	Keys []encryptionKey `json:"keys,omitempty"`
+}
+
+type encryptionKey struct {
+	Name   string `json:"name"`
+	Secret string `json:"secret"`
+}
+
+// encryptionProviders are the supported providers; identity stores data unencrypted and has no keys
+var encryptionProviders = map[string]bool{
+	"aescbc":    true,
+	"aesgcm":    true,
+	"secretbox": true,
+	"identity":  false,
+}
+
+// EncryptionConfigGenerator generates a kube-apiserver EncryptionConfiguration. Keys from
+// previous_config are kept behind the new key so existing data stays readable during rotation.
+type EncryptionConfigGenerator struct{}
+
+func (g *EncryptionConfigGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *EncryptionConfigGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	resources := getStringListConfig(config, "resources")
+	if len(resources) == 0 {
+		resources = []string{"secrets"}
+	}
+	providers := getStringListConfig(config, "providers")
+	if len(providers) == 0 {
+		providers = []string{"aescbc", "identity"}
+	}
+	keyName := getStringConfig(config, "key_name", "key-"+time.Now().UTC().Format("20060102150405"))
+	keySize := getIntConfig(config, "key_size", 256)
+	retainKeys := getIntConfig(config, "retain_keys", 0)
+	previousConfig := getStringConfig(config, "previous_config", "")
+
+	for _, resource := range resources {
+		if resource == "" || strings.ContainsAny(resource, " \t\n") {
+			return nil, fmt.Errorf("invalid resource: %q", resource)
+		}
+	}
+	seen := make(map[string]bool, len(providers))
+	for _, provider := range providers {
+		if _, ok := encryptionProviders[provider]; !ok {
+			return nil, fmt.Errorf("unsupported provider: %q (supported: aescbc, aesgcm, secretbox, identity)", provider)
+		}
+		if seen[provider] {
+			return nil, fmt.Errorf("duplicate provider: %s", provider)
+		}
+		seen[provider] = true
+	}
+	primary := providers[0]
+	if !encryptionProviders[primary] {
+		return nil, fmt.Errorf("first provider encrypts new writes and must be aescbc, aesgcm or secretbox, got: %s", primary)
+	}
+	if keyName == "" {
+		return nil, fmt.Errorf("key_name cannot be empty")
+	}
+	if retainKeys < 0 {
+		return nil, fmt.Errorf("retain_keys must not be negative, got: %d", retainKeys)
+	}
+
+	previousKeys, previousOrder, err := parsePreviousEncryptionKeys(previousConfig)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range previousKeys[primary] {
+		if key.Name == keyName {
+			return nil, fmt.Errorf("key_name %s already exists in previous_config", keyName)
+		}
+	}
+
+	secret, err := generateEncryptionKey(primary, keySize, random)
+	if err != nil {
+		return nil, err
+	}
+
+	// Providers only present in the previous config stay readable, ahead of identity
+	order := slices.Clone(providers)
+	for _, provider := range previousOrder {
+		if slices.Contains(order, provider) {
+			continue
+		}
+		if identity := slices.Index(order, "identity"); identity >= 0 {
+			order = slices.Insert(order, identity, provider)
+		} else {
+			order = append(order, provider)
+		}
+	}
+
+	var providerList []map[string]encryptionKeySet
+	for _, provider := range order {
+		if !encryptionProviders[provider] {
+			providerList = append(providerList, map[string]encryptionKeySet{provider: {}})
+			continue
+		}
+		keys := previousKeys[provider]
+		if provider == primary {
+			keys = append([]encryptionKey{{Name: keyName, Secret: secret}}, keys...)
+		}
+		if retainKeys > 0 && len(keys) > retainKeys {
+			keys = keys[:retainKeys]
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		providerList = append(providerList, map[string]encryptionKeySet{provider: {Keys: keys}})
+	}
+
+	out, err := yaml.Marshal(encryptionConfiguration{
+		APIVersion: encryptionConfigAPIVersion,
+		Kind:       encryptionConfigKind,
+		Resources: []encryptionResourceRule{{
+			Resources: resources,
+			Providers: providerList,
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal EncryptionConfiguration: %w", err)
+	}
+
+	return map[string]string{
+		"config":     string(out),
+		"key_name":   keyName,
+		"key_base64": secret,
+		"provider":   primary,
+	}, nil
+}
+
+// generateEncryptionKey returns a base64 key for the provider from the crypto generators
+func generateEncryptionKey(provider string, keySize int, random io.Reader) (string, error) {
+	var result map[string]string
+	var err error
+	if provider == "secretbox" {
+		// secretbox uses a 32 byte XSalsa20-Poly1305 key
+		result, err = (&crypto.XChaCha20KeyGenerator{}).GenerateWithEntropy(nil, random)
+	} else {
+		result, err = (&crypto.AESKeyGenerator{}).GenerateWithEntropy(map[string]interface{}{"key_size": keySize}, random)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to generate %s key: %w", provider, err)
+	}
+	return result["key_base64"], nil
+}
+
+// parsePreviousEncryptionKeys collects the keys per provider from an existing EncryptionConfiguration,
+// along with the order the providers first appear in
+func parsePreviousEncryptionKeys(previous string) (map[string][]encryptionKey, []string, error) {
+	keys := map[string][]encryptionKey{}
+	if strings.TrimSpace(previous) == "" {
+		return keys, nil, nil
+	}
+
+	var parsed encryptionConfiguration
+	if err := yaml.UnmarshalStrict([]byte(previous), &parsed); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse previous_config: %w", err)
+	}
+	if parsed.APIVersion != encryptionConfigAPIVersion || parsed.Kind != encryptionConfigKind {
+		return nil, nil, fmt.Errorf("previous_config must be a %s %s", encryptionConfigAPIVersion, encryptionConfigKind)
+	}
+
+	var order []string
+	names := map[string]map[string]bool{}
+	for _, rule := range parsed.Resources {
+		for _, entry := range rule.Providers {
+			for provider, keySet := range entry {
+				if _, ok := encryptionProviders[provider]; !ok {
+					return nil, nil, fmt.Errorf("unsupported provider %q in previous_config", provider)
+				}
+				if !slices.Contains(order, provider) {
+					order = append(order, provider)
+					names[provider] = map[string]bool{}
+				}
+				for _, key := range keySet.Keys {
+					if !names[provider][key.Name] {
+						names[provider][key.Name] = true
+						keys[provider] = append(keys[provider], key)
+					}
+				}
+			}
+		}
+	}
+	return keys, order, nil
+}
@@ -0,0 +1,175 @@
+package kubernetes
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func parseEncryptionConfig(t *testing.T, data string) encryptionConfiguration {
+	t.Helper()
+	var parsed encryptionConfiguration
+	if err := yaml.UnmarshalStrict([]byte(data), &parsed); err != nil {
+		t.Fatalf("config is not valid YAML: %v\n%s", err, data)
+	}
+	if parsed.APIVersion != encryptionConfigAPIVersion || parsed.Kind != encryptionConfigKind {
+		t.Fatalf("unexpected apiVersion/kind %s/%s", parsed.APIVersion, parsed.Kind)
+	}
+	if len(parsed.Resources) != 1 {
+		t.Fatalf("expected 1 resource rule, got %d", len(parsed.Resources))
+	}
+	return parsed
+}
+
+func providerNames(rule encryptionResourceRule) []string {
+	var names []string
+	for _, entry := range rule.Providers {
+		for name := range entry {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func TestEncryptionConfigGenerator_Generate(t *testing.T) {
+	gen := &EncryptionConfigGenerator{}
+
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		providers []string
+		keyLength int
+		resources []string
+		wantError bool
+	}{
+		{
+			name:      "defaults",
+			config:    map[string]interface{}{},
+			providers: []string{"aescbc", "identity"},
+			keyLength: 32,
+			resources: []string{"secrets"},
+		},
+		{
+			name: "secretbox for secrets and configmaps",
+			config: map[string]interface{}{
+				"resources": []interface{}{"secrets", "configmaps"},
+				"providers": []interface{}{"secretbox"},
+				"key_name":  "key1",
+			},
+			providers: []string{"secretbox"},
+			keyLength: 32,
+			resources: []string{"secrets", "configmaps"},
+		},
+		{
+			name: "aesgcm 128 bit",
+			config: map[string]interface{}{
+				"providers": []interface{}{"aesgcm", "identity"},
+				"key_size":  128,
+			},
+			providers: []string{"aesgcm", "identity"},
+			keyLength: 16,
+			resources: []string{"secrets"},
+		},
+		{
+			name:      "identity first",
+			config:    map[string]interface{}{"providers": []interface{}{"identity", "aescbc"}},
+			wantError: true,
+		},
+		{
+			name:      "unsupported provider",
+			config:    map[string]interface{}{"providers": "kms"},
+			wantError: true,
+		},
+		{
+			name:      "duplicate provider",
+			config:    map[string]interface{}{"providers": []interface{}{"aescbc", "aescbc"}},
+			wantError: true,
+		},
+		{
+			name:      "invalid key size",
+			config:    map[string]interface{}{"key_size": 512},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(tt.config)
+			if tt.wantError {
+				if err == nil {
+					t.Error("Generate() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			parsed := parseEncryptionConfig(t, result["config"])
+			rule := parsed.Resources[0]
+			if got := providerNames(rule); len(got) != len(tt.providers) || got[0] != tt.providers[0] {
+				t.Errorf("providers = %v, want %v", got, tt.providers)
+			}
+			if len(rule.Resources) != len(tt.resources) {
+				t.Errorf("resources = %v, want %v", rule.Resources, tt.resources)
+			}
+
+			keys := rule.Providers[0][tt.providers[0]].Keys
+			if len(keys) != 1 || keys[0].Name != result["key_name"] || keys[0].Secret != result["key_base64"] {
+				t.Fatalf("primary keys = %+v", keys)
+			}
+			key, err := base64.StdEncoding.DecodeString(keys[0].Secret)
+			if err != nil || len(key) != tt.keyLength {
+				t.Errorf("key length = %d, want %d", len(key), tt.keyLength)
+			}
+			if result["provider"] != tt.providers[0] {
+				t.Errorf("provider = %s, want %s", result["provider"], tt.providers[0])
+			}
+		})
+	}
+}
+
+func TestEncryptionConfigGenerator_Rotation(t *testing.T) {
+	gen := &EncryptionConfigGenerator{}
+
+	first, err := gen.Generate(map[string]interface{}{"key_name": "key1"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	second, err := gen.Generate(map[string]interface{}{"key_name": "key2", "previous_config": first["config"]})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	rule := parseEncryptionConfig(t, second["config"]).Resources[0]
+	keys := rule.Providers[0]["aescbc"].Keys
+	if len(keys) != 2 || keys[0].Name != "key2" || keys[1].Name != "key1" || keys[1].Secret != first["key_base64"] {
+		t.Fatalf("rotated keys = %+v", keys)
+	}
+
+	// Migrating to secretbox keeps the aescbc keys readable ahead of identity
+	third, err := gen.Generate(map[string]interface{}{
+		"key_name":        "key3",
+		"providers":       []interface{}{"secretbox", "identity"},
+		"previous_config": second["config"],
+		"retain_keys":     1,
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	rule = parseEncryptionConfig(t, third["config"]).Resources[0]
+	if got := providerNames(rule); len(got) != 3 || got[0] != "secretbox" || got[1] != "aescbc" || got[2] != "identity" {
+		t.Fatalf("providers = %v, want [secretbox aescbc identity]", got)
+	}
+	if keys := rule.Providers[1]["aescbc"].Keys; len(keys) != 1 || keys[0].Name != "key2" {
+		t.Errorf("retained aescbc keys = %+v", keys)
+	}
+
+	if _, err := gen.Generate(map[string]interface{}{"key_name": "key2", "previous_config": second["config"]}); err == nil {
+		t.Error("Generate() expected error for duplicate key name")
+	}
+	if _, err := gen.Generate(map[string]interface{}{"previous_config": "kind: ConfigMap"}); err == nil {
+		t.Error("Generate() expected error for invalid previous_config")
+	}
+}
@@ -0,0 +1,57 @@
+package kubernetes
+
+import "math"
+
+func getStringConfig(config map[string]interface{}, key, defaultValue string) string {
+	if config == nil {
+		return defaultValue
+	}
+	if val, ok := config[key].(string); ok {
+		return val
+	}
+	return defaultValue
+}
+
+func getIntConfig(config map[string]interface{}, key string, defaultValue int) int {
+	if config == nil {
+		return defaultValue
+	}
+	switch val := config[key].(type) {
+	case int:
+		return val
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return defaultValue
+		}
+		if val < math.MinInt || val > math.MaxInt {
+			return defaultValue
+		}
+		if val != math.Trunc(val) {
+			return defaultValue
+		}
+		return int(val)
+	}
+	return defaultValue
+}
+
+// getStringListConfig accepts a single string or a list of strings. Returns nil if the key doesn't exist.
+func getStringListConfig(config map[string]interface{}, key string) []string {
+	if config == nil {
+		return nil
+	}
+	switch val := config[key].(type) {
+	case string:
+		return []string{val}
+	case []string:
+		return val
+	case []interface{}:
+		result := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
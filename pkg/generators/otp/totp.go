@@ -0,0 +1,151 @@
+package otp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+var algorithms = map[string]otp.Algorithm{
+	"SHA1":   otp.AlgorithmSHA1,
+	"SHA256": otp.AlgorithmSHA256,
+	"SHA512": otp.AlgorithmSHA512,
+}
+
+// TOTPSeedGenerator generates TOTP (RFC 6238) or HOTP (RFC 4226) seeds with an otpauth:// URI and QR code
+type TOTPSeedGenerator struct{}
+
+func (g *TOTPSeedGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *TOTPSeedGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	otpType := strings.ToLower(getStringConfig(config, "type", "totp"))
+	issuer := getStringConfig(config, "issuer", "")
+	accountName := getStringConfig(config, "account_name", "")
+	digits := getIntConfig(config, "digits", 6)
+	period := getIntConfig(config, "period", 30)
+	counter := getIntConfig(config, "counter", 0)
+	algorithmName := strings.ToUpper(getStringConfig(config, "algorithm", "SHA1"))
+	secretSize := getIntConfig(config, "secret_size", 20)
+	qrSize := getIntConfig(config, "qr_size", 256)
+
+	if otpType != "totp" && otpType != "hotp" {
+		return nil, fmt.Errorf("unsupported type: %s (supported: totp, hotp)", otpType)
+	}
+	if issuer == "" {
+		return nil, fmt.Errorf("issuer is required")
+	}
+	if accountName == "" {
+		return nil, fmt.Errorf("account_name is required")
+	}
+	if strings.Contains(issuer, ":") {
+		return nil, fmt.Errorf("issuer must not contain ':'")
+	}
+	if digits != 6 && digits != 8 {
+		return nil, fmt.Errorf("digits must be 6 or 8, got: %d", digits)
+	}
+	if period < 1 || period > 300 {
+		return nil, fmt.Errorf("period must be between 1 and 300 seconds, got: %d", period)
+	}
+	if counter < 0 {
+		return nil, fmt.Errorf("counter must not be negative, got: %d", counter)
+	}
+	algorithm, ok := algorithms[algorithmName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm: %s (supported: SHA1, SHA256, SHA512)", algorithmName)
+	}
+	if secretSize < 10 || secretSize > 64 {
+		return nil, fmt.Errorf("secret_size must be between 10 and 64 bytes, got: %d", secretSize)
+	}
+	if qrSize < 64 || qrSize > 2048 {
+		return nil, fmt.Errorf("qr_size must be between 64 and 2048 pixels, got: %d", qrSize)
+	}
+
+	secretBytes := make([]byte, secretSize)
+	if _, err := io.ReadFull(random, secretBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	secret := base32NoPadding.EncodeToString(secretBytes)
+
+	// otpauth://totp/Issuer:account?secret=...&issuer=Issuer&algorithm=SHA1&digits=6&period=30
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", algorithmName)
+	params.Set("digits", strconv.Itoa(digits))
+	if otpType == "totp" {
+		params.Set("period", strconv.Itoa(period))
+	} else {
+		params.Set("counter", strconv.Itoa(counter))
+	}
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     otpType,
+		Path:     "/" + issuer + ":" + accountName,
+		RawQuery: strings.ReplaceAll(params.Encode(), "+", "%20"),
+	}
+
+	key, err := otp.NewKeyFromURL(uri.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse otpauth URI: %w", err)
+	}
+
+	var currentCode string
+	if otpType == "totp" {
+		currentCode, err = totp.GenerateCodeCustom(secret, time.Now(), totp.ValidateOpts{
+			Period:    uint(period),
+			Digits:    otp.Digits(digits),
+			Algorithm: algorithm,
+		})
+	} else {
+		currentCode, err = hotp.GenerateCodeCustom(secret, uint64(counter), hotp.ValidateOpts{
+			Digits:    otp.Digits(digits),
+			Algorithm: algorithm,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate current code: %w", err)
+	}
+
+	img, err := key.Image(qrSize, qrSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code: %w", err)
+	}
+	var qr bytes.Buffer
+	if err := png.Encode(&qr, img); err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	result := map[string]string{
+		"secret":       secret,
+		"uri":          key.URL(),
+		"qr_code_png":  base64.StdEncoding.EncodeToString(qr.Bytes()),
+		"current_code": currentCode,
+		"type":         otpType,
+		"issuer":       issuer,
+		"account_name": accountName,
+		"algorithm":    algorithmName,
+		"digits":       strconv.Itoa(digits),
+	}
+	if otpType == "totp" {
+		result["period"] = strconv.Itoa(period)
+	} else {
+		result["counter"] = strconv.Itoa(counter)
+	}
+	return result, nil
+}
@@ -0,0 +1,158 @@
+package otp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestTOTPSeedGenerator_Generate(t *testing.T) {
+	gen := &TOTPSeedGenerator{}
+
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		wantError bool
+		algorithm otp.Algorithm
+		digits    int
+		period    uint
+	}{
+		{
+			name:      "default totp",
+			config:    map[string]interface{}{"issuer": "Example", "account_name": "admin@example.com"},
+			algorithm: otp.AlgorithmSHA1,
+			digits:    6,
+			period:    30,
+		},
+		{
+			name: "custom totp",
+			config: map[string]interface{}{
+				"issuer":       "Example Corp",
+				"account_name": "svc-backup",
+				"algorithm":    "sha256",
+				"digits":       8,
+				"period":       60,
+				"qr_size":      128,
+			},
+			algorithm: otp.AlgorithmSHA256,
+			digits:    8,
+			period:    60,
+		},
+		{
+			name: "hotp",
+			config: map[string]interface{}{
+				"type":         "hotp",
+				"issuer":       "Example",
+				"account_name": "admin",
+				"counter":      5,
+			},
+			algorithm: otp.AlgorithmSHA1,
+			digits:    6,
+		},
+		{
+			name:      "missing issuer",
+			config:    map[string]interface{}{"account_name": "admin"},
+			wantError: true,
+		},
+		{
+			name:      "missing account name",
+			config:    map[string]interface{}{"issuer": "Example"},
+			wantError: true,
+		},
+		{
+			name:      "invalid digits",
+			config:    map[string]interface{}{"issuer": "Example", "account_name": "admin", "digits": 7},
+			wantError: true,
+		},
+		{
+			name:      "invalid algorithm",
+			config:    map[string]interface{}{"issuer": "Example", "account_name": "admin", "algorithm": "MD5"},
+			wantError: true,
+		},
+		{
+			name:      "invalid type",
+			config:    map[string]interface{}{"issuer": "Example", "account_name": "admin", "type": "sms"},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(tt.config)
+			if tt.wantError {
+				if err == nil {
+					t.Error("Generate() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			key, err := otp.NewKeyFromURL(result["uri"])
+			if err != nil {
+				t.Fatalf("Generate() invalid uri %q: %v", result["uri"], err)
+			}
+			if key.Secret() != result["secret"] {
+				t.Errorf("uri secret = %q, want %q", key.Secret(), result["secret"])
+			}
+			if key.Issuer() != tt.config["issuer"] {
+				t.Errorf("uri issuer = %q, want %q", key.Issuer(), tt.config["issuer"])
+			}
+			if key.AccountName() != tt.config["account_name"] {
+				t.Errorf("uri account name = %q, want %q", key.AccountName(), tt.config["account_name"])
+			}
+			if key.Algorithm() != tt.algorithm {
+				t.Errorf("uri algorithm = %v, want %v", key.Algorithm(), tt.algorithm)
+			}
+			if key.Digits().Length() != tt.digits || len(result["current_code"]) != tt.digits {
+				t.Errorf("digits = %d, current_code = %q, want %d digits", key.Digits().Length(), result["current_code"], tt.digits)
+			}
+
+			if key.Type() == "totp" {
+				if key.Period() != uint64(tt.period) {
+					t.Errorf("uri period = %d, want %d", key.Period(), tt.period)
+				}
+				valid, err := totp.ValidateCustom(result["current_code"], result["secret"], time.Now(), totp.ValidateOpts{
+					Period:    tt.period,
+					Skew:      1,
+					Digits:    otp.Digits(tt.digits),
+					Algorithm: tt.algorithm,
+				})
+				if err != nil || !valid {
+					t.Errorf("current_code %q does not validate: %v", result["current_code"], err)
+				}
+			} else {
+				valid, err := hotp.ValidateCustom(result["current_code"], 5, result["secret"], hotp.ValidateOpts{
+					Digits:    otp.Digits(tt.digits),
+					Algorithm: tt.algorithm,
+				})
+				if err != nil || !valid {
+					t.Errorf("current_code %q does not validate: %v", result["current_code"], err)
+				}
+			}
+
+			qr, err := base64.StdEncoding.DecodeString(result["qr_code_png"])
+			if err != nil {
+				t.Fatalf("qr_code_png is not base64: %v", err)
+			}
+			img, err := png.Decode(bytes.NewReader(qr))
+			if err != nil {
+				t.Fatalf("qr_code_png is not a PNG: %v", err)
+			}
+			size := 256
+			if s, ok := tt.config["qr_size"].(int); ok {
+				size = s
+			}
+			if img.Bounds().Dx() != size || img.Bounds().Dy() != size {
+				t.Errorf("QR size = %v, want %dx%d", img.Bounds(), size, size)
+			}
+		})
+	}
+}
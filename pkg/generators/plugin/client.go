@@ -0,0 +1,194 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultTimeout bounds each plugin call when no timeout is configured
+const DefaultTimeout = 10 * time.Second
+
+var namePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Config describes how to reach a plugin
+type Config struct {
+	// Name identifies the plugin in logs and metrics
+	Name string `json:"name"`
+	// Address is unix:///path/to/plugin.sock or host:port
+	Address string `json:"address"`
+	// Timeout bounds each plugin call, DefaultTimeout when zero
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+	// CAFile enables TLS and verifies the plugin certificate with this CA bundle
+	CAFile string `json:"caFile,omitempty"`
+	// ServerName overrides the name used to verify the plugin certificate
+	ServerName string `json:"serverName,omitempty"`
+	// Insecure allows plaintext connections to addresses other than unix sockets and loopback
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// FileConfig is the format of the generator plugins config file
+type FileConfig struct {
+	Plugins []Config `json:"plugins"`
+}
+
+// LoadConfigFile reads the plugins of a generator plugins config file
+func LoadConfigFile(path string) ([]Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generator plugins config: %w", err)
+	}
+	var file FileConfig
+	if err := yaml.UnmarshalStrict(raw, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse generator plugins config: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, config := range file.Plugins {
+		if err := config.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[config.Name] {
+			return nil, fmt.Errorf("duplicate generator plugin name: %s", config.Name)
+		}
+		seen[config.Name] = true
+	}
+	return file.Plugins, nil
+}
+
+// Validate checks the plugin name, address and timeout
+func (c Config) Validate() error {
+	if !namePattern.MatchString(c.Name) || len(c.Name) > 63 {
+		return fmt.Errorf("invalid generator plugin name: %q", c.Name)
+	}
+	if strings.TrimSpace(c.Address) == "" {
+		return fmt.Errorf("generator plugin %s requires an address", c.Name)
+	}
+	if c.Timeout.Duration < 0 {
+		return fmt.Errorf("generator plugin %s timeout must not be negative", c.Name)
+	}
+	return nil
+}
+
+func (c Config) timeout() time.Duration {
+	if c.Timeout.Duration > 0 {
+		return c.Timeout.Duration
+	}
+	return DefaultTimeout
+}
+
+// transportCredentials uses TLS when a CA is configured. Plaintext is limited to unix sockets and
+// loopback unless Insecure is set, since generator config and output are secrets.
+func (c Config) transportCredentials() (credentials.TransportCredentials, error) {
+	if c.CAFile != "" {
+		creds, err := credentials.NewClientTLSFromFile(c.CAFile, c.ServerName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA for generator plugin %s: %w", c.Name, err)
+		}
+		return creds, nil
+	}
+	if !c.Insecure && !isLocalAddress(c.Address) {
+		return nil, fmt.Errorf("generator plugin %s: plaintext connections to %s require insecure: true or a caFile", c.Name, c.Address)
+	}
+	return insecure.NewCredentials(), nil
+}
+
+func isLocalAddress(address string) bool {
+	if strings.HasPrefix(address, "unix:") || strings.HasPrefix(address, "unix-abstract:") {
+		return true
+	}
+	for _, scheme := range []string{"dns:///", "passthrough:///"} {
+		address = strings.TrimPrefix(address, scheme)
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Client is a connection to a plugin
+type Client struct {
+	conn   *grpc.ClientConn
+	health healthpb.HealthClient
+}
+
+// Dial creates a client for the plugin. The connection is established lazily on the first call.
+func Dial(config Config) (*Client, error) {
+	creds, err := config.transportCredentials()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.NewClient(config.Address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for generator plugin %s: %w", config.Name, err)
+	}
+	return &Client{conn: conn, health: healthpb.NewHealthClient(conn)}, nil
+}
+
+// Describe returns the generator types of the plugin
+func (c *Client) Describe(ctx context.Context) (*Description, error) {
+	out := new(structpb.Struct)
+	if err := c.conn.Invoke(ctx, describeMethod, &emptypb.Empty{}, out); err != nil {
+		return nil, err
+	}
+	var description Description
+	if err := fromStruct(out, &description); err != nil {
+		return nil, fmt.Errorf("invalid describe response: %w", err)
+	}
+	return &description, nil
+}
+
+// Generate runs a generator type of the plugin
+func (c *Client) Generate(ctx context.Context, generatorType string, config map[string]interface{}) (map[string]string, error) {
+	in, err := toStruct(generateRequest{Type: generatorType, Config: config})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode generator config: %w", err)
+	}
+	out := new(structpb.Struct)
+	if err := c.conn.Invoke(ctx, generateMethod, in, out); err != nil {
+		return nil, err
+	}
+	var response generateResponse
+	if err := fromStruct(out, &response); err != nil {
+		return nil, fmt.Errorf("invalid generate response: %w", err)
+	}
+	if response.Values == nil {
+		return nil, fmt.Errorf("generate response has no values")
+	}
+	return response.Values, nil
+}
+
+// Check returns an error unless the plugin reports SERVING
+func (c *Client) Check(ctx context.Context) error {
+	resp, err := c.health.Check(ctx, &healthpb.HealthCheckRequest{Service: ServiceName})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("plugin is %s", resp.GetStatus())
+	}
+	return nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,314 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/logicIQ/secret-santa/pkg/generators"
+	"github.com/logicIQ/secret-santa/pkg/metrics"
+)
+
+// DefaultProbeInterval is how often plugin health is checked
+const DefaultProbeInterval = 30 * time.Second
+
+// Manager keeps the connections to plugins and registers their generator types in the generator registry
+type Manager struct {
+	mu            sync.Mutex
+	plugins       map[string]*pluginState
+	static        []Config
+	probeInterval time.Duration
+}
+
+type pluginState struct {
+	config  Config
+	client  *Client
+	types   map[string]TypeSchema
+	healthy bool
+}
+
+// NewManager returns a manager for the plugins of the config file. Plugins added with Sync later,
+// for example from GeneratorPlugin resources, cannot reuse their names.
+func NewManager(static []Config) *Manager {
+	return &Manager{
+		plugins:       make(map[string]*pluginState),
+		static:        static,
+		probeInterval: DefaultProbeInterval,
+	}
+}
+
+// ProbeInterval returns how often plugins are checked
+func (m *Manager) ProbeInterval() time.Duration {
+	return m.probeInterval
+}
+
+// IsStatic reports whether name is a plugin of the config file
+func (m *Manager) IsStatic(name string) bool {
+	for _, config := range m.static {
+		if config.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Start syncs the plugins of the config file and probes all plugins until ctx is done. It implements
+// the controller-runtime Runnable interface.
+func (m *Manager) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("generator-plugins")
+	for _, config := range m.static {
+		if _, err := m.Sync(ctx, config); err != nil {
+			logger.Error(err, "Failed to register generator plugin, retrying on the next probe", "plugin", config.Name)
+		}
+	}
+
+	ticker := time.NewTicker(m.probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			m.closeAll()
+			return nil
+		case <-ticker.C:
+			m.Probe(ctx)
+		}
+	}
+}
+
+// Probe checks the health of every plugin. Plugins that recover are described again so their types
+// are registered.
+func (m *Manager) Probe(ctx context.Context) {
+	logger := log.FromContext(ctx).WithName("generator-plugins")
+
+	m.mu.Lock()
+	configs := make([]Config, 0, len(m.plugins))
+	recovering := map[string]bool{}
+	for name, state := range m.plugins {
+		configs = append(configs, state.config)
+		recovering[name] = !state.healthy || len(state.types) == 0
+	}
+	m.mu.Unlock()
+
+	for _, config := range configs {
+		if err := m.Check(ctx, config.Name); err != nil {
+			logger.V(1).Info("Generator plugin unavailable", "plugin", config.Name, "error", err.Error())
+			continue
+		}
+		if recovering[config.Name] {
+			if _, err := m.Sync(ctx, config); err != nil {
+				logger.Error(err, "Failed to register generator plugin", "plugin", config.Name)
+			}
+		}
+	}
+}
+
+// Check calls the health service of a plugin and records the result
+func (m *Manager) Check(ctx context.Context, name string) error {
+	m.mu.Lock()
+	state, ok := m.plugins[name]
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("generator plugin %s is not registered", name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, state.config.timeout())
+	defer cancel()
+	err := state.client.Check(ctx)
+	m.setHealthy(name, err == nil)
+	return err
+}
+
+// Sync connects to a plugin, describes it and registers its generator types. Types the plugin no
+// longer offers are unregistered. Types that are already registered by built-in generators or other
+// plugins are skipped and reported in the error.
+func (m *Manager) Sync(ctx context.Context, config Config) ([]TypeSchema, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	client, err := m.connect(config)
+	if err != nil {
+		return nil, err
+	}
+
+	describeCtx, cancel := context.WithTimeout(ctx, config.timeout())
+	defer cancel()
+	description, err := client.Describe(describeCtx)
+	recordRequest(config.Name, "Describe", err)
+	if err == nil {
+		err = description.validate()
+	}
+	if err != nil {
+		m.setHealthy(config.Name, false)
+		return nil, fmt.Errorf("failed to describe generator plugin %s: %w", config.Name, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.plugins[config.Name]
+	if !ok || state.client != client {
+		return nil, fmt.Errorf("generator plugin %s changed during sync", config.Name)
+	}
+
+	offered := make(map[string]TypeSchema, len(description.Types))
+	for _, schema := range description.Types {
+		offered[schema.Type] = schema
+	}
+	for generatorType := range state.types {
+		if _, ok := offered[generatorType]; !ok {
+			generators.Unregister(generatorType)
+			delete(state.types, generatorType)
+		}
+	}
+
+	var conflicts []string
+	for generatorType, schema := range offered {
+		if _, ours := state.types[generatorType]; !ours {
+			gen := &Generator{manager: m, plugin: config.Name, generatorType: generatorType}
+			if err := generators.Register(generatorType, gen); err != nil {
+				conflicts = append(conflicts, generatorType)
+				continue
+			}
+		}
+		state.types[generatorType] = schema
+	}
+	state.healthy = true
+	metrics.SetGeneratorPluginUp(config.Name, true)
+	metrics.SetGeneratorPluginTypes(config.Name, float64(len(state.types)))
+
+	types := make([]TypeSchema, 0, len(state.types))
+	for _, schema := range state.types {
+		types = append(types, schema)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Type < types[j].Type })
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return types, fmt.Errorf("generator plugin %s: types already registered: %s", config.Name, strings.Join(conflicts, ", "))
+	}
+	return types, nil
+}
+
+// connect returns the client of a plugin, replacing it when the config changed
+func (m *Manager) connect(config Config) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.plugins[config.Name]
+	if ok && state.config == config {
+		return state.client, nil
+	}
+	client, err := Dial(config)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		_ = state.client.Close()
+		state.config, state.client = config, client
+		return client, nil
+	}
+	m.plugins[config.Name] = &pluginState{
+		config: config,
+		client: client,
+		types:  make(map[string]TypeSchema),
+	}
+	return client, nil
+}
+
+// Remove unregisters the generator types of a plugin and closes its connection
+func (m *Manager) Remove(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.plugins[name]
+	if !ok {
+		return
+	}
+	for generatorType := range state.types {
+		generators.Unregister(generatorType)
+	}
+	_ = state.client.Close()
+	delete(m.plugins, name)
+	metrics.DeleteGeneratorPlugin(name)
+}
+
+func (m *Manager) closeAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, state := range m.plugins {
+		_ = state.client.Close()
+	}
+}
+
+func (m *Manager) setHealthy(name string, healthy bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if state, ok := m.plugins[name]; ok {
+		state.healthy = healthy
+	}
+	metrics.SetGeneratorPluginUp(name, healthy)
+}
+
+// lookup returns what is needed to call a generator type of a plugin
+func (m *Manager) lookup(name, generatorType string) (*Client, TypeSchema, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.plugins[name]
+	if !ok {
+		return nil, TypeSchema{}, 0, fmt.Errorf("generator plugin %s is not registered", name)
+	}
+	schema, ok := state.types[generatorType]
+	if !ok {
+		return nil, TypeSchema{}, 0, fmt.Errorf("generator plugin %s no longer provides %s", name, generatorType)
+	}
+	return state.client, schema, state.config.timeout(), nil
+}
+
+func recordRequest(plugin, method string, err error) {
+	switch {
+	case err == nil:
+		metrics.RecordGeneratorPluginRequest(plugin, method, metrics.StatusSuccess)
+	case errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded:
+		metrics.RecordGeneratorPluginRequest(plugin, method, metrics.StatusTimeout)
+	default:
+		metrics.RecordGeneratorPluginRequest(plugin, method, metrics.StatusFailed)
+	}
+}
+
+// Generator runs a generator type of a plugin
+type Generator struct {
+	manager       *Manager
+	plugin        string
+	generatorType string
+}
+
+func (g *Generator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithContext(context.Background(), config)
+}
+
+// GenerateWithContext validates config against the type schema and calls the plugin within its timeout
+func (g *Generator) GenerateWithContext(ctx context.Context, config map[string]interface{}) (map[string]string, error) {
+	client, schema, timeout, err := g.manager.lookup(g.plugin, g.generatorType)
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.ValidateConfig(config); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	values, err := client.Generate(ctx, g.generatorType, config)
+	recordRequest(g.plugin, "Generate", err)
+	if err != nil {
+		return nil, fmt.Errorf("generator plugin %s: %w", g.plugin, err)
+	}
+	return values, nil
+}
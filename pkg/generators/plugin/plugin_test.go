@@ -0,0 +1,263 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/logicIQ/secret-santa/pkg/generators"
+)
+
+type testServer struct {
+	types []TypeSchema
+	delay time.Duration
+}
+
+func (s *testServer) Describe(ctx context.Context) (*Description, error) {
+	return &Description{Types: s.types}, nil
+}
+
+func (s *testServer) Generate(ctx context.Context, generatorType string, config map[string]interface{}) (map[string]string, error) {
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if config["fail"] == true {
+		return nil, fmt.Errorf("failed on request")
+	}
+	return map[string]string{"value": fmt.Sprintf("%s:%v", generatorType, config["prefix"])}, nil
+}
+
+type builtinGenerator struct{}
+
+func (g *builtinGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return map[string]string{"value": "builtin"}, nil
+}
+
+// startServer serves impl on a unix socket and returns its address
+func startServer(t *testing.T, impl Server) string {
+	t.Helper()
+	// Unix socket paths are limited to about 100 characters, t.TempDir can be longer
+	dir, err := os.MkdirTemp("", "ssplugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "plugin.sock")
+
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	RegisterServer(s, impl)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+	return "unix://" + socket
+}
+
+func TestManagerSync(t *testing.T) {
+	generators.Clear()
+	t.Cleanup(generators.Clear)
+	if err := generators.Register("builtin_type", &builtinGenerator{}); err != nil {
+		t.Fatal(err)
+	}
+
+	server := &testServer{types: []TypeSchema{
+		{Type: "acme_token", Config: []Field{{Name: "prefix", Type: "string", Required: true}, {Name: "fail", Type: "boolean"}}},
+		{Type: "acme_key"},
+		{Type: "builtin_type"},
+	}}
+	config := Config{Name: "acme", Address: startServer(t, server)}
+	manager := NewManager(nil)
+
+	types, err := manager.Sync(context.Background(), config)
+	if err == nil || !strings.Contains(err.Error(), "builtin_type") {
+		t.Errorf("Sync() error = %v, want conflict with builtin_type", err)
+	}
+	if len(types) != 2 || types[0].Type != "acme_key" || types[1].Type != "acme_token" {
+		t.Fatalf("Sync() types = %v, want acme_key and acme_token", types)
+	}
+	if err := manager.Check(context.Background(), "acme"); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	gen, err := generators.Get("acme_token")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	result, err := generators.GenerateContext(context.Background(), gen, map[string]interface{}{"prefix": "p"})
+	if err != nil {
+		t.Fatalf("GenerateContext() error = %v", err)
+	}
+	if result["value"] != "acme_token:p" {
+		t.Errorf("GenerateContext() value = %q, want acme_token:p", result["value"])
+	}
+
+	for _, tt := range []struct {
+		name   string
+		config map[string]interface{}
+	}{
+		{name: "missing required", config: map[string]interface{}{}},
+		{name: "unknown parameter", config: map[string]interface{}{"prefix": "p", "other": 1}},
+		{name: "wrong type", config: map[string]interface{}{"prefix": 1.0}},
+		{name: "plugin error", config: map[string]interface{}{"prefix": "p", "fail": true}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := gen.Generate(tt.config); err == nil {
+				t.Error("Generate() expected error")
+			}
+		})
+	}
+
+	// Types the plugin stops offering are unregistered
+	server.types = server.types[:1]
+	if _, err := manager.Sync(context.Background(), config); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if generators.IsSupported("acme_key") {
+		t.Error("acme_key should be unregistered")
+	}
+
+	manager.Remove("acme")
+	if generators.IsSupported("acme_token") {
+		t.Error("acme_token should be unregistered after Remove")
+	}
+	if !generators.IsSupported("builtin_type") {
+		t.Error("builtin_type must not be touched by plugins")
+	}
+}
+
+func TestGeneratorTimeout(t *testing.T) {
+	generators.Clear()
+	t.Cleanup(generators.Clear)
+
+	server := &testServer{types: []TypeSchema{{Type: "slow_type"}}, delay: time.Second}
+	config := Config{Name: "slow", Address: startServer(t, server), Timeout: metav1.Duration{Duration: 50 * time.Millisecond}}
+	manager := NewManager(nil)
+	if _, err := manager.Sync(context.Background(), config); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	gen, err := generators.Get("slow_type")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := gen.Generate(nil); err == nil {
+		t.Error("Generate() expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Generate() took %v, want the configured timeout", elapsed)
+	}
+}
+
+func TestManagerUnavailable(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ssplugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := Config{Name: "missing", Address: "unix://" + filepath.Join(dir, "missing.sock"), Timeout: metav1.Duration{Duration: 100 * time.Millisecond}}
+	manager := NewManager([]Config{config})
+	if !manager.IsStatic("missing") {
+		t.Error("IsStatic() = false for a config file plugin")
+	}
+	if _, err := manager.Sync(context.Background(), config); err == nil {
+		t.Error("Sync() expected error for an unavailable plugin")
+	}
+	if err := manager.Check(context.Background(), "missing"); err == nil {
+		t.Error("Check() expected error for an unavailable plugin")
+	}
+}
+
+func TestConfigTransport(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "unix socket", config: Config{Name: "a", Address: "unix:///run/plugin.sock"}},
+		{name: "loopback", config: Config{Name: "a", Address: "127.0.0.1:9000"}},
+		{name: "localhost", config: Config{Name: "a", Address: "dns:///localhost:9000"}},
+		{name: "remote plaintext", config: Config{Name: "a", Address: "plugin.tools.svc:9000"}, wantErr: true},
+		{name: "remote insecure", config: Config{Name: "a", Address: "plugin.tools.svc:9000", Insecure: true}},
+		{name: "missing ca", config: Config{Name: "a", Address: "plugin.tools.svc:9000", CAFile: "/nonexistent/ca.crt"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.config.transportCredentials()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("transportCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			content: "plugins:\n- name: acme\n  address: unix:///run/acme.sock\n  timeout: 5s\n- name: other\n  address: 127.0.0.1:9000\n",
+			want:    2,
+		},
+		{name: "duplicate name", content: "plugins:\n- name: acme\n  address: a\n- name: acme\n  address: b\n", wantErr: true},
+		{name: "invalid name", content: "plugins:\n- name: Acme\n  address: a\n", wantErr: true},
+		{name: "missing address", content: "plugins:\n- name: acme\n", wantErr: true},
+		{name: "unknown field", content: "plugins:\n- name: acme\n  address: a\n  adress: b\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "plugins.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			configs, err := LoadConfigFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfigFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(configs) != tt.want {
+				t.Errorf("LoadConfigFile() = %d plugins, want %d", len(configs), tt.want)
+			}
+		})
+	}
+}
+
+func TestDescriptionValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		types   []TypeSchema
+		wantErr bool
+	}{
+		{name: "valid", types: []TypeSchema{{Type: "acme.token", Config: []Field{{Name: "n", Type: "integer"}}}}},
+		{name: "empty", wantErr: true},
+		{name: "invalid type name", types: []TypeSchema{{Type: "Acme"}}, wantErr: true},
+		{name: "duplicate type", types: []TypeSchema{{Type: "a"}, {Type: "a"}}, wantErr: true},
+		{name: "unknown field type", types: []TypeSchema{{Type: "a", Config: []Field{{Name: "n", Type: "int"}}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Description{Types: tt.types}).validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+// Package plugin runs generators out of process. Plugins are gRPC servers implementing the
+// GeneratorPlugin service of generator.proto, reached over a unix socket or TCP.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"regexp"
+	"sort"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ServiceName is the gRPC service implemented by plugins, also used for health checks
+const ServiceName = "secretsanta.plugin.v1.GeneratorPlugin"
+
+const (
+	describeMethod = "/" + ServiceName + "/Describe"
+	generateMethod = "/" + ServiceName + "/Generate"
+)
+
+// typePattern matches the generator type names accepted by the SecretSanta CRD
+var typePattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]*$`)
+
+// Description is the Describe response of a plugin
+type Description struct {
+	Types []TypeSchema `json:"types"`
+}
+
+// TypeSchema describes a generator type provided by a plugin
+type TypeSchema struct {
+	Type        string   `json:"type"`
+	Description string   `json:"description,omitempty"`
+	Config      []Field  `json:"config,omitempty"`
+	Outputs     []string `json:"outputs,omitempty"`
+}
+
+// Field describes a config parameter of a generator type
+type Field struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type generateRequest struct {
+	Type   string                 `json:"type"`
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+type generateResponse struct {
+	Values map[string]string `json:"values"`
+}
+
+// Server is implemented by plugins
+type Server interface {
+	Describe(ctx context.Context) (*Description, error)
+	Generate(ctx context.Context, generatorType string, config map[string]interface{}) (map[string]string, error)
+}
+
+// RegisterServer adds the plugin service and a health service reporting SERVING to s
+func RegisterServer(s *grpc.Server, impl Server) {
+	s.RegisterService(&serviceDesc, impl)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, healthServer)
+}
+
+// Serve serves impl on lis until the listener fails
+func Serve(lis net.Listener, impl Server) error {
+	s := grpc.NewServer()
+	RegisterServer(s, impl)
+	return s.Serve(lis)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*Server)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Describe", Handler: describeHandler},
+		{MethodName: "Generate", Handler: generateHandler},
+	},
+	Metadata: "generator.proto",
+}
+
+func describeHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	call := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		description, err := srv.(Server).Describe(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return toStruct(description)
+	}
+	if interceptor == nil {
+		return call(ctx, in)
+	}
+	return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: describeMethod}, call)
+}
+
+func generateHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	call := func(ctx context.Context, req interface{}) (interface{}, error) {
+		var request generateRequest
+		if err := fromStruct(req.(*structpb.Struct), &request); err != nil {
+			return nil, err
+		}
+		values, err := srv.(Server).Generate(ctx, request.Type, request.Config)
+		if err != nil {
+			return nil, err
+		}
+		return toStruct(generateResponse{Values: values})
+	}
+	if interceptor == nil {
+		return call(ctx, in)
+	}
+	return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: generateMethod}, call)
+}
+
+func toStruct(v interface{}) (*structpb.Struct, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(m)
+}
+
+func fromStruct(s *structpb.Struct, v interface{}) error {
+	raw, err := json.Marshal(s.AsMap())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// validate checks the generator types and field declarations of a plugin
+func (d *Description) validate() error {
+	if len(d.Types) == 0 {
+		return fmt.Errorf("plugin describes no generator types")
+	}
+	seen := map[string]bool{}
+	for _, schema := range d.Types {
+		if len(schema.Type) > 63 || !typePattern.MatchString(schema.Type) {
+			return fmt.Errorf("invalid generator type name: %q", schema.Type)
+		}
+		if seen[schema.Type] {
+			return fmt.Errorf("generator type %s is described twice", schema.Type)
+		}
+		seen[schema.Type] = true
+		for _, field := range schema.Config {
+			if field.Name == "" {
+				return fmt.Errorf("generator type %s has a config field without a name", schema.Type)
+			}
+			if !validFieldTypes[field.Type] {
+				return fmt.Errorf("generator type %s field %s has unsupported type %q", schema.Type, field.Name, field.Type)
+			}
+		}
+	}
+	return nil
+}
+
+var validFieldTypes = map[string]bool{
+	"": true, "any": true, "string": true, "integer": true, "number": true, "boolean": true, "object": true, "array": true,
+}
+
+// ValidateConfig checks config against the declared fields. Types that declare no fields accept any config.
+func (s TypeSchema) ValidateConfig(config map[string]interface{}) error {
+	if len(s.Config) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]Field, len(s.Config))
+	for _, field := range s.Config {
+		fields[field.Name] = field
+		if _, ok := config[field.Name]; field.Required && !ok {
+			return fmt.Errorf("%s is required for generator %s", field.Name, s.Type)
+		}
+	}
+
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("unknown config parameter %s for generator %s", key, s.Type)
+		}
+		if !matchesFieldType(field.Type, config[key]) {
+			return fmt.Errorf("%s must be of type %s for generator %s", key, field.Type, s.Type)
+		}
+	}
+	return nil
+}
+
+func matchesFieldType(fieldType string, value interface{}) bool {
+	switch fieldType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		switch v := value.(type) {
+		case int, int32, int64:
+			return true
+		case float64:
+			return v == math.Trunc(v)
+		}
+		return false
+	case "number":
+		switch value.(type) {
+		case int, int32, int64, float32, float64:
+			return true
+		}
+		return false
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	default:
+		return true
+	}
+}
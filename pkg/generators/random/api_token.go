@@ -0,0 +1,46 @@
+package random
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/logicIQ/secret-santa/pkg/apitoken"
+)
+
+// APITokenGenerator generates prefixed API tokens with an embedded checksum, like GitHub tokens
+type APITokenGenerator struct{}
+
+func (g *APITokenGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *APITokenGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	format := apitoken.Format{
+		Prefix:       getStringConfig(config, "prefix", ""),
+		Alphabet:     getStringConfig(config, "alphabet", "base62"),
+		Checksum:     getStringConfig(config, "checksum", "crc32"),
+		EntropyBytes: getIntConfig(config, "entropy_bytes", 24),
+	}
+
+	token, err := format.Generate(random)
+	if err != nil {
+		return nil, err
+	}
+
+	// Servers only need to store the hash to verify presented tokens
+	hash := sha256.Sum256([]byte(token))
+
+	return map[string]string{
+		"token":        token,
+		"sha256":       hex.EncodeToString(hash[:]),
+		"prefix":       format.Prefix,
+		"alphabet":     format.Alphabet,
+		"checksum":     format.Checksum,
+		"entropy_bits": strconv.Itoa(format.EntropyBytes * 8),
+		"generatedAt":  time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
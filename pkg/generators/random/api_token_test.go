@@ -0,0 +1,77 @@
+package random
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/logicIQ/secret-santa/pkg/apitoken"
+)
+
+func TestAPITokenGenerator_Generate(t *testing.T) {
+	gen := &APITokenGenerator{}
+
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		length  int
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			config: map[string]interface{}{"prefix": "acme_"},
+			length: 5 + 33 + 6,
+		},
+		{
+			name:   "github layout",
+			config: map[string]interface{}{"prefix": "ghp_", "entropy_bytes": 22},
+			length: 4 + 30 + 6,
+		},
+		{
+			name:   "hex without checksum",
+			config: map[string]interface{}{"prefix": "tok-", "alphabet": "hex", "checksum": "none", "entropy_bytes": 16},
+			length: 4 + 32,
+		},
+		{
+			name:    "missing prefix",
+			config:  map[string]interface{}{},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported checksum",
+			config:  map[string]interface{}{"prefix": "acme_", "checksum": "sha1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			token := result["token"]
+			if len(token) != tt.length {
+				t.Errorf("Generate() token length = %d, want %d", len(token), tt.length)
+			}
+			if !strings.HasPrefix(token, result["prefix"]) {
+				t.Errorf("Generate() token %s missing prefix %s", token, result["prefix"])
+			}
+			hash := sha256.Sum256([]byte(token))
+			if result["sha256"] != hex.EncodeToString(hash[:]) {
+				t.Error("Generate() sha256 does not match token")
+			}
+
+			format := apitoken.Format{Prefix: result["prefix"], Alphabet: result["alphabet"], Checksum: result["checksum"]}
+			if err := format.Validate(token); err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package random
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// appSecretPreset describes the secret format a framework expects. Presets are either byteLength
+// random bytes passed through encoding, or length characters drawn from charset.
+type appSecretPreset struct {
+	byteLength int
+	encoding   string
+	charset    string
+	length     int
+	prefix     string
+	// truncate limits the encoded output to this many characters when set
+	truncate int
+	env      string
+	pattern  *regexp.Regexp
+}
+
+var appSecretPresets = map[string]appSecretPreset{
+	// django.core.management.utils.get_random_secret_key
+	"django": {
+		charset: "abcdefghijklmnopqrstuvwxyz0123456789!@#$%^&*(-_=+)",
+		length:  50,
+		env:     "DJANGO_SECRET_KEY",
+		pattern: regexp.MustCompile(`^[a-z0-9!@#$%^&*(\-_=+)]{50}$`),
+	},
+	// bin/rails secret
+	"rails": {
+		byteLength: 64,
+		encoding:   "hex",
+		env:        "SECRET_KEY_BASE",
+		pattern:    regexp.MustCompile(`^[0-9a-f]{128}$`),
+	},
+	// php artisan key:generate
+	"laravel": {
+		byteLength: 32,
+		encoding:   "base64",
+		prefix:     "base64:",
+		env:        "APP_KEY",
+		pattern:    regexp.MustCompile(`^base64:[A-Za-z0-9+/]{43}=$`),
+	},
+	// cryptography.fernet.Fernet.generate_key
+	"fernet": {
+		byteLength: 32,
+		encoding:   "base64url",
+		env:        "FERNET_KEY",
+		pattern:    regexp.MustCompile(`^[A-Za-z0-9_-]{43}=$`),
+	},
+	// openssl rand -base64 32, as recommended for NextAuth.js / Auth.js
+	"nextauth": {
+		byteLength: 32,
+		encoding:   "base64",
+		env:        "NEXTAUTH_SECRET",
+		pattern:    regexp.MustCompile(`^[A-Za-z0-9+/]{43}=$`),
+	},
+	// Symfony APP_SECRET (bin2hex(random_bytes(16)))
+	"symfony": {
+		byteLength: 16,
+		encoding:   "hex",
+		env:        "APP_SECRET",
+		pattern:    regexp.MustCompile(`^[0-9a-f]{32}$`),
+	},
+	// secrets.token_hex(), as recommended for Flask SECRET_KEY
+	"flask": {
+		byteLength: 32,
+		encoding:   "hex",
+		env:        "SECRET_KEY",
+		pattern:    regexp.MustCompile(`^[0-9a-f]{64}$`),
+	},
+	// mix phx.gen.secret
+	"phoenix": {
+		byteLength: 64,
+		encoding:   "base64",
+		truncate:   64,
+		env:        "SECRET_KEY_BASE",
+		pattern:    regexp.MustCompile(`^[A-Za-z0-9+/]{64}$`),
+	},
+}
+
+var appSecretEncoders = map[string]func([]byte) string{
+	"hex":       hex.EncodeToString,
+	"base64":    base64.StdEncoding.EncodeToString,
+	"base64url": base64.URLEncoding.EncodeToString,
+}
+
+type AppSecretGenerator struct{}
+
+func (g *AppSecretGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *AppSecretGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	name := strings.ToLower(getStringConfig(config, "preset", ""))
+	if name == "" {
+		return nil, fmt.Errorf("preset is required (supported: %s)", strings.Join(appSecretPresetNames(), ", "))
+	}
+	preset, ok := appSecretPresets[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported preset: %s (supported: %s)", name, strings.Join(appSecretPresetNames(), ", "))
+	}
+
+	value, err := preset.generate(random)
+	if err != nil {
+		return nil, err
+	}
+	if !preset.pattern.MatchString(value) {
+		return nil, fmt.Errorf("generated %s secret does not match the expected format", name)
+	}
+
+	return map[string]string{
+		"value":       value,
+		"preset":      name,
+		"env":         preset.env,
+		"generatedAt": time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+func (p appSecretPreset) generate(random io.Reader) (string, error) {
+	if p.charset != "" {
+		charsetLen := big.NewInt(int64(len(p.charset)))
+		result := make([]byte, p.length)
+		for i := range result {
+			n, err := rand.Int(random, charsetLen)
+			if err != nil {
+				return "", err
+			}
+			result[i] = p.charset[n.Int64()]
+		}
+		return p.prefix + string(result), nil
+	}
+
+	encode, ok := appSecretEncoders[p.encoding]
+	if !ok {
+		return "", fmt.Errorf("unsupported preset encoding: %s", p.encoding)
+	}
+	bytes := make([]byte, p.byteLength)
+	if _, err := io.ReadFull(random, bytes); err != nil {
+		return "", err
+	}
+	encoded := encode(bytes)
+	if p.truncate > 0 && len(encoded) > p.truncate {
+		encoded = encoded[:p.truncate]
+	}
+	return p.prefix + encoded, nil
+}
+
+func appSecretPresetNames() []string {
+	names := make([]string, 0, len(appSecretPresets))
+	for name := range appSecretPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
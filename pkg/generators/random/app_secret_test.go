@@ -0,0 +1,92 @@
+package random
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestAppSecretGenerator_Generate(t *testing.T) {
+	gen := &AppSecretGenerator{}
+
+	tests := []struct {
+		preset string
+		length int
+		check  func(t *testing.T, value string)
+	}{
+		{preset: "django", length: 50},
+		{preset: "rails", length: 128},
+		{
+			preset: "laravel",
+			length: 51,
+			check: func(t *testing.T, value string) {
+				key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, "base64:"))
+				if err != nil || len(key) != 32 {
+					t.Errorf("laravel key does not decode to 32 bytes: %v", err)
+				}
+			},
+		},
+		{
+			preset: "fernet",
+			length: 44,
+			check: func(t *testing.T, value string) {
+				key, err := base64.URLEncoding.DecodeString(value)
+				if err != nil || len(key) != 32 {
+					t.Errorf("fernet key does not decode to 32 bytes: %v", err)
+				}
+			},
+		},
+		{preset: "nextauth", length: 44},
+		{preset: "symfony", length: 32},
+		{preset: "flask", length: 64},
+		{preset: "phoenix", length: 64},
+		{preset: "Rails", length: 128},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			result, err := gen.Generate(map[string]interface{}{"preset": tt.preset})
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if len(result["value"]) != tt.length {
+				t.Errorf("Generate() value length = %d, want %d", len(result["value"]), tt.length)
+			}
+			if result["preset"] != strings.ToLower(tt.preset) {
+				t.Errorf("Generate() preset = %q", result["preset"])
+			}
+			if result["env"] == "" {
+				t.Error("Generate() env is empty")
+			}
+			if tt.check != nil {
+				tt.check(t, result["value"])
+			}
+		})
+	}
+}
+
+func TestAppSecretGenerator_Errors(t *testing.T) {
+	gen := &AppSecretGenerator{}
+
+	for _, config := range []map[string]interface{}{{}, {"preset": "struts"}} {
+		if _, err := gen.Generate(config); err == nil {
+			t.Errorf("Generate(%v) expected error", config)
+		}
+	}
+}
+
+func TestAppSecretPresets_Valid(t *testing.T) {
+	for name, preset := range appSecretPresets {
+		if preset.pattern == nil {
+			t.Errorf("preset %s has no pattern", name)
+		}
+		if preset.charset == "" {
+			if _, ok := appSecretEncoders[preset.encoding]; !ok {
+				t.Errorf("preset %s has unknown encoding %q", name, preset.encoding)
+			}
+			if preset.byteLength < 16 {
+				t.Errorf("preset %s byteLength %d too small", name, preset.byteLength)
+			}
+		}
+	}
+}
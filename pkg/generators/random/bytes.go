@@ -4,11 +4,16 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
+	"io"
 )
 
 type BytesGenerator struct{}
 
 func (g *BytesGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *BytesGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	length := getIntConfig(config, "length", 16)
 
 	if length < 1 {
@@ -19,7 +24,7 @@ func (g *BytesGenerator) Generate(config map[string]interface{}) (map[string]str
 	}
 
 	buf := make([]byte, length)
-	_, err := rand.Read(buf)
+	_, err := io.ReadFull(random, buf)
 	if err != nil {
 		return nil, err
 	}
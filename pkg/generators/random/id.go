@@ -4,12 +4,17 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"time"
 )
 
 type IDGenerator struct{}
 
 func (g *IDGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *IDGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	byteLength := getIntConfig(config, "byte_length", 8)
 	prefix := getStringConfig(config, "prefix", "")
 
@@ -21,7 +26,7 @@ func (g *IDGenerator) Generate(config map[string]interface{}) (map[string]string
 	}
 
 	bytes := make([]byte, byteLength)
-	if _, err := rand.Read(bytes); err != nil {
+	if _, err := io.ReadFull(random, bytes); err != nil {
 		return nil, err
 	}
 
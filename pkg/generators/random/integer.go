@@ -3,6 +3,7 @@ package random
 import (
 	"crypto/rand"
 	"fmt"
+	"io"
 	"math/big"
 	"time"
 )
@@ -10,6 +11,10 @@ import (
 type IntegerGenerator struct{}
 
 func (g *IntegerGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *IntegerGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	min := getIntConfig(config, "min", 0)
 	max := getIntConfig(config, "max", 100)
 
@@ -19,7 +24,7 @@ func (g *IntegerGenerator) Generate(config map[string]interface{}) (map[string]s
 
 	// Generate random number in range [min, max]
 	rangeSize := max - min + 1
-	n, err := rand.Int(rand.Reader, big.NewInt(int64(rangeSize)))
+	n, err := rand.Int(random, big.NewInt(int64(rangeSize)))
 	if err != nil {
 		return nil, err
 	}
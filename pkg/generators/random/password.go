@@ -3,6 +3,7 @@ package random
 import (
 	"crypto/rand"
 	"fmt"
+	"io"
 	"math/big"
 	"strings"
 	"time"
@@ -11,6 +12,10 @@ import (
 type PasswordGenerator struct{}
 
 func (g *PasswordGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *PasswordGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	length := getIntConfig(config, "length", 16)
 	if length <= 0 || length > 1000000 {
 		return nil, fmt.Errorf("password length must be between 1 and 1000000, got %d", length)
@@ -48,7 +53,7 @@ func (g *PasswordGenerator) Generate(config map[string]interface{}) (map[string]
 	charsetLen := big.NewInt(int64(len(charsetStr)))
 	password := make([]byte, length)
 	for i := range password {
-		n, err := rand.Int(rand.Reader, charsetLen)
+		n, err := rand.Int(random, charsetLen)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate random number: %w", err)
 		}
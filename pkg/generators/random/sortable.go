@@ -0,0 +1,151 @@
+package random
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	base62Alphabet    = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// ksuidEpoch is the KSUID custom epoch (2014-05-13T16:53:20Z)
+	ksuidEpoch = 1400000000
+	// ksuidLength is the length of a base62 encoded 20-byte KSUID
+	ksuidLength = 27
+)
+
+// ULIDGenerator generates ULIDs: a 48-bit millisecond timestamp and 80 random bits in Crockford base32
+type ULIDGenerator struct{}
+
+func (g *ULIDGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *ULIDGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	now := time.Now().UTC()
+
+	var id [16]byte
+	ms := uint64(now.UnixMilli())
+	binary.BigEndian.PutUint16(id[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	if _, err := io.ReadFull(random, id[6:]); err != nil {
+		return nil, err
+	}
+
+	value := encodeULID(id)
+	if getBoolConfig(config, "lowercase", false) {
+		value = strings.ToLower(value)
+	}
+
+	return map[string]string{
+		"value":       value,
+		"timestamp":   time.UnixMilli(int64(ms)).UTC().Format(time.RFC3339Nano),
+		"generatedAt": now.Format(time.RFC3339),
+	}, nil
+}
+
+// encodeULID encodes 128 bits as 26 Crockford base32 characters, the first holding the top 3 bits
+func encodeULID(id [16]byte) string {
+	n := new(big.Int).SetBytes(id[:])
+	mask := big.NewInt(31)
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordAlphabet[new(big.Int).And(n, mask).Int64()]
+		n.Rsh(n, 5)
+	}
+	return string(out)
+}
+
+// KSUIDGenerator generates KSUIDs: a 32-bit second timestamp and 128 random bits in base62
+type KSUIDGenerator struct{}
+
+func (g *KSUIDGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *KSUIDGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	now := time.Now().UTC()
+
+	var id [20]byte
+	binary.BigEndian.PutUint32(id[0:4], uint32(now.Unix()-ksuidEpoch))
+	if _, err := io.ReadFull(random, id[4:]); err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"value":       encodeKSUID(id),
+		"timestamp":   time.Unix(now.Unix(), 0).UTC().Format(time.RFC3339),
+		"generatedAt": now.Format(time.RFC3339),
+	}, nil
+}
+
+// encodeKSUID encodes 160 bits as 27 base62 characters using the 0-9A-Za-z ordering of the KSUID spec
+func encodeKSUID(id [20]byte) string {
+	n := new(big.Int).SetBytes(id[:])
+	base := big.NewInt(62)
+	mod := new(big.Int)
+	out := make([]byte, ksuidLength)
+	for i := ksuidLength - 1; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		out[i] = base62Alphabet[mod.Int64()]
+	}
+	return string(out)
+}
+
+// NanoIDGenerator generates NanoIDs with an optional custom alphabet
+type NanoIDGenerator struct{}
+
+func (g *NanoIDGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *NanoIDGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	length := getIntConfig(config, "length", 21)
+	alphabet := getStringConfig(config, "alphabet", "_-0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+
+	if length < 1 {
+		return nil, fmt.Errorf("length must be at least 1")
+	}
+	if length > 1024 {
+		return nil, fmt.Errorf("length too large, maximum 1024")
+	}
+
+	chars := []rune(alphabet)
+	if len(chars) < 2 {
+		return nil, fmt.Errorf("alphabet must contain at least 2 characters")
+	}
+	if len(chars) > 256 {
+		return nil, fmt.Errorf("alphabet too large, maximum 256 characters")
+	}
+	seen := make(map[rune]bool, len(chars))
+	for _, c := range chars {
+		if seen[c] {
+			return nil, fmt.Errorf("alphabet contains duplicate character %q", c)
+		}
+		seen[c] = true
+	}
+
+	result := make([]rune, length)
+	alphabetSize := big.NewInt(int64(len(chars)))
+	for i := range result {
+		n, err := rand.Int(random, alphabetSize)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = chars[n.Int64()]
+	}
+
+	return map[string]string{
+		"value":       string(result),
+		"alphabet":    alphabet,
+		"length":      strconv.Itoa(length),
+		"generatedAt": time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
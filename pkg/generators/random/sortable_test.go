@@ -0,0 +1,178 @@
+package random
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestULIDGenerator_Generate(t *testing.T) {
+	gen := &ULIDGenerator{}
+
+	before := time.Now().UTC().Truncate(time.Millisecond)
+	result, err := gen.Generate(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	value := result["value"]
+	if len(value) != 26 {
+		t.Fatalf("Generate() value length = %d, want 26", len(value))
+	}
+	for _, c := range value {
+		if !strings.ContainsRune(crockfordAlphabet, c) {
+			t.Fatalf("Generate() value %q contains invalid character %q", value, c)
+		}
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, result["timestamp"])
+	if err != nil {
+		t.Fatalf("Generate() invalid timestamp: %v", err)
+	}
+	if timestamp.Before(before) || timestamp.After(time.Now()) {
+		t.Errorf("Generate() timestamp %v out of range", timestamp)
+	}
+
+	lower, err := gen.Generate(map[string]interface{}{"lowercase": true})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if lower["value"] != strings.ToLower(lower["value"]) {
+		t.Errorf("Generate() value %q is not lowercase", lower["value"])
+	}
+}
+
+func TestULIDGenerator_Sortable(t *testing.T) {
+	gen := &ULIDGenerator{}
+
+	first, err := gen.Generate(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	second, err := gen.Generate(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if first["value"] >= second["value"] {
+		t.Errorf("ULIDs not sortable: %s >= %s", first["value"], second["value"])
+	}
+}
+
+func TestEncodeULID(t *testing.T) {
+	var max [16]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	if got := encodeULID(max); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("encodeULID(max) = %s", got)
+	}
+	if got := encodeULID([16]byte{}); got != strings.Repeat("0", 26) {
+		t.Errorf("encodeULID(zero) = %s", got)
+	}
+}
+
+func TestKSUIDGenerator_Generate(t *testing.T) {
+	gen := &KSUIDGenerator{}
+
+	result, err := gen.Generate(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	value := result["value"]
+	if len(value) != 27 {
+		t.Fatalf("Generate() value length = %d, want 27", len(value))
+	}
+	for _, c := range value {
+		if !strings.ContainsRune(base62Alphabet, c) {
+			t.Fatalf("Generate() value %q contains invalid character %q", value, c)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339, result["timestamp"]); err != nil {
+		t.Errorf("Generate() invalid timestamp: %v", err)
+	}
+}
+
+func TestEncodeKSUID(t *testing.T) {
+	// Example from the KSUID specification
+	raw, _ := hex.DecodeString("0669F7EFB5A1CD34B5F99D1154FB6853345C9735")
+	var id [20]byte
+	copy(id[:], raw)
+	if got := encodeKSUID(id); got != "0ujtsYcgvSTl8PAuAdqWYSMnLOv" {
+		t.Errorf("encodeKSUID() = %s, want 0ujtsYcgvSTl8PAuAdqWYSMnLOv", got)
+	}
+}
+
+func TestNanoIDGenerator_Generate(t *testing.T) {
+	gen := &NanoIDGenerator{}
+
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		length    int
+		alphabet  string
+		wantError bool
+	}{
+		{
+			name:     "default",
+			config:   map[string]interface{}{},
+			length:   21,
+			alphabet: "_-0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ",
+		},
+		{
+			name:     "custom alphabet",
+			config:   map[string]interface{}{"length": 12, "alphabet": "0123456789abcdef"},
+			length:   12,
+			alphabet: "0123456789abcdef",
+		},
+		{
+			name:     "unicode alphabet",
+			config:   map[string]interface{}{"length": 8, "alphabet": "αβγδ"},
+			length:   8,
+			alphabet: "αβγδ",
+		},
+		{
+			name:      "duplicate characters",
+			config:    map[string]interface{}{"alphabet": "aab"},
+			wantError: true,
+		},
+		{
+			name:      "single character alphabet",
+			config:    map[string]interface{}{"alphabet": "a"},
+			wantError: true,
+		},
+		{
+			name:      "zero length",
+			config:    map[string]interface{}{"length": 0},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(tt.config)
+			if tt.wantError {
+				if err == nil {
+					t.Error("Generate() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			value := result["value"]
+			if utf8.RuneCountInString(value) != tt.length {
+				t.Errorf("Generate() value length = %d, want %d", utf8.RuneCountInString(value), tt.length)
+			}
+			for _, c := range value {
+				if !strings.ContainsRune(tt.alphabet, c) {
+					t.Errorf("Generate() value %q contains invalid character %q", value, c)
+				}
+			}
+		})
+	}
+}
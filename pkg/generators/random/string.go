@@ -3,6 +3,7 @@ package random
 import (
 	"crypto/rand"
 	"fmt"
+	"io"
 	"math/big"
 	"strings"
 )
@@ -10,6 +11,10 @@ import (
 type StringGenerator struct{}
 
 func (g *StringGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *StringGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	length := getIntConfig(config, "length", 16)
 	if length <= 0 {
 		return nil, fmt.Errorf("string length must be positive, got %d", length)
@@ -51,7 +56,7 @@ func (g *StringGenerator) Generate(config map[string]interface{}) (map[string]st
 	charsetLen := big.NewInt(int64(len(charsetStr)))
 
 	for i := 0; i < length; i++ {
-		n, err := rand.Int(rand.Reader, charsetLen)
+		n, err := rand.Int(random, charsetLen)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate random character: %w", err)
 		}
@@ -1,18 +1,83 @@
 package random
 
 import (
-	"github.com/google/uuid"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+var uuidNamespaces = map[string]uuid.UUID{
+	"dns":  uuid.NameSpaceDNS,
+	"url":  uuid.NameSpaceURL,
+	"oid":  uuid.NameSpaceOID,
+	"x500": uuid.NameSpaceX500,
+}
+
 type UUIDGenerator struct{}
 
 func (g *UUIDGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
-	id := uuid.New()
-	return map[string]string{
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *UUIDGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	version := getIntConfig(config, "version", 4)
+
+	var id uuid.UUID
+	var err error
+	switch version {
+	case 4:
+		id, err = uuid.NewRandomFromReader(random)
+	case 7:
+		id, err = uuid.NewV7FromReader(random)
+	case 3, 5:
+		id, err = nameBasedUUID(config, version)
+	default:
+		return nil, fmt.Errorf("unsupported UUID version: %d (supported: 3, 4, 5, 7)", version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]string{
 		"value":       id.String(),
-		"version":     "4",
+		"version":     strconv.Itoa(version),
 		"variant":     "RFC4122",
 		"generatedAt": time.Now().UTC().Format(time.RFC3339),
-	}, nil
+	}
+	if version == 7 {
+		sec, nsec := id.Time().UnixTime()
+		result["timestamp"] = time.Unix(sec, nsec).UTC().Format(time.RFC3339Nano)
+	}
+	return result, nil
+}
+
+// nameBasedUUID derives a deterministic v3 (MD5) or v5 (SHA-1) UUID from a namespace and name
+func nameBasedUUID(config map[string]interface{}, version int) (uuid.UUID, error) {
+	namespace := getStringConfig(config, "namespace", "")
+	name := getStringConfig(config, "name", "")
+	if namespace == "" {
+		return uuid.Nil, fmt.Errorf("namespace is required for UUID version %d", version)
+	}
+	if name == "" {
+		return uuid.Nil, fmt.Errorf("name is required for UUID version %d", version)
+	}
+
+	space, ok := uuidNamespaces[strings.ToLower(namespace)]
+	if !ok {
+		parsed, err := uuid.Parse(namespace)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("namespace must be dns, url, oid, x500 or a UUID: %w", err)
+		}
+		space = parsed
+	}
+
+	if version == 3 {
+		return uuid.NewMD5(space, []byte(name)), nil
+	}
+	return uuid.NewSHA1(space, []byte(name)), nil
 }
@@ -1,8 +1,10 @@
 package random
 
 import (
-	"github.com/google/uuid"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestUUIDGenerator_Generate(t *testing.T) {
@@ -33,3 +35,89 @@ func TestUUIDGenerator_Generate(t *testing.T) {
 		t.Errorf("Generate() expected UUID version 4, got %d", parsedUUID.Version())
 	}
 }
+
+func TestUUIDGenerator_Versions(t *testing.T) {
+	gen := &UUIDGenerator{}
+
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		version   uuid.Version
+		expected  string
+		wantError bool
+	}{
+		{
+			name:    "version 7",
+			config:  map[string]interface{}{"version": 7},
+			version: 7,
+		},
+		{
+			name:     "version 5 dns namespace",
+			config:   map[string]interface{}{"version": 5, "namespace": "dns", "name": "example.com"},
+			version:  5,
+			expected: "cfbff0d1-9375-5685-968c-48ce8b15ae17",
+		},
+		{
+			name:     "version 3 dns namespace",
+			config:   map[string]interface{}{"version": 3, "namespace": "dns", "name": "example.com"},
+			version:  3,
+			expected: "9073926b-929f-31c2-abc9-fad77ae3e8eb",
+		},
+		{
+			name:     "version 5 custom namespace",
+			config:   map[string]interface{}{"version": 5, "namespace": "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "name": "example.com"},
+			version:  5,
+			expected: "cfbff0d1-9375-5685-968c-48ce8b15ae17",
+		},
+		{
+			name:      "version 5 missing name",
+			config:    map[string]interface{}{"version": 5, "namespace": "dns"},
+			wantError: true,
+		},
+		{
+			name:      "version 5 invalid namespace",
+			config:    map[string]interface{}{"version": 5, "namespace": "tenant", "name": "a"},
+			wantError: true,
+		},
+		{
+			name:      "unsupported version",
+			config:    map[string]interface{}{"version": 2},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(tt.config)
+			if tt.wantError {
+				if err == nil {
+					t.Error("Generate() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			parsed, err := uuid.Parse(result["value"])
+			if err != nil {
+				t.Fatalf("Generate() invalid UUID format: %v", err)
+			}
+			if parsed.Version() != tt.version {
+				t.Errorf("Generate() version = %d, want %d", parsed.Version(), tt.version)
+			}
+			if tt.expected != "" && result["value"] != tt.expected {
+				t.Errorf("Generate() value = %s, want %s", result["value"], tt.expected)
+			}
+			if tt.version == 7 {
+				timestamp, err := time.Parse(time.RFC3339Nano, result["timestamp"])
+				if err != nil {
+					t.Fatalf("Generate() invalid timestamp: %v", err)
+				}
+				if time.Since(timestamp) > time.Minute {
+					t.Errorf("Generate() timestamp %v too old", timestamp)
+				}
+			}
+		})
+	}
+}
@@ -35,6 +35,13 @@ func Register(generatorType string, generator Generator) error {
 	return nil
 }
 
+// Unregister removes a generator type, used when a plugin stops offering it
+func Unregister(generatorType string) {
+	globalRegistry.mu.Lock()
+	defer globalRegistry.mu.Unlock()
+	delete(globalRegistry.generators, generatorType)
+}
+
 func Get(generatorType string) (Generator, error) {
 	if strings.TrimSpace(generatorType) == "" {
 		return nil, fmt.Errorf("generator type cannot be empty")
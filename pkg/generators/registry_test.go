@@ -74,6 +74,21 @@ func TestRegistry(t *testing.T) {
 		}
 	})
 
+	t.Run("Unregister", func(t *testing.T) {
+		Clear()
+		if err := Register("test_generator", mockGen); err != nil {
+			t.Fatalf("Failed to register test_generator: %v", err)
+		}
+		Unregister("test_generator")
+		if IsSupported("test_generator") {
+			t.Error("Expected test_generator to be unregistered")
+		}
+		if err := Register("test_generator", mockGen); err != nil {
+			t.Errorf("Expected re-registration after unregister, got error: %v", err)
+		}
+		Unregister("non_existent")
+	})
+
 	t.Run("Clear", func(t *testing.T) {
 		Clear()
 		if err := Register("test_generator", mockGen); err != nil {
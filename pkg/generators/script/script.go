@@ -0,0 +1,273 @@
+package script
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/ext"
+	"github.com/google/uuid"
+)
+
+const (
+	alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	maxExpressionLength = 16384
+	defaultCostLimit    = 100000
+	maxCostLimit        = 10000000
+	// maxRandomLength bounds a single random helper call
+	maxRandomLength = 4096
+	// evalTimeout stops programs that stay within the cost limit but are still slow
+	evalTimeout = time.Second
+)
+
+// ScriptGenerator evaluates a sandboxed CEL expression that returns a map of outputs. Programs have no
+// I/O, are bounded by a cost limit and get crypto-safe random helpers and earlier generator outputs.
+type ScriptGenerator struct{}
+
+func (g *ScriptGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithInputs(config, nil, rand.Reader)
+}
+
+func (g *ScriptGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	return g.GenerateWithInputs(config, nil, random)
+}
+
+func (g *ScriptGenerator) GenerateWithInputs(config map[string]interface{}, inputs map[string]interface{}, random io.Reader) (map[string]string, error) {
+	expression := getStringConfig(config, "expression", "")
+	costLimit := getIntConfig(config, "cost_limit", defaultCostLimit)
+
+	if strings.TrimSpace(expression) == "" {
+		return nil, fmt.Errorf("expression is required")
+	}
+	if len(expression) > maxExpressionLength {
+		return nil, fmt.Errorf("expression too long, maximum %d characters, got: %d", maxExpressionLength, len(expression))
+	}
+	if costLimit < 1 || costLimit > maxCostLimit {
+		return nil, fmt.Errorf("cost_limit must be between 1 and %d, got: %d", maxCostLimit, costLimit)
+	}
+
+	env, err := newEnv(random)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile expression: %w", issues.Err())
+	}
+	program, err := env.Program(ast,
+		cel.CostLimit(uint64(costLimit)),
+		cel.CostTracking(randomCost{}),
+		cel.InterruptCheckFrequency(100),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create program: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), evalTimeout)
+	defer cancel()
+	val, _, err := program.ContextEval(ctx, map[string]interface{}{
+		"outputs":  outputsInput(inputs),
+		"metadata": metadataInput(inputs),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression: %w", err)
+	}
+	return toOutputs(val)
+}
+
+func newEnv(random io.Reader) (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("outputs", cel.MapType(cel.StringType, cel.MapType(cel.StringType, cel.StringType))),
+		// namespace is a reserved word in CEL
+		cel.Variable("metadata", cel.MapType(cel.StringType, cel.StringType)),
+		ext.Strings(),
+		ext.Encoders(),
+		ext.Math(),
+		ext.Lists(),
+		cel.Function("random.string",
+			cel.Overload("random_string_int", []*cel.Type{cel.IntType}, cel.StringType,
+				cel.UnaryBinding(func(length ref.Val) ref.Val {
+					return randomString(random, length, types.String(alphanumeric))
+				})),
+			cel.Overload("random_string_int_string", []*cel.Type{cel.IntType, cel.StringType}, cel.StringType,
+				cel.BinaryBinding(func(length, charset ref.Val) ref.Val {
+					return randomString(random, length, charset)
+				})),
+		),
+		cel.Function("random.bytes",
+			cel.Overload("random_bytes_int", []*cel.Type{cel.IntType}, cel.BytesType,
+				cel.UnaryBinding(func(length ref.Val) ref.Val {
+					b, err := randomBytes(random, length)
+					if err != nil {
+						return types.WrapErr(err)
+					}
+					return types.Bytes(b)
+				})),
+		),
+		cel.Function("random.hex",
+			cel.Overload("random_hex_int", []*cel.Type{cel.IntType}, cel.StringType,
+				cel.UnaryBinding(func(length ref.Val) ref.Val {
+					b, err := randomBytes(random, length)
+					if err != nil {
+						return types.WrapErr(err)
+					}
+					return types.String(hex.EncodeToString(b))
+				})),
+		),
+		cel.Function("random.int",
+			cel.Overload("random_int_int_int", []*cel.Type{cel.IntType, cel.IntType}, cel.IntType,
+				cel.BinaryBinding(func(minVal, maxVal ref.Val) ref.Val {
+					return randomInt(random, int64(minVal.(types.Int)), int64(maxVal.(types.Int)))
+				})),
+		),
+		cel.Function("random.uuid",
+			cel.Overload("random_uuid", []*cel.Type{}, cel.StringType,
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					id, err := uuid.NewRandomFromReader(random)
+					if err != nil {
+						return types.WrapErr(err)
+					}
+					return types.String(id.String())
+				})),
+		),
+	)
+}
+
+// randomCost charges random helpers by their output length so the cost limit also bounds memory
+type randomCost struct{}
+
+func (randomCost) CallCost(function, overloadID string, args []ref.Val, result ref.Val) *uint64 {
+	switch overloadID {
+	case "random_string_int", "random_string_int_string", "random_bytes_int", "random_hex_int":
+		if length, ok := args[0].(types.Int); ok && length > 0 {
+			cost := uint64(length) + 1
+			return &cost
+		}
+	}
+	return nil
+}
+
+func checkLength(length ref.Val) (int, error) {
+	n, ok := length.(types.Int)
+	if !ok {
+		return 0, fmt.Errorf("length must be an int")
+	}
+	if n < 1 || n > maxRandomLength {
+		return 0, fmt.Errorf("length must be between 1 and %d, got: %d", maxRandomLength, n)
+	}
+	return int(n), nil
+}
+
+func randomString(random io.Reader, length, charset ref.Val) ref.Val {
+	n, err := checkLength(length)
+	if err != nil {
+		return types.WrapErr(err)
+	}
+	chars := []rune(string(charset.(types.String)))
+	if len(chars) == 0 {
+		return types.NewErr("charset cannot be empty")
+	}
+	max := big.NewInt(int64(len(chars)))
+	result := make([]rune, n)
+	for i := range result {
+		index, err := rand.Int(random, max)
+		if err != nil {
+			return types.WrapErr(err)
+		}
+		result[i] = chars[index.Int64()]
+	}
+	return types.String(string(result))
+}
+
+func randomBytes(random io.Reader, length ref.Val) ([]byte, error) {
+	n, err := checkLength(length)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(random, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// randomInt returns a uniform integer in [minVal, maxVal]
+func randomInt(random io.Reader, minVal, maxVal int64) ref.Val {
+	if minVal > maxVal {
+		return types.NewErr("random.int min %d is greater than max %d", minVal, maxVal)
+	}
+	span := new(big.Int).Sub(big.NewInt(maxVal), big.NewInt(minVal))
+	span.Add(span, big.NewInt(1))
+	n, err := rand.Int(random, span)
+	if err != nil {
+		return types.WrapErr(err)
+	}
+	return types.Int(n.Add(n, big.NewInt(minVal)).Int64())
+}
+
+func outputsInput(inputs map[string]interface{}) map[string]map[string]string {
+	outputs := map[string]map[string]string{}
+	previous, _ := inputs["outputs"].(map[string]interface{})
+	for name, value := range previous {
+		if values, ok := value.(map[string]string); ok {
+			outputs[name] = values
+		}
+	}
+	return outputs
+}
+
+func metadataInput(inputs map[string]interface{}) map[string]string {
+	namespace, _ := inputs["namespace"].(string)
+	return map[string]string{"namespace": namespace}
+}
+
+// toOutputs converts the map returned by the expression to generator outputs. Bytes are base64
+// encoded, numbers and booleans formatted.
+func toOutputs(val ref.Val) (map[string]string, error) {
+	mapper, ok := val.(traits.Mapper)
+	if !ok {
+		return nil, fmt.Errorf("expression must return a map, got: %s", val.Type().TypeName())
+	}
+
+	outputs := map[string]string{}
+	it := mapper.Iterator()
+	for it.HasNext() == types.True {
+		key := it.Next()
+		name, ok := key.(types.String)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("output keys must be non-empty strings, got: %v", key)
+		}
+		switch value := mapper.Get(key).(type) {
+		case types.String:
+			outputs[string(name)] = string(value)
+		case types.Bytes:
+			outputs[string(name)] = base64.StdEncoding.EncodeToString(value)
+		case types.Int:
+			outputs[string(name)] = strconv.FormatInt(int64(value), 10)
+		case types.Uint:
+			outputs[string(name)] = strconv.FormatUint(uint64(value), 10)
+		case types.Double:
+			outputs[string(name)] = strconv.FormatFloat(float64(value), 'f', -1, 64)
+		case types.Bool:
+			outputs[string(name)] = strconv.FormatBool(bool(value))
+		default:
+			return nil, fmt.Errorf("output %s must be a string, bytes, number or bool, got: %s", name, value.Type().TypeName())
+		}
+	}
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("expression returned no outputs")
+	}
+	return outputs, nil
+}
@@ -0,0 +1,118 @@
+package time
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// isoDurationPattern matches ISO 8601 durations such as P1M, P30D, PT12H or P1Y2M3DT4H5M6S
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// RotatingGenerator computes a rotation timestamp from a base time and rotation period, like the
+// Terraform time_rotating resource. The controller regenerates the SecretSanta when it elapses.
+type RotatingGenerator struct{}
+
+func (g *RotatingGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	rfc3339 := getStringConfig(config, "rfc3339", "")
+
+	now := time.Now().UTC()
+	base := now
+	if rfc3339 != "" {
+		parsed, err := time.Parse(time.RFC3339, rfc3339)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rfc3339 format: %v", err)
+		}
+		base = parsed.UTC()
+	}
+
+	rotation, err := rotationTime(config, base)
+	if err != nil {
+		return nil, err
+	}
+	if !rotation.After(base) {
+		return nil, fmt.Errorf("rotation period must be positive")
+	}
+
+	expiresIn := int64(rotation.Sub(now).Seconds())
+	if expiresIn < 0 {
+		expiresIn = 0
+	}
+
+	return map[string]string{
+		"rfc3339":            base.Format(time.RFC3339),
+		"unix":               strconv.FormatInt(base.Unix(), 10),
+		"rotation_rfc3339":   rotation.Format(time.RFC3339),
+		"rotation_unix":      strconv.FormatInt(rotation.Unix(), 10),
+		"expires_in_seconds": strconv.FormatInt(expiresIn, 10),
+		"expired":            strconv.FormatBool(!now.Before(rotation)),
+	}, nil
+}
+
+// rotationTime applies exactly one of the rotation_* settings to base
+func rotationTime(config map[string]interface{}, base time.Time) (time.Time, error) {
+	units := []struct {
+		key   string
+		apply func(time.Time, int) time.Time
+	}{
+		{"rotation_years", func(t time.Time, n int) time.Time { return t.AddDate(n, 0, 0) }},
+		{"rotation_months", func(t time.Time, n int) time.Time { return t.AddDate(0, n, 0) }},
+		{"rotation_days", func(t time.Time, n int) time.Time { return t.AddDate(0, 0, n) }},
+		{"rotation_hours", func(t time.Time, n int) time.Time { return t.Add(time.Duration(n) * time.Hour) }},
+		{"rotation_minutes", func(t time.Time, n int) time.Time { return t.Add(time.Duration(n) * time.Minute) }},
+	}
+
+	var rotation time.Time
+	set := 0
+	for _, unit := range units {
+		if _, ok := config[unit.key]; !ok {
+			continue
+		}
+		n := getIntConfig(config, unit.key, 0)
+		if n < 1 {
+			return time.Time{}, fmt.Errorf("%s must be a positive integer", unit.key)
+		}
+		rotation = unit.apply(base, n)
+		set++
+	}
+	if period := getStringConfig(config, "rotation_period", ""); period != "" {
+		parsed, err := applyPeriod(base, period)
+		if err != nil {
+			return time.Time{}, err
+		}
+		rotation = parsed
+		set++
+	}
+
+	if set != 1 {
+		return time.Time{}, fmt.Errorf("exactly one of rotation_years, rotation_months, rotation_days, rotation_hours, rotation_minutes or rotation_period is required")
+	}
+	return rotation, nil
+}
+
+// applyPeriod adds an ISO 8601 duration (P30D, PT12H, P1M) or a Go duration (720h) to base
+func applyPeriod(base time.Time, period string) (time.Time, error) {
+	match := isoDurationPattern.FindStringSubmatch(period)
+	if match == nil || period == "P" || period[len(period)-1] == 'T' {
+		d, err := time.ParseDuration(period)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid rotation_period %q: must be an ISO 8601 duration such as P30D or a duration such as 720h", period)
+		}
+		return base.Add(d), nil
+	}
+
+	n := make([]int, len(match))
+	for i := 1; i < len(match); i++ {
+		if match[i] == "" {
+			continue
+		}
+		value, err := strconv.Atoi(match[i])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid rotation_period %q: %w", period, err)
+		}
+		n[i] = value
+	}
+	t := base.AddDate(n[1], n[2], n[3]*7+n[4])
+	return t.Add(time.Duration(n[5])*time.Hour + time.Duration(n[6])*time.Minute + time.Duration(n[7])*time.Second), nil
+}
@@ -0,0 +1,134 @@
+package time
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRotatingGenerator_Generate(t *testing.T) {
+	gen := &RotatingGenerator{}
+
+	tests := []struct {
+		name         string
+		config       map[string]interface{}
+		wantRotation string
+		wantExpired  bool
+		wantError    bool
+	}{
+		{
+			name:         "rotation days",
+			config:       map[string]interface{}{"rfc3339": "2030-01-31T00:00:00Z", "rotation_days": 30},
+			wantRotation: "2030-03-02T00:00:00Z",
+		},
+		{
+			name:         "rotation months",
+			config:       map[string]interface{}{"rfc3339": "2030-01-15T10:00:00Z", "rotation_months": 3},
+			wantRotation: "2030-04-15T10:00:00Z",
+		},
+		{
+			name:         "rotation hours",
+			config:       map[string]interface{}{"rfc3339": "2030-01-01T00:00:00Z", "rotation_hours": 36},
+			wantRotation: "2030-01-02T12:00:00Z",
+		},
+		{
+			name:         "iso 8601 period",
+			config:       map[string]interface{}{"rfc3339": "2030-01-01T00:00:00Z", "rotation_period": "P1Y2M3DT4H5M6S"},
+			wantRotation: "2031-03-04T04:05:06Z",
+		},
+		{
+			name:         "iso 8601 weeks",
+			config:       map[string]interface{}{"rfc3339": "2030-01-01T00:00:00Z", "rotation_period": "P2W"},
+			wantRotation: "2030-01-15T00:00:00Z",
+		},
+		{
+			name:         "go duration period",
+			config:       map[string]interface{}{"rfc3339": "2030-01-01T00:00:00Z", "rotation_period": "90m"},
+			wantRotation: "2030-01-01T01:30:00Z",
+		},
+		{
+			name:         "expired",
+			config:       map[string]interface{}{"rfc3339": "2020-01-01T00:00:00Z", "rotation_days": 1},
+			wantRotation: "2020-01-02T00:00:00Z",
+			wantExpired:  true,
+		},
+		{
+			name:      "no rotation",
+			config:    map[string]interface{}{},
+			wantError: true,
+		},
+		{
+			name:      "multiple rotations",
+			config:    map[string]interface{}{"rotation_days": 1, "rotation_hours": 1},
+			wantError: true,
+		},
+		{
+			name:      "zero rotation",
+			config:    map[string]interface{}{"rotation_days": 0},
+			wantError: true,
+		},
+		{
+			name:      "invalid period",
+			config:    map[string]interface{}{"rotation_period": "P"},
+			wantError: true,
+		},
+		{
+			name:      "negative period",
+			config:    map[string]interface{}{"rotation_period": "-1h"},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(tt.config)
+			if tt.wantError {
+				if err == nil {
+					t.Error("Generate() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			if result["rotation_rfc3339"] != tt.wantRotation {
+				t.Errorf("Generate() rotation_rfc3339 = %s, want %s", result["rotation_rfc3339"], tt.wantRotation)
+			}
+			if result["expired"] != strconv.FormatBool(tt.wantExpired) {
+				t.Errorf("Generate() expired = %s, want %v", result["expired"], tt.wantExpired)
+			}
+
+			rotation, _ := time.Parse(time.RFC3339, tt.wantRotation)
+			if result["rotation_unix"] != strconv.FormatInt(rotation.Unix(), 10) {
+				t.Errorf("Generate() rotation_unix = %s", result["rotation_unix"])
+			}
+			expiresIn, err := strconv.ParseInt(result["expires_in_seconds"], 10, 64)
+			if err != nil {
+				t.Fatalf("Generate() invalid expires_in_seconds: %v", err)
+			}
+			if tt.wantExpired && expiresIn != 0 {
+				t.Errorf("Generate() expires_in_seconds = %d, want 0", expiresIn)
+			}
+			if !tt.wantExpired && expiresIn <= 0 {
+				t.Errorf("Generate() expires_in_seconds = %d, want positive", expiresIn)
+			}
+		})
+	}
+}
+
+func TestRotatingGenerator_DefaultNow(t *testing.T) {
+	gen := &RotatingGenerator{}
+
+	result, err := gen.Generate(map[string]interface{}{"rotation_hours": 1})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	expiresIn, _ := strconv.Atoi(result["expires_in_seconds"])
+	if expiresIn < 3590 || expiresIn > 3600 {
+		t.Errorf("Generate() expires_in_seconds = %d, want about 3600", expiresIn)
+	}
+	if result["expired"] != "false" {
+		t.Errorf("Generate() expired = %s, want false", result["expired"])
+	}
+}
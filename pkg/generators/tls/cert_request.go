@@ -3,14 +3,18 @@ package tls
 import (
 	"crypto/rand"
 	"crypto/x509"
-	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
+	"io"
 )
 
 type CertRequestGenerator struct{}
 
 func (g *CertRequestGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *CertRequestGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	// Parse private key from config
 	privateKeyPEM := getStringConfig(config, "private_key_pem", "")
 	if privateKeyPEM == "" {
@@ -42,16 +46,47 @@ func (g *CertRequestGenerator) Generate(config map[string]interface{}) (map[stri
 		}
 	}
 
+	sans, err := parseSubjectAltNames(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requested usages are carried in the extensionRequest attribute for the signing CA
+	keyUsage, extKeyUsage, err := parseAllowedUses(config, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	isCA := getBoolConfig(config, "is_ca", false)
+	if isCA {
+		keyUsage |= x509.KeyUsageCertSign
+	}
+	extensions, err := requestedExtensions(keyUsage, extKeyUsage, isCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode requested extensions: %w", err)
+	}
+	extraExtensions, err := parseExtraExtensions(config)
+	if err != nil {
+		return nil, err
+	}
+	extensions = append(extensions, extraExtensions...)
+
+	subject, err := parseSubject(config, "")
+	if err != nil {
+		return nil, err
+	}
+
 	// Create CSR template
 	template := x509.CertificateRequest{
-		Subject: pkix.Name{
-			CommonName: getStringConfig(config, "common_name", ""),
-		},
-		DNSNames: getStringSliceConfig(config, "dns_names"),
+		Subject:         subject,
+		DNSNames:        sans.DNSNames,
+		IPAddresses:     sans.IPAddresses,
+		URIs:            sans.URIs,
+		EmailAddresses:  sans.EmailAddresses,
+		ExtraExtensions: extensions,
 	}
 
 	// Generate CSR
-	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &template, privateKey)
+	csrDER, err := x509.CreateCertificateRequest(random, &template, privateKey)
 	if err != nil {
 		return nil, err
 	}
@@ -94,6 +94,54 @@ func TestCertRequestGenerator_Generate(t *testing.T) {
 		})
 	}
 
+	// Test SANs, requested usages and custom extensions
+	t.Run("CSR with extensions", func(t *testing.T) {
+		config := map[string]interface{}{
+			"private_key_pem": string(privateKeyPEM),
+			"subject": map[string]interface{}{
+				"common_name":  "client",
+				"organization": []interface{}{"Test Org"},
+			},
+			"ip_addresses":    []interface{}{"192.168.1.10"},
+			"uris":            []interface{}{"spiffe://example.com/client"},
+			"email_addresses": []interface{}{"ops@example.com"},
+			"allowed_uses":    []interface{}{"digital_signature", "client_auth"},
+			"extensions": []interface{}{
+				map[string]interface{}{"oid": "1.3.6.1.4.1.99999.2", "value_hex": "0500"},
+			},
+		}
+		result, err := gen.Generate(config)
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+
+		block, _ := pem.Decode([]byte(result["cert_request_pem"]))
+		if block == nil {
+			t.Fatal("Generate() invalid CSR PEM")
+		}
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			t.Fatalf("Generate() failed to parse CSR: %v", err)
+		}
+
+		if csr.Subject.CommonName != "client" || len(csr.Subject.Organization) != 1 {
+			t.Errorf("Generate() CSR subject = %v, want CN=client O=Test Org", csr.Subject)
+		}
+		if len(csr.IPAddresses) != 1 || len(csr.URIs) != 1 || len(csr.EmailAddresses) != 1 {
+			t.Errorf("Generate() CSR SANs = %v %v %v, want 1 IP, 1 URI, 1 email", csr.IPAddresses, csr.URIs, csr.EmailAddresses)
+		}
+
+		requested := map[string]bool{}
+		for _, ext := range csr.Extensions {
+			requested[ext.Id.String()] = true
+		}
+		for _, oid := range []string{"2.5.29.15", "2.5.29.37", "1.3.6.1.4.1.99999.2"} {
+			if !requested[oid] {
+				t.Errorf("Generate() CSR missing requested extension %s", oid)
+			}
+		}
+	})
+
 	// Test missing private key
 	t.Run("missing private key", func(t *testing.T) {
 		config := map[string]interface{}{
@@ -0,0 +1,458 @@
+package tls
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	oidExtensionKeyUsage         = asn1.ObjectIdentifier{2, 5, 29, 15}
+	oidExtensionExtendedKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37}
+	oidExtensionBasicConstraints = asn1.ObjectIdentifier{2, 5, 29, 19}
+)
+
+// keyUsages maps allowed_uses names to X.509 key usage bits
+var keyUsages = map[string]x509.KeyUsage{
+	"digital_signature":  x509.KeyUsageDigitalSignature,
+	"content_commitment": x509.KeyUsageContentCommitment,
+	"key_encipherment":   x509.KeyUsageKeyEncipherment,
+	"data_encipherment":  x509.KeyUsageDataEncipherment,
+	"key_agreement":      x509.KeyUsageKeyAgreement,
+	"cert_signing":       x509.KeyUsageCertSign,
+	"crl_signing":        x509.KeyUsageCRLSign,
+	"encipher_only":      x509.KeyUsageEncipherOnly,
+	"decipher_only":      x509.KeyUsageDecipherOnly,
+}
+
+// extKeyUsages maps allowed_uses names to X.509 extended key usages
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	"any_extended":                      x509.ExtKeyUsageAny,
+	"server_auth":                       x509.ExtKeyUsageServerAuth,
+	"client_auth":                       x509.ExtKeyUsageClientAuth,
+	"code_signing":                      x509.ExtKeyUsageCodeSigning,
+	"email_protection":                  x509.ExtKeyUsageEmailProtection,
+	"ipsec_end_system":                  x509.ExtKeyUsageIPSECEndSystem,
+	"ipsec_tunnel":                      x509.ExtKeyUsageIPSECTunnel,
+	"ipsec_user":                        x509.ExtKeyUsageIPSECUser,
+	"timestamping":                      x509.ExtKeyUsageTimeStamping,
+	"ocsp_signing":                      x509.ExtKeyUsageOCSPSigning,
+	"microsoft_server_gated_crypto":     x509.ExtKeyUsageMicrosoftServerGatedCrypto,
+	"netscape_server_gated_crypto":      x509.ExtKeyUsageNetscapeServerGatedCrypto,
+	"microsoft_commercial_code_signing": x509.ExtKeyUsageMicrosoftCommercialCodeSigning,
+	"microsoft_kernel_code_signing":     x509.ExtKeyUsageMicrosoftKernelCodeSigning,
+}
+
+// extKeyUsageOIDs are the OIDs used when encoding extended key usages into a CSR
+var extKeyUsageOIDs = map[x509.ExtKeyUsage]asn1.ObjectIdentifier{
+	x509.ExtKeyUsageAny:                            {2, 5, 29, 37, 0},
+	x509.ExtKeyUsageServerAuth:                     {1, 3, 6, 1, 5, 5, 7, 3, 1},
+	x509.ExtKeyUsageClientAuth:                     {1, 3, 6, 1, 5, 5, 7, 3, 2},
+	x509.ExtKeyUsageCodeSigning:                    {1, 3, 6, 1, 5, 5, 7, 3, 3},
+	x509.ExtKeyUsageEmailProtection:                {1, 3, 6, 1, 5, 5, 7, 3, 4},
+	x509.ExtKeyUsageIPSECEndSystem:                 {1, 3, 6, 1, 5, 5, 7, 3, 5},
+	x509.ExtKeyUsageIPSECTunnel:                    {1, 3, 6, 1, 5, 5, 7, 3, 6},
+	x509.ExtKeyUsageIPSECUser:                      {1, 3, 6, 1, 5, 5, 7, 3, 7},
+	x509.ExtKeyUsageTimeStamping:                   {1, 3, 6, 1, 5, 5, 7, 3, 8},
+	x509.ExtKeyUsageOCSPSigning:                    {1, 3, 6, 1, 5, 5, 7, 3, 9},
+	x509.ExtKeyUsageMicrosoftServerGatedCrypto:     {1, 3, 6, 1, 4, 1, 311, 10, 3, 3},
+	x509.ExtKeyUsageNetscapeServerGatedCrypto:      {2, 16, 840, 1, 113730, 4, 1},
+	x509.ExtKeyUsageMicrosoftCommercialCodeSigning: {1, 3, 6, 1, 4, 1, 311, 2, 1, 22},
+	x509.ExtKeyUsageMicrosoftKernelCodeSigning:     {1, 3, 6, 1, 4, 1, 311, 61, 1, 1},
+}
+
+// parseAllowedUses converts the allowed_uses config list into key usage bits and extended key usages.
+// The defaults are returned when allowed_uses is not set.
+func parseAllowedUses(config map[string]interface{}, defaultUsage x509.KeyUsage, defaultExtUsage []x509.ExtKeyUsage) (x509.KeyUsage, []x509.ExtKeyUsage, error) {
+	uses := getStringListConfig(config, "allowed_uses")
+	if uses == nil {
+		return defaultUsage, defaultExtUsage, nil
+	}
+
+	var keyUsage x509.KeyUsage
+	var extKeyUsage []x509.ExtKeyUsage
+	for _, use := range uses {
+		name := strings.ToLower(strings.TrimSpace(use))
+		if ku, ok := keyUsages[name]; ok {
+			keyUsage |= ku
+			continue
+		}
+		if eku, ok := extKeyUsages[name]; ok {
+			extKeyUsage = append(extKeyUsage, eku)
+			continue
+		}
+		return 0, nil, fmt.Errorf("unsupported allowed_uses value: %s", use)
+	}
+	return keyUsage, extKeyUsage, nil
+}
+
+// subjectKeys are the config keys of the certificate subject
+var subjectKeys = []string{
+	"common_name", "serial_number", "organization", "organizational_unit", "country",
+	"province", "locality", "street_address", "postal_code",
+}
+
+// parseSubject builds the certificate subject from either a nested "subject" map or top-level config keys.
+// Mixing both is rejected, since the top-level keys would otherwise be ignored.
+func parseSubject(config map[string]interface{}, defaultCommonName string) (pkix.Name, error) {
+	source := config
+	if nested, ok := config["subject"].(map[string]interface{}); ok {
+		for _, key := range subjectKeys {
+			if _, exists := config[key]; exists {
+				return pkix.Name{}, fmt.Errorf("%s must be set under subject when subject is used", key)
+			}
+		}
+		source = nested
+	}
+
+	return pkix.Name{
+		CommonName:         getStringConfig(source, "common_name", defaultCommonName),
+		SerialNumber:       getStringConfig(source, "serial_number", ""),
+		Organization:       getStringListConfig(source, "organization"),
+		OrganizationalUnit: getStringListConfig(source, "organizational_unit"),
+		Country:            getStringListConfig(source, "country"),
+		Province:           getStringListConfig(source, "province"),
+		Locality:           getStringListConfig(source, "locality"),
+		StreetAddress:      getStringListConfig(source, "street_address"),
+		PostalCode:         getStringListConfig(source, "postal_code"),
+	}, nil
+}
+
+// subjectAltNames holds the parsed SAN entries of a certificate or CSR
+type subjectAltNames struct {
+	DNSNames       []string
+	IPAddresses    []net.IP
+	URIs           []*url.URL
+	EmailAddresses []string
+}
+
+// parseSubjectAltNames reads dns_names, ip_addresses, uris and email_addresses from config
+func parseSubjectAltNames(config map[string]interface{}) (subjectAltNames, error) {
+	sans := subjectAltNames{
+		DNSNames:       getStringListConfig(config, "dns_names"),
+		EmailAddresses: getStringListConfig(config, "email_addresses"),
+	}
+
+	ips, err := parseIPAddresses(getStringListConfig(config, "ip_addresses"))
+	if err != nil {
+		return subjectAltNames{}, err
+	}
+	sans.IPAddresses = ips
+
+	uris, err := parseURIs(getStringListConfig(config, "uris"))
+	if err != nil {
+		return subjectAltNames{}, err
+	}
+	sans.URIs = uris
+
+	return sans, nil
+}
+
+func parseIPAddresses(values []string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, v := range values {
+		ip := net.ParseIP(strings.TrimSpace(v))
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address: %s", v)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+func parseURIs(values []string) ([]*url.URL, error) {
+	var uris []*url.URL
+	for _, v := range values {
+		u, err := url.Parse(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid URI %s: %w", v, err)
+		}
+		if u.Scheme == "" {
+			return nil, fmt.Errorf("invalid URI %s: missing scheme", v)
+		}
+		uris = append(uris, u)
+	}
+	return uris, nil
+}
+
+// parseExtraExtensions reads custom extensions from the "extensions" config list.
+// Each entry needs an "oid" and exactly one of "value_base64" (DER), "value_hex" (DER)
+// or "value_string" (encoded as an ASN.1 UTF8String).
+func parseExtraExtensions(config map[string]interface{}) ([]pkix.Extension, error) {
+	raw, ok := config["extensions"].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	var extensions []pkix.Extension
+	for i, item := range raw {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("extensions[%d] must be an object", i)
+		}
+
+		oid, err := parseOID(getStringConfig(entry, "oid", ""))
+		if err != nil {
+			return nil, fmt.Errorf("extensions[%d]: %w", i, err)
+		}
+
+		var value []byte
+		switch {
+		case getStringConfig(entry, "value_base64", "") != "":
+			value, err = base64.StdEncoding.DecodeString(getStringConfig(entry, "value_base64", ""))
+		case getStringConfig(entry, "value_hex", "") != "":
+			value, err = hex.DecodeString(getStringConfig(entry, "value_hex", ""))
+		case getStringConfig(entry, "value_string", "") != "":
+			value, err = asn1.MarshalWithParams(getStringConfig(entry, "value_string", ""), "utf8")
+		default:
+			return nil, fmt.Errorf("extensions[%d]: one of value_base64, value_hex or value_string is required", i)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("extensions[%d]: invalid value: %w", i, err)
+		}
+
+		extensions = append(extensions, pkix.Extension{
+			Id:       oid,
+			Critical: getBoolConfig(entry, "critical", false),
+			Value:    value,
+		})
+	}
+	return extensions, nil
+}
+
+func parseOID(s string) (asn1.ObjectIdentifier, error) {
+	if s == "" {
+		return nil, fmt.Errorf("oid is required")
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid oid: %s", s)
+	}
+	oid := make(asn1.ObjectIdentifier, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid oid: %s", s)
+		}
+		oid[i] = n
+	}
+	return oid, nil
+}
+
+// parseNotBefore resolves the not_before config value. It accepts an RFC3339 timestamp
+// or a duration relative to now (e.g. "-1h" to backdate by one hour).
+func parseNotBefore(config map[string]interface{}, now time.Time) (time.Time, error) {
+	value := strings.TrimSpace(getStringConfig(config, "not_before", ""))
+	if value == "" {
+		return now, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	offset, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid not_before %q: must be an RFC3339 timestamp or a duration", value)
+	}
+	return now.Add(offset), nil
+}
+
+// readyForRenewal reports whether the certificate is inside its early renewal window
+func readyForRenewal(notAfter time.Time, earlyRenewalHours int, now time.Time) bool {
+	return !now.Before(notAfter.Add(-time.Duration(earlyRenewalHours) * time.Hour))
+}
+
+// renewalOutputs returns the renewal outputs of a certificate. renewal_time is only set when
+// early_renewal_hours is configured; the controller schedules the rotation of the secret at that time.
+func renewalOutputs(config map[string]interface{}, notAfter time.Time, now time.Time) map[string]string {
+	earlyRenewalHours := getIntConfig(config, "early_renewal_hours", 0)
+	outputs := map[string]string{
+		"ready_for_renewal": strconv.FormatBool(readyForRenewal(notAfter, earlyRenewalHours, now)),
+	}
+	if earlyRenewalHours > 0 {
+		outputs["renewal_time"] = notAfter.Add(-time.Duration(earlyRenewalHours) * time.Hour).Format(time.RFC3339)
+	}
+	return outputs
+}
+
+// requestedExtensions encodes key usage, extended key usage and basic constraints
+// as extensions so they can be carried in a CSR extensionRequest attribute.
+func requestedExtensions(keyUsage x509.KeyUsage, extKeyUsage []x509.ExtKeyUsage, isCA bool) ([]pkix.Extension, error) {
+	var extensions []pkix.Extension
+
+	if keyUsage != 0 {
+		value, err := marshalKeyUsage(keyUsage)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionKeyUsage, Critical: true, Value: value})
+	}
+
+	if len(extKeyUsage) > 0 {
+		oids := make([]asn1.ObjectIdentifier, 0, len(extKeyUsage))
+		for _, eku := range extKeyUsage {
+			oids = append(oids, extKeyUsageOIDs[eku])
+		}
+		value, err := asn1.Marshal(oids)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionExtendedKeyUsage, Value: value})
+	}
+
+	if isCA {
+		value, err := asn1.Marshal(struct {
+			IsCA bool `asn1:"optional"`
+		}{IsCA: true})
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionBasicConstraints, Critical: true, Value: value})
+	}
+
+	return extensions, nil
+}
+
+func marshalKeyUsage(ku x509.KeyUsage) ([]byte, error) {
+	var a [2]byte
+	a[0] = reverseBitsInAByte(byte(ku))
+	a[1] = reverseBitsInAByte(byte(ku >> 8))
+
+	l := 1
+	if a[1] != 0 {
+		l = 2
+	}
+	bitString := a[:l]
+	return asn1.Marshal(asn1.BitString{Bytes: bitString, BitLength: asn1BitLength(bitString)})
+}
+
+func reverseBitsInAByte(in byte) byte {
+	b1 := in>>4 | in<<4
+	b2 := b1>>2&0x33 | b1<<2&0xcc
+	return b2>>1&0x55 | b2<<1&0xaa
+}
+
+// asn1BitLength returns the bit-length of bitString by considering the most-significant bit in a byte to be the "first" bit
+func asn1BitLength(bitString []byte) int {
+	bitLen := len(bitString) * 8
+	for i := range bitString {
+		b := bitString[len(bitString)-i-1]
+		for bit := uint(0); bit < 8; bit++ {
+			if (b>>bit)&1 == 1 {
+				return bitLen
+			}
+			bitLen--
+		}
+	}
+	return 0
+}
+
+// sanPolicy restricts which SANs a locally signed certificate may carry
+type sanPolicy struct {
+	DNSDomains   []string
+	IPRanges     []*net.IPNet
+	URIDomains   []string
+	EmailDomains []string
+	Filter       bool
+}
+
+// parseSANPolicy reads the allowed_* SAN restrictions from the signer config
+func parseSANPolicy(config map[string]interface{}) (sanPolicy, error) {
+	policy := sanPolicy{
+		DNSDomains:   getStringListConfig(config, "allowed_dns_domains"),
+		URIDomains:   getStringListConfig(config, "allowed_uri_domains"),
+		EmailDomains: getStringListConfig(config, "allowed_email_domains"),
+	}
+	for _, cidr := range getStringListConfig(config, "allowed_ip_ranges") {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return sanPolicy{}, fmt.Errorf("invalid allowed_ip_ranges entry %s: %w", cidr, err)
+		}
+		policy.IPRanges = append(policy.IPRanges, ipNet)
+	}
+
+	switch mode := strings.ToLower(getStringConfig(config, "san_policy", "reject")); mode {
+	case "reject":
+	case "filter":
+		policy.Filter = true
+	default:
+		return sanPolicy{}, fmt.Errorf("unsupported san_policy: %s (supported: reject, filter)", mode)
+	}
+	return policy, nil
+}
+
+// apply enforces the policy on the given SANs, either dropping or rejecting entries that are not allowed
+func (p sanPolicy) apply(sans subjectAltNames) (subjectAltNames, error) {
+	var result subjectAltNames
+
+	for _, name := range sans.DNSNames {
+		if p.DNSDomains != nil && !domainAllowed(strings.TrimPrefix(name, "*."), p.DNSDomains) {
+			if !p.Filter {
+				return subjectAltNames{}, fmt.Errorf("DNS name %s is not allowed by CA policy", name)
+			}
+			continue
+		}
+		result.DNSNames = append(result.DNSNames, name)
+	}
+
+	for _, ip := range sans.IPAddresses {
+		if p.IPRanges != nil && !ipAllowed(ip, p.IPRanges) {
+			if !p.Filter {
+				return subjectAltNames{}, fmt.Errorf("IP address %s is not allowed by CA policy", ip)
+			}
+			continue
+		}
+		result.IPAddresses = append(result.IPAddresses, ip)
+	}
+
+	for _, uri := range sans.URIs {
+		if p.URIDomains != nil && !domainAllowed(uri.Hostname(), p.URIDomains) {
+			if !p.Filter {
+				return subjectAltNames{}, fmt.Errorf("URI %s is not allowed by CA policy", uri)
+			}
+			continue
+		}
+		result.URIs = append(result.URIs, uri)
+	}
+
+	for _, email := range sans.EmailAddresses {
+		at := strings.LastIndex(email, "@")
+		if p.EmailDomains != nil && (at < 0 || !domainAllowed(email[at+1:], p.EmailDomains)) {
+			if !p.Filter {
+				return subjectAltNames{}, fmt.Errorf("email address %s is not allowed by CA policy", email)
+			}
+			continue
+		}
+		result.EmailAddresses = append(result.EmailAddresses, email)
+	}
+
+	return result, nil
+}
+
+// domainAllowed reports whether name equals or is a subdomain of one of the allowed domains
+func domainAllowed(name string, domains []string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "."))
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func ipAllowed(ip net.IP, ranges []*net.IPNet) bool {
+	for _, r := range ranges {
+		if r.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -80,3 +80,19 @@ func publicKeysMatch(privateKey, publicKey interface{}) bool {
 		return false
 	}
 }
+
+func getBoolConfig(config map[string]interface{}, key string, defaultValue bool) bool {
+	if val, ok := config[key].(bool); ok {
+		return val
+	}
+	return defaultValue
+}
+
+// getStringListConfig is like getStringSliceConfig but also accepts a single string value.
+// Returns nil if the key doesn't exist.
+func getStringListConfig(config map[string]interface{}, key string) []string {
+	if val, ok := config[key].(string); ok {
+		return []string{val}
+	}
+	return getStringSliceConfig(config, key)
+}
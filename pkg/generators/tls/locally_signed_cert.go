@@ -5,6 +5,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"math/big"
 	"time"
 )
@@ -12,6 +13,10 @@ import (
 type LocallySignedCertGenerator struct{}
 
 func (g *LocallySignedCertGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *LocallySignedCertGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	// Parse CSR
 	csrPEM := getStringConfig(config, "cert_request_pem", "")
 	if csrPEM == "" {
@@ -89,26 +94,72 @@ func (g *LocallySignedCertGenerator) Generate(config map[string]interface{}) (ma
 	if validityHours <= 0 {
 		return nil, fmt.Errorf("validity_period_hours must be positive, got %d", validityHours)
 	}
-	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	serialNumber, err := rand.Int(random, new(big.Int).Lsh(big.NewInt(1), 128))
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate serial number: %w", err)
 	}
 	now := time.Now().UTC()
+	notBefore, err := parseNotBefore(config, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Subject and SANs come from the CSR unless overridden in config
+	subject := csr.Subject
+	_, hasSubject := config["subject"]
+	_, hasCommonName := config["common_name"]
+	if hasSubject || hasCommonName {
+		if subject, err = parseSubject(config, ""); err != nil {
+			return nil, err
+		}
+	}
+	sans, err := resolveSANs(config, csr)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := parseSANPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+	sans, err = policy.apply(sans)
+	if err != nil {
+		return nil, err
+	}
+
+	isCA := getBoolConfig(config, "is_ca", false)
+	keyUsage, extKeyUsage, err := parseAllowedUses(config,
+		x509.KeyUsageKeyEncipherment|x509.KeyUsageDigitalSignature,
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})
+	if err != nil {
+		return nil, err
+	}
+	if isCA {
+		keyUsage |= x509.KeyUsageCertSign
+	}
+
+	extraExtensions, err := parseExtraExtensions(config)
+	if err != nil {
+		return nil, err
+	}
+
 	template := x509.Certificate{
 		SerialNumber:          serialNumber,
-		Subject:               csr.Subject,
-		NotBefore:             now,
-		NotAfter:              now.Add(time.Duration(validityHours) * time.Hour),
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		DNSNames:              csr.DNSNames,
-		IPAddresses:           csr.IPAddresses,
-		URIs:                  csr.URIs,
+		Subject:               subject,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(time.Duration(validityHours) * time.Hour),
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           extKeyUsage,
+		DNSNames:              sans.DNSNames,
+		IPAddresses:           sans.IPAddresses,
+		URIs:                  sans.URIs,
+		EmailAddresses:        sans.EmailAddresses,
 		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		ExtraExtensions:       extraExtensions,
 	}
 
 	// Sign certificate
-	certDER, err := x509.CreateCertificate(rand.Reader, &template, caCert, csr.PublicKey, caPrivateKey)
+	certDER, err := x509.CreateCertificate(random, &template, caCert, csr.PublicKey, caPrivateKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create certificate: %w", err)
 	}
@@ -119,11 +170,42 @@ func (g *LocallySignedCertGenerator) Generate(config map[string]interface{}) (ma
 		Bytes: certDER,
 	})
 
-	return map[string]string{
+	outputs := map[string]string{
 		"cert_pem":            string(certPEM),
 		"ca_key_algorithm":    getKeyAlgorithm(caPrivateKey),
 		"validity_start_time": template.NotBefore.Format(time.RFC3339),
 		"validity_end_time":   template.NotAfter.Format(time.RFC3339),
-		"ready_for_renewal":   "false",
-	}, nil
+	}
+	for k, v := range renewalOutputs(config, template.NotAfter, now) {
+		outputs[k] = v
+	}
+	return outputs, nil
+}
+
+// resolveSANs returns the SANs from the CSR, replacing each SAN type that is set in config
+func resolveSANs(config map[string]interface{}, csr *x509.CertificateRequest) (subjectAltNames, error) {
+	overrides, err := parseSubjectAltNames(config)
+	if err != nil {
+		return subjectAltNames{}, err
+	}
+
+	sans := subjectAltNames{
+		DNSNames:       csr.DNSNames,
+		IPAddresses:    csr.IPAddresses,
+		URIs:           csr.URIs,
+		EmailAddresses: csr.EmailAddresses,
+	}
+	if _, ok := config["dns_names"]; ok {
+		sans.DNSNames = overrides.DNSNames
+	}
+	if _, ok := config["ip_addresses"]; ok {
+		sans.IPAddresses = overrides.IPAddresses
+	}
+	if _, ok := config["uris"]; ok {
+		sans.URIs = overrides.URIs
+	}
+	if _, ok := config["email_addresses"]; ok {
+		sans.EmailAddresses = overrides.EmailAddresses
+	}
+	return sans, nil
 }
@@ -156,6 +156,99 @@ func TestLocallySignedCertGenerator_Generate(t *testing.T) {
 		})
 	}
 
+	// Test SAN overrides, CA policy and allowed uses
+	t.Run("SAN override and policy", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			config    map[string]interface{}
+			wantDNS   []string
+			wantIPs   int
+			wantError bool
+		}{
+			{
+				name: "override DNS names",
+				config: map[string]interface{}{
+					"dns_names":    []interface{}{"override.example.com"},
+					"ip_addresses": []interface{}{"10.0.0.5"},
+				},
+				wantDNS: []string{"override.example.com"},
+				wantIPs: 1,
+			},
+			{
+				name: "filter DNS names outside allowed domains",
+				config: map[string]interface{}{
+					"allowed_dns_domains": []interface{}{"www.test.example.com"},
+					"san_policy":          "filter",
+				},
+				wantDNS: []string{"www.test.example.com"},
+			},
+			{
+				name: "reject DNS names outside allowed domains",
+				config: map[string]interface{}{
+					"allowed_dns_domains": []interface{}{"other.com"},
+				},
+				wantError: true,
+			},
+			{
+				name: "reject IPs outside allowed ranges",
+				config: map[string]interface{}{
+					"ip_addresses":      []interface{}{"192.168.0.1"},
+					"allowed_ip_ranges": []interface{}{"10.0.0.0/8"},
+				},
+				wantError: true,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				config := map[string]interface{}{
+					"cert_request_pem":   string(csrPEM),
+					"ca_private_key_pem": string(caPrivateKeyPEM),
+					"ca_cert_pem":        string(caCertPEM),
+					"allowed_uses":       []interface{}{"digital_signature", "client_auth"},
+				}
+				for k, v := range tt.config {
+					config[k] = v
+				}
+
+				result, err := gen.Generate(config)
+				if tt.wantError {
+					if err == nil {
+						t.Error("Generate() expected error")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Generate() error = %v", err)
+				}
+
+				block, _ := pem.Decode([]byte(result["cert_pem"]))
+				if block == nil {
+					t.Fatal("Generate() invalid certificate PEM")
+				}
+				cert, err := x509.ParseCertificate(block.Bytes)
+				if err != nil {
+					t.Fatalf("Generate() failed to parse certificate: %v", err)
+				}
+
+				if len(cert.DNSNames) != len(tt.wantDNS) {
+					t.Fatalf("Generate() DNS names = %v, want %v", cert.DNSNames, tt.wantDNS)
+				}
+				for i, name := range tt.wantDNS {
+					if cert.DNSNames[i] != name {
+						t.Errorf("Generate() DNS name[%d] = %s, want %s", i, cert.DNSNames[i], name)
+					}
+				}
+				if len(cert.IPAddresses) != tt.wantIPs {
+					t.Errorf("Generate() IP addresses = %v, want %d", cert.IPAddresses, tt.wantIPs)
+				}
+				if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageClientAuth {
+					t.Errorf("Generate() ext key usage = %v, want client_auth", cert.ExtKeyUsage)
+				}
+			})
+		}
+	})
+
 	// Test missing CSR
 	t.Run("missing CSR", func(t *testing.T) {
 		config := map[string]interface{}{
@@ -1,39 +1,45 @@
 package tls
 
 import (
-	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
-	"golang.org/x/crypto/ssh"
+	"io"
 	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/logicIQ/secret-santa/pkg/entropy"
 )
 
 type PrivateKeyGenerator struct{}
 
 func (g *PrivateKeyGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *PrivateKeyGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	algorithm := strings.ToUpper(strings.TrimSpace(getStringConfig(config, "algorithm", "RSA")))
 
 	switch algorithm {
 	case "RSA":
-		return g.generateRSA(config)
+		return g.generateRSA(config, random)
 	case "ECDSA":
-		return g.generateECDSA(config)
+		return g.generateECDSA(config, random)
 	case "ED25519":
-		return g.generateED25519(config)
+		return g.generateED25519(config, random)
 	default:
 		return nil, fmt.Errorf("unsupported algorithm: %s (supported: RSA, ECDSA, ED25519)", algorithm)
 	}
 }
 
-func (g *PrivateKeyGenerator) generateRSA(config map[string]interface{}) (map[string]string, error) {
+func (g *PrivateKeyGenerator) generateRSA(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	bits := getIntConfig(config, "rsa_bits", 2048)
 
-	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	privateKey, err := entropy.RSAKey(random, bits)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +86,7 @@ func (g *PrivateKeyGenerator) generateRSA(config map[string]interface{}) (map[st
 	}, nil
 }
 
-func (g *PrivateKeyGenerator) generateECDSA(config map[string]interface{}) (map[string]string, error) {
+func (g *PrivateKeyGenerator) generateECDSA(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	curve := strings.ToUpper(strings.TrimSpace(getStringConfig(config, "ecdsa_curve", "P224")))
 
 	var ellipticCurve elliptic.Curve
@@ -97,7 +103,7 @@ func (g *PrivateKeyGenerator) generateECDSA(config map[string]interface{}) (map[
 		return nil, fmt.Errorf("unsupported ECDSA curve: %s", curve)
 	}
 
-	privateKey, err := ecdsa.GenerateKey(ellipticCurve, rand.Reader)
+	privateKey, err := entropy.ECDSAKey(ellipticCurve, random)
 	if err != nil {
 		return nil, err
 	}
@@ -137,8 +143,8 @@ func (g *PrivateKeyGenerator) generateECDSA(config map[string]interface{}) (map[
 	}, nil
 }
 
-func (g *PrivateKeyGenerator) generateED25519(config map[string]interface{}) (map[string]string, error) {
-	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+func (g *PrivateKeyGenerator) generateED25519(config map[string]interface{}, random io.Reader) (map[string]string, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(random)
 	if err != nil {
 		return nil, err
 	}
@@ -2,60 +2,95 @@ package tls
 
 import (
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
-	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"math/big"
 	"time"
+
+	"github.com/logicIQ/secret-santa/pkg/entropy"
 )
 
 type SelfSignedCertGenerator struct{}
 
 func (g *SelfSignedCertGenerator) Generate(config map[string]interface{}) (map[string]string, error) {
+	return g.GenerateWithEntropy(config, rand.Reader)
+}
+
+func (g *SelfSignedCertGenerator) GenerateWithEntropy(config map[string]interface{}, random io.Reader) (map[string]string, error) {
 	// TODO: Add support for ECDSA and Ed25519 key algorithms through key_algorithm config parameter
 	// Generate private key
 	keySize := getIntConfig(config, "key_size", 2048)
-	privateKey, err := rsa.GenerateKey(rand.Reader, keySize)
+	privateKey, err := entropy.RSAKey(random, keySize)
 	if err != nil {
 		return nil, err
 	}
 
 	// Create certificate template
-	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	serialNumber, err := rand.Int(random, new(big.Int).Lsh(big.NewInt(1), 128))
 	if err != nil {
 		return nil, err
 	}
 
+	now := time.Now()
+	notBefore, err := parseNotBefore(config, now)
+	if err != nil {
+		return nil, err
+	}
 	validityDays := getIntConfig(config, "validity_days", 365)
-	notBefore := time.Now()
-	notAfter := notBefore.Add(time.Duration(validityDays) * 24 * time.Hour)
+	validity := time.Duration(validityDays) * 24 * time.Hour
+	if hours := getIntConfig(config, "validity_period_hours", 0); hours > 0 {
+		validity = time.Duration(hours) * time.Hour
+	}
+	notAfter := notBefore.Add(validity)
+
+	subject, err := parseSubject(config, "localhost")
+	if err != nil {
+		return nil, err
+	}
+	sans, err := parseSubjectAltNames(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(sans.DNSNames) == 0 {
+		sans.DNSNames = []string{subject.CommonName}
+	}
+
+	isCA := getBoolConfig(config, "is_ca", false)
+	keyUsage, extKeyUsage, err := parseAllowedUses(config,
+		x509.KeyUsageKeyEncipherment|x509.KeyUsageDigitalSignature,
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})
+	if err != nil {
+		return nil, err
+	}
+	if isCA {
+		keyUsage |= x509.KeyUsageCertSign
+	}
 
-	dnsNames := getStringSliceConfig(config, "dns_names")
-	if len(dnsNames) == 0 {
-		dnsNames = []string{getStringConfig(config, "common_name", "localhost")}
+	extraExtensions, err := parseExtraExtensions(config)
+	if err != nil {
+		return nil, err
 	}
 
 	template := x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject: pkix.Name{
-			CommonName:         getStringConfig(config, "common_name", "localhost"),
-			Organization:       getStringSliceConfig(config, "organization"),
-			OrganizationalUnit: getStringSliceConfig(config, "organizational_unit"),
-			Country:            getStringSliceConfig(config, "country"),
-			Province:           getStringSliceConfig(config, "province"),
-			Locality:           getStringSliceConfig(config, "locality"),
-		},
-		NotBefore:   notBefore,
-		NotAfter:    notAfter,
-		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		DNSNames:    dnsNames,
+		SerialNumber:          serialNumber,
+		Subject:               subject,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           extKeyUsage,
+		DNSNames:              sans.DNSNames,
+		IPAddresses:           sans.IPAddresses,
+		URIs:                  sans.URIs,
+		EmailAddresses:        sans.EmailAddresses,
+		BasicConstraintsValid: isCA,
+		IsCA:                  isCA,
+		ExtraExtensions:       extraExtensions,
 	}
 
 	// Create certificate
-	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	certDER, err := x509.CreateCertificate(random, &template, &template, &privateKey.PublicKey, privateKey)
 	if err != nil {
 		return nil, err
 	}
@@ -79,12 +114,15 @@ func (g *SelfSignedCertGenerator) Generate(config map[string]interface{}) (map[s
 		return nil, fmt.Errorf("failed to encode private key to PEM")
 	}
 
-	return map[string]string{
+	outputs := map[string]string{
 		"cert_pem":            string(certPEM),
 		"private_key_pem":     string(privateKeyPEM),
 		"key_algorithm":       "RSA",
 		"validity_start_time": template.NotBefore.Format(time.RFC3339),
 		"validity_end_time":   template.NotAfter.Format(time.RFC3339),
-		"ready_for_renewal":   "false",
-	}, nil
+	}
+	for k, v := range renewalOutputs(config, template.NotAfter, now) {
+		outputs[k] = v
+	}
+	return outputs, nil
 }
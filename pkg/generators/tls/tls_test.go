@@ -4,6 +4,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"testing"
+	"time"
 )
 
 func TestSelfSignedCertGenerator_Generate(t *testing.T) {
@@ -114,3 +115,96 @@ func TestSelfSignedCertGenerator_Generate(t *testing.T) {
 		})
 	}
 }
+
+func TestSelfSignedCertGenerator_Extensions(t *testing.T) {
+	gen := &SelfSignedCertGenerator{}
+
+	config := map[string]interface{}{
+		"subject": map[string]interface{}{
+			"common_name":    "client",
+			"organization":   "Test Org",
+			"street_address": []interface{}{"1 Main St"},
+			"postal_code":    "94105",
+		},
+		"dns_names":       []interface{}{"client.example.com"},
+		"ip_addresses":    []interface{}{"10.0.0.1", "::1"},
+		"uris":            []interface{}{"spiffe://example.com/client"},
+		"email_addresses": []interface{}{"ops@example.com"},
+		"allowed_uses":    []interface{}{"digital_signature", "client_auth"},
+		"is_ca":           true,
+		"not_before":      "-1h",
+		"extensions": []interface{}{
+			map[string]interface{}{"oid": "1.3.6.1.4.1.99999.1", "value_string": "custom"},
+		},
+		"validity_period_hours": float64(24),
+		"early_renewal_hours":   float64(48),
+	}
+
+	result, err := gen.Generate(config)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	block, _ := pem.Decode([]byte(result["cert_pem"]))
+	if block == nil {
+		t.Fatal("Generate() invalid certificate PEM")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+
+	if cert.Subject.CommonName != "client" || len(cert.Subject.Organization) != 1 || cert.Subject.Organization[0] != "Test Org" {
+		t.Errorf("Certificate subject = %v, want CN=client O=Test Org", cert.Subject)
+	}
+	if len(cert.Subject.StreetAddress) != 1 || len(cert.Subject.PostalCode) != 1 {
+		t.Errorf("Certificate missing street address or postal code: %v", cert.Subject)
+	}
+	if len(cert.IPAddresses) != 2 || len(cert.URIs) != 1 || len(cert.EmailAddresses) != 1 {
+		t.Errorf("Certificate SANs = %v %v %v, want 2 IPs, 1 URI, 1 email", cert.IPAddresses, cert.URIs, cert.EmailAddresses)
+	}
+	if cert.KeyUsage != x509.KeyUsageDigitalSignature|x509.KeyUsageCertSign {
+		t.Errorf("Certificate key usage = %v, want digital_signature|cert_signing", cert.KeyUsage)
+	}
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageClientAuth {
+		t.Errorf("Certificate ext key usage = %v, want client_auth", cert.ExtKeyUsage)
+	}
+	if !cert.IsCA {
+		t.Error("Certificate is not a CA")
+	}
+	if time.Until(cert.NotBefore) > -59*time.Minute {
+		t.Errorf("Certificate not_before = %v, want backdated by 1h", cert.NotBefore)
+	}
+
+	found := false
+	for _, ext := range cert.Extensions {
+		if ext.Id.String() == "1.3.6.1.4.1.99999.1" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Certificate missing custom extension")
+	}
+
+	if result["ready_for_renewal"] != "true" {
+		t.Errorf("Generate() ready_for_renewal = %s, want true", result["ready_for_renewal"])
+	}
+	if want := cert.NotAfter.Add(-48 * time.Hour).Format(time.RFC3339); result["renewal_time"] != want {
+		t.Errorf("Generate() renewal_time = %s, want %s", result["renewal_time"], want)
+	}
+
+	invalid := []map[string]interface{}{
+		{"allowed_uses": []interface{}{"bogus"}},
+		{"ip_addresses": []interface{}{"not-an-ip"}},
+		{"uris": []interface{}{"no-scheme"}},
+		{"not_before": "yesterday"},
+		{"extensions": []interface{}{map[string]interface{}{"oid": "1.2.x", "value_hex": "0500"}}},
+		{"extensions": []interface{}{map[string]interface{}{"oid": "1.2.3"}}},
+		{"subject": map[string]interface{}{"common_name": "client"}, "organization": "Ignored Org"},
+	}
+	for _, cfg := range invalid {
+		if _, err := gen.Generate(cfg); err == nil {
+			t.Errorf("Generate() expected error for config %v", cfg)
+		}
+	}
+}
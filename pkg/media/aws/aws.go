@@ -153,6 +153,31 @@ func (m *AWSSecretsManagerMedia) Store(ctx context.Context, secretSanta *secrets
 	return nil
 }
 
+// Rotate stores data as the new current version of the secret, creating the secret if it does not exist
+func (m *AWSSecretsManagerMedia) Rotate(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	cfg, err := loadAWSConfig(ctx, m.Region)
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := secretsmanager.NewFromConfig(cfg)
+
+	secretName := resolveSecretName(m.SecretName, secretSanta, "")
+
+	_, err = client.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(secretName),
+		SecretString: aws.String(data),
+	})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return m.Store(ctx, secretSanta, data, enableMetadata)
+		}
+		return err
+	}
+	return nil
+}
+
 func (m *AWSSecretsManagerMedia) GetType() string {
 	return "aws-secrets-manager"
 }
@@ -165,6 +190,15 @@ type AWSParameterStoreMedia struct {
 }
 
 func (m *AWSParameterStoreMedia) Store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	return m.put(ctx, secretSanta, data, enableMetadata, false)
+}
+
+// Rotate overwrites the parameter value, creating the parameter if it does not exist
+func (m *AWSParameterStoreMedia) Rotate(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	return m.put(ctx, secretSanta, data, enableMetadata, true)
+}
+
+func (m *AWSParameterStoreMedia) put(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool, overwrite bool) error {
 	cfg, err := loadAWSConfig(ctx, m.Region)
 	if err != nil {
 		return fmt.Errorf("failed to load AWS config: %w", err)
@@ -191,10 +225,18 @@ func (m *AWSParameterStoreMedia) Store(ctx context.Context, secretSanta *secrets
 	_, err = client.PutParameter(ctx, input)
 	if err != nil {
 		var paramExists *ssm_types.ParameterAlreadyExists
-		if errors.As(err, &paramExists) {
+		if !errors.As(err, &paramExists) {
+			return err
+		}
+		if !overwrite {
 			return nil
 		}
-		return err
+		// Parameter Store rejects tags when overwriting, so existing tags are kept as-is
+		input.Tags = nil
+		input.Overwrite = aws.Bool(true)
+		if _, err := client.PutParameter(ctx, input); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -0,0 +1,380 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+	"github.com/logicIQ/secret-santa/pkg/media"
+)
+
+const (
+	// DefaultPath stores files per namespace in the directory, the format extension is appended
+	DefaultPath = "{{ .Namespace }}/{{ .SecretName }}"
+	// DefaultFileMode only lets the owner read the secret
+	DefaultFileMode os.FileMode = 0o600
+	// DefaultDirMode is used for directories created below the base directory
+	DefaultDirMode os.FileMode = 0o700
+)
+
+var (
+	envKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	extensions  = map[string]string{"raw": "", "env": ".env", "json": ".json", "yaml": ".yaml"}
+)
+
+// FileMedia writes secrets to files in a local directory, e.g. an emptyDir shared with a sidecar or
+// the output directory of an offline bootstrap
+type FileMedia struct {
+	// Directory is the base directory, created if it does not exist
+	Directory string
+	// Root is a directory containing Directory that the media never leaves, defaults to Directory.
+	// Root is trusted and created if missing. Below it directories are created one at a time and
+	// symbolic links are rejected, so links planted by other writers of Root are never followed.
+	Root string
+	// Path is a template with .Namespace, .Name and .SecretName relative to Directory
+	Path string
+	// Format is raw (default), env, json or yaml. The structured formats parse the template output
+	// as a YAML or JSON object.
+	Format   string
+	FileMode os.FileMode
+	DirMode  os.FileMode
+}
+
+func (m *FileMedia) Store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	return m.store(secretSanta, data, enableMetadata, true)
+}
+
+// Rotate atomically replaces the file, readers see either the old or the new content
+func (m *FileMedia) Rotate(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	return m.store(secretSanta, data, enableMetadata, false)
+}
+
+func (m *FileMedia) GetType() string {
+	return "file"
+}
+
+func (m *FileMedia) store(secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool, createOnce bool) error {
+	if m.Directory == "" {
+		return fmt.Errorf("directory is required")
+	}
+	format := m.Format
+	if format == "" {
+		format = "raw"
+	}
+	extension, ok := extensions[format]
+	if !ok {
+		return fmt.Errorf("unsupported format %q, must be raw, env, json or yaml", m.Format)
+	}
+	target, err := m.resolvePath(secretSanta, extension)
+	if err != nil {
+		return err
+	}
+	content, err := formatContent(format, secretSanta, data, enableMetadata)
+	if err != nil {
+		return err
+	}
+
+	fileMode, dirMode := m.FileMode, m.DirMode
+	if fileMode == 0 {
+		fileMode = DefaultFileMode
+	}
+	if dirMode == 0 {
+		dirMode = DefaultDirMode
+	}
+	base := m.Root
+	if base == "" {
+		base = m.Directory
+	}
+	rel, err := filepath.Rel(base, target)
+	if err != nil || !filepath.IsLocal(rel) {
+		return fmt.Errorf("directory %s is outside the root %s", m.Directory, base)
+	}
+	if err := os.MkdirAll(base, dirMode); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", base, err)
+	}
+	root, err := os.OpenRoot(base)
+	if err != nil {
+		return fmt.Errorf("failed to open directory %s: %w", base, err)
+	}
+	defer root.Close()
+	dirPath := filepath.Dir(target)
+	dir, err := openDir(root, base, filepath.Dir(rel), dirMode)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	// All operations below use names inside dir, none of them follows a symbolic link at the target
+	name := filepath.Base(target)
+	if createOnce {
+		if _, err := dir.Lstat(name); err == nil {
+			return nil // File already exists, which is fine for create-once policy
+		}
+	}
+
+	// The content is written to a temporary file in the same directory, so it can be moved into place
+	// atomically and a partially written file is never visible
+	tmpName := "." + name + ".tmp-" + rand.Text()
+	tmp, err := dir.OpenFile(tmpName, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer dir.Remove(tmpName)
+	if err := writeFile(tmp, content, fileMode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Join(dirPath, tmpName), err)
+	}
+
+	if createOnce {
+		// Link fails if the target exists, so a file created concurrently is not replaced
+		if err := dir.Link(tmpName, name); err != nil {
+			if errors.Is(err, os.ErrExist) {
+				return nil
+			}
+			return fmt.Errorf("failed to create %s: %w", target, err)
+		}
+	} else if err := dir.Rename(tmpName, name); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", target, err)
+	}
+	return syncDir(dir, dirPath)
+}
+
+// openDir opens the directory at the relative path below root, creating missing directories.
+// Symbolic links are rejected, and every directory opened must be the one that was inspected, so a
+// link swapped in concurrently is not followed either.
+func openDir(root *os.Root, base, path string, mode os.FileMode) (*os.Root, error) {
+	dir, err := root.OpenRoot(".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open directory %s: %w", base, err)
+	}
+	if path == "." {
+		return dir, nil
+	}
+	current := base
+	for _, name := range strings.Split(path, string(filepath.Separator)) {
+		current = filepath.Join(current, name)
+		sub, err := openSubdir(dir, name, current, mode)
+		dir.Close()
+		if err != nil {
+			return nil, err
+		}
+		dir = sub
+	}
+	return dir, nil
+}
+
+// openSubdir opens or creates the directory name in dir, path is only used in errors
+func openSubdir(dir *os.Root, name, path string, mode os.FileMode) (*os.Root, error) {
+	if err := dir.Mkdir(name, mode); err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("failed to create directory %s: %w", path, err)
+	}
+	info, err := dir.Lstat(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", path, err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return nil, fmt.Errorf("%s is a symbolic link, refusing to follow it", path)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", path)
+	}
+	sub, err := dir.OpenRoot(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open directory %s: %w", path, err)
+	}
+	opened, err := sub.Stat(".")
+	if err != nil || !os.SameFile(info, opened) {
+		sub.Close()
+		return nil, fmt.Errorf("directory %s was replaced while it was opened", path)
+	}
+	return sub, nil
+}
+
+// resolvePath renders the path template and ensures the file stays inside the directory
+func (m *FileMedia) resolvePath(secretSanta *secretsantav1alpha1.SecretSanta, extension string) (string, error) {
+	text := m.Path
+	if text == "" {
+		text = DefaultPath + extension
+	}
+	tmpl, err := template.New("path").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse path template: %w", err)
+	}
+	secretName := secretSanta.Spec.SecretName
+	if secretName == "" {
+		secretName = secretSanta.Name
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct {
+		Namespace  string
+		Name       string
+		SecretName string
+	}{secretSanta.Namespace, secretSanta.Name, secretName})
+	if err != nil {
+		return "", fmt.Errorf("failed to render path template: %w", err)
+	}
+
+	path := buf.String()
+	if !filepath.IsLocal(path) || strings.HasSuffix(path, string(filepath.Separator)) {
+		return "", fmt.Errorf("path must be a file inside the directory, got: %q", path)
+	}
+	return filepath.Join(m.Directory, path), nil
+}
+
+// formatContent lays out the template output in the file format
+func formatContent(format string, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) ([]byte, error) {
+	if format == "raw" {
+		return []byte(data), nil
+	}
+	keys, err := media.ParseKeys(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		// JSON has no comments, so metadata is not written
+		encoded, err := json.MarshalIndent(keys, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encoded)
+		buf.WriteByte('\n')
+	case "yaml":
+		writeMetadataComments(&buf, secretSanta, enableMetadata)
+		encoded, err := yaml.Marshal(keys)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encoded)
+	case "env":
+		writeMetadataComments(&buf, secretSanta, enableMetadata)
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			if !envKeyRegex.MatchString(name) {
+				return nil, fmt.Errorf("invalid environment variable name %q", name)
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&buf, "%s=%s\n", name, quoteEnvValue(keys[name]))
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// quoteEnvValue single quotes values, values with single quotes or newlines are double quoted with
+// escapes
+func quoteEnvValue(value string) string {
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// writeMetadataComments writes the metadata as comments at the top of env and YAML files
+func writeMetadataComments(buf *bytes.Buffer, secretSanta *secretsantav1alpha1.SecretSanta, enableMetadata bool) {
+	if !enableMetadata {
+		return
+	}
+	fmt.Fprintf(buf, "# secrets.secret-santa.io/created-at: %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(buf, "# secrets.secret-santa.io/generator-types: %s\n", getGeneratorTypes(secretSanta.Spec.Generators))
+	fmt.Fprintf(buf, "# secrets.secret-santa.io/template-checksum: %s\n", calculateTemplateChecksum(secretSanta.Spec.Template))
+	fmt.Fprintf(buf, "# secrets.secret-santa.io/source-cr: %s/%s\n", secretSanta.Namespace, secretSanta.Name)
+}
+
+// ResolveDirectory returns the directory of a SecretSanta inside <root>/<namespace>, where root is
+// configured by the operator. Relative directories are relative to the namespace directory, absolute
+// ones must be below it, so SecretSantas of different namespaces never share files.
+func ResolveDirectory(root, namespace, directory string) (string, error) {
+	if !filepath.IsAbs(root) {
+		return "", fmt.Errorf("file media root must be an absolute path, got: %q", root)
+	}
+	if directory == "" {
+		return "", fmt.Errorf("directory is required")
+	}
+	if !filepath.IsLocal(namespace) || strings.ContainsRune(namespace, filepath.Separator) {
+		return "", fmt.Errorf("invalid namespace %q", namespace)
+	}
+	namespaceRoot := filepath.Join(root, namespace)
+	if !filepath.IsAbs(directory) {
+		directory = filepath.Join(namespaceRoot, directory)
+	}
+	directory = filepath.Clean(directory)
+	rel, err := filepath.Rel(namespaceRoot, directory)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("directory %q is outside the file media directory %s of the namespace", directory, namespaceRoot)
+	}
+	return directory, nil
+}
+
+// ParseMode parses an octal file mode such as "0640"
+func ParseMode(mode string) (os.FileMode, error) {
+	parsed, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || parsed == 0 || parsed > 0o777 {
+		return 0, fmt.Errorf("invalid file mode %q, must be octal permissions such as 0600", mode)
+	}
+	return os.FileMode(parsed), nil
+}
+
+func writeFile(f *os.File, content []byte, mode os.FileMode) error {
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	// Chmod is not subject to the umask, unlike the mode passed when creating a file
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir persists the directory entry of a new or renamed file, path is only used in errors
+func syncDir(dir *os.Root, path string) error {
+	d, err := dir.Open(".")
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return fmt.Errorf("failed to sync directory %s: %w", path, err)
+	}
+	return nil
+}
+
+// getGeneratorTypes extracts generator types from the configuration
+func getGeneratorTypes(generators []secretsantav1alpha1.GeneratorConfig) string {
+	types := make([]string, len(generators))
+	for i, gen := range generators {
+		types[i] = gen.Type
+	}
+	return strings.Join(types, ",")
+}
+
+// calculateTemplateChecksum creates a SHA256 checksum of the template
+func calculateTemplateChecksum(template string) string {
+	hash := sha256.Sum256([]byte(template))
+	return fmt.Sprintf("%x", hash)[:16]
+}
@@ -0,0 +1,303 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+)
+
+func testSecretSanta() *secretsantav1alpha1.SecretSanta {
+	return &secretsantav1alpha1.SecretSanta{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "default"},
+		Spec: secretsantav1alpha1.SecretSantaSpec{
+			Template:   "{{ .pass.value }}",
+			Generators: []secretsantav1alpha1.GeneratorConfig{{Name: "pass", Type: "random_password"}},
+		},
+	}
+}
+
+func TestFileMediaStore(t *testing.T) {
+	data := "password: \"it's a secret\"\nuser: admin\nport: 5432\n"
+
+	tests := []struct {
+		name           string
+		media          FileMedia
+		data           string
+		enableMetadata bool
+		path           string
+		want           string
+		wantMetadata   bool
+	}{
+		{
+			name:  "raw with default path",
+			media: FileMedia{},
+			data:  "password123",
+			path:  "default/test-secret",
+			want:  "password123",
+		},
+		{
+			name:  "env",
+			media: FileMedia{Format: "env"},
+			data:  data,
+			path:  "default/test-secret.env",
+			want:  "password=\"it's a secret\"\nport='5432'\nuser='admin'\n",
+		},
+		{
+			name:  "json",
+			media: FileMedia{Format: "json", Path: "{{ .Name }}/creds.json"},
+			data:  data,
+			path:  "test-secret/creds.json",
+			want:  "{\n  \"password\": \"it's a secret\",\n  \"port\": \"5432\",\n  \"user\": \"admin\"\n}\n",
+		},
+		{
+			name:  "yaml",
+			media: FileMedia{Format: "yaml"},
+			data:  data,
+			path:  "default/test-secret.yaml",
+			want:  "password: it's a secret\nport: \"5432\"\nuser: admin\n",
+		},
+		{
+			name:           "env with metadata",
+			media:          FileMedia{Format: "env"},
+			data:           "user: admin",
+			enableMetadata: true,
+			path:           "default/test-secret.env",
+			want:           "user='admin'\n",
+			wantMetadata:   true,
+		},
+		{
+			name:           "raw ignores metadata",
+			media:          FileMedia{},
+			data:           "password123",
+			enableMetadata: true,
+			path:           "default/test-secret",
+			want:           "password123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			media := tt.media
+			media.Directory = t.TempDir()
+
+			err := media.Store(context.Background(), testSecretSanta(), tt.data, tt.enableMetadata)
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(filepath.Join(media.Directory, tt.path))
+			require.NoError(t, err)
+			if tt.wantMetadata {
+				assert.Contains(t, string(content), "# secrets.secret-santa.io/source-cr: default/test-secret\n")
+				assert.Contains(t, string(content), "# secrets.secret-santa.io/generator-types: random_password\n")
+				assert.True(t, strings.HasSuffix(string(content), tt.want))
+			} else {
+				assert.Equal(t, tt.want, string(content))
+			}
+		})
+	}
+}
+
+func TestFileMediaModes(t *testing.T) {
+	dir := t.TempDir()
+	media := &FileMedia{Directory: dir, Path: "secrets/app/password", FileMode: 0o640, DirMode: 0o750}
+	require.NoError(t, media.Store(context.Background(), testSecretSanta(), "password123", false))
+
+	info, err := os.Stat(filepath.Join(dir, "secrets/app/password"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(dir, "secrets/app"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o750), info.Mode().Perm())
+
+	media = &FileMedia{Directory: dir, Path: "default"}
+	require.NoError(t, media.Store(context.Background(), testSecretSanta(), "password123", false))
+	info, err = os.Stat(filepath.Join(dir, "default"))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultFileMode, info.Mode().Perm())
+}
+
+func TestFileMediaCreateOnceAndRotate(t *testing.T) {
+	dir := t.TempDir()
+	media := &FileMedia{Directory: dir}
+	ss := testSecretSanta()
+	path := filepath.Join(dir, "default/test-secret")
+
+	require.NoError(t, media.Store(context.Background(), ss, "first", false))
+	require.NoError(t, media.Store(context.Background(), ss, "second", false))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(content))
+
+	require.NoError(t, media.Rotate(context.Background(), ss, "rotated", false))
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "rotated", string(content))
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestFileMediaSymlinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	victim := filepath.Join(outside, "victim")
+	require.NoError(t, os.WriteFile(victim, []byte("untouched"), 0o600))
+	ss := testSecretSanta()
+
+	// A planted link to a directory outside the root is not followed
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "team-a")))
+	media := &FileMedia{Directory: filepath.Join(root, "team-a", "app"), Root: root}
+	err := media.Store(context.Background(), ss, "secret", false)
+	assert.ErrorContains(t, err, "is a symbolic link")
+	_, err = os.Stat(filepath.Join(outside, "app"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Neither is a link to another directory inside the root
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "team-b", "app", "default"), 0o700))
+	require.NoError(t, os.Symlink(filepath.Join(root, "team-b", "app", "default"), filepath.Join(root, "team-c")))
+	media = &FileMedia{Directory: filepath.Join(root, "team-c"), Root: root, Path: "{{ .Name }}"}
+	assert.ErrorContains(t, media.Store(context.Background(), ss, "secret", false), "is a symbolic link")
+
+	// A link at the file itself is replaced on rotation, its target is left alone
+	media = &FileMedia{Directory: filepath.Join(root, "team-d"), Root: root}
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "team-d", "default"), 0o700))
+	target := filepath.Join(root, "team-d", "default", "test-secret")
+	require.NoError(t, os.Symlink(victim, target))
+	require.NoError(t, media.Rotate(context.Background(), ss, "rotated", false))
+	content, err := os.ReadFile(victim)
+	require.NoError(t, err)
+	assert.Equal(t, "untouched", string(content))
+	info, err := os.Lstat(target)
+	require.NoError(t, err)
+	assert.True(t, info.Mode().IsRegular())
+
+	media = &FileMedia{Directory: outside, Root: root}
+	assert.ErrorContains(t, media.Store(context.Background(), ss, "secret", false), "is outside the root")
+}
+
+func TestFileMediaErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		media   FileMedia
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "missing directory",
+			media:   FileMedia{},
+			wantErr: "directory is required",
+		},
+		{
+			name:    "unsupported format",
+			media:   FileMedia{Directory: "x", Format: "toml"},
+			wantErr: "unsupported format",
+		},
+		{
+			name:    "path traversal",
+			media:   FileMedia{Directory: "x", Path: "../{{ .Name }}"},
+			wantErr: "path must be a file inside the directory",
+		},
+		{
+			name:    "absolute path",
+			media:   FileMedia{Directory: "x", Path: "/etc/{{ .Name }}"},
+			wantErr: "path must be a file inside the directory",
+		},
+		{
+			name:    "unknown template field",
+			media:   FileMedia{Directory: "x", Path: "{{ .Missing }}"},
+			wantErr: "failed to render path template",
+		},
+		{
+			name:    "structured format with text output",
+			media:   FileMedia{Directory: "x", Format: "json"},
+			data:    "password123",
+			wantErr: "failed to parse template output",
+		},
+		{
+			name:    "nested value",
+			media:   FileMedia{Directory: "x", Format: "yaml"},
+			data:    "db:\n  user: admin",
+			wantErr: "must have a scalar value",
+		},
+		{
+			name:    "invalid env name",
+			media:   FileMedia{Directory: "x", Format: "env"},
+			data:    "db-user: admin",
+			wantErr: "invalid environment variable name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			media := tt.media
+			if media.Directory != "" {
+				media.Directory = t.TempDir()
+			}
+			err := media.Store(context.Background(), testSecretSanta(), tt.data, false)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	mode, err := ParseMode("0640")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), mode)
+
+	mode, err = ParseMode("600")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), mode)
+
+	for _, invalid := range []string{"", "0", "0888", "rw-r-----", "01777"} {
+		_, err := ParseMode(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestResolveDirectory(t *testing.T) {
+	tests := []struct {
+		name      string
+		root      string
+		directory string
+		want      string
+		wantErr   string
+	}{
+		{name: "absolute inside namespace", root: "/run/secret-santa", directory: "/run/secret-santa/default/app", want: "/run/secret-santa/default/app"},
+		{name: "relative", root: "/run/secret-santa/", directory: "team-a/app", want: "/run/secret-santa/default/team-a/app"},
+		{name: "namespace directory", root: "/run/secret-santa", directory: ".", want: "/run/secret-santa/default"},
+		{name: "root itself", root: "/run/secret-santa", directory: "/run/secret-santa", wantErr: "outside the file media directory"},
+		{name: "other namespace", root: "/run/secret-santa", directory: "/run/secret-santa/kube-system", wantErr: "outside the file media directory"},
+		{name: "absolute outside root", root: "/run/secret-santa", directory: "/etc", wantErr: "outside the file media directory"},
+		{name: "sibling prefix", root: "/run/secret-santa", directory: "/run/secret-santa/default-other", wantErr: "outside the file media directory"},
+		{name: "traversal", root: "/run/secret-santa", directory: "../kube-system", wantErr: "outside the file media directory"},
+		{name: "missing directory", root: "/run/secret-santa", wantErr: "directory is required"},
+		{name: "relative root", root: "secrets", directory: "team-a", wantErr: "must be an absolute path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveDirectory(tt.root, "default", tt.directory)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFileMediaGetType(t *testing.T) {
+	assert.Equal(t, "file", (&FileMedia{}).GetType())
+}
@@ -25,6 +25,15 @@ type GCPSecretManagerMedia struct {
 }
 
 func (m *GCPSecretManagerMedia) Store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	return m.store(ctx, secretSanta, data, enableMetadata, true)
+}
+
+// Rotate adds a new version to the secret, creating the secret if it does not exist
+func (m *GCPSecretManagerMedia) Rotate(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	return m.store(ctx, secretSanta, data, enableMetadata, false)
+}
+
+func (m *GCPSecretManagerMedia) store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool, createOnce bool) error {
 	if m.ProjectID == "" {
 		return fmt.Errorf("GCP project ID is required")
 	}
@@ -91,21 +100,23 @@ func (m *GCPSecretManagerMedia) Store(ctx context.Context, secretSanta *secretsa
 	_, err = client.CreateSecret(ctx, createReq)
 	if err != nil {
 		if st, ok := status.FromError(err); ok && st.Code() == codes.AlreadyExists {
-			// Secret already exists, check if it has versions (create-once policy)
-			listReq := &secretmanagerpb.ListSecretVersionsRequest{
-				Parent: secretPath,
-			}
-			versions := client.ListSecretVersions(ctx, listReq)
-			_, vErr := versions.Next()
-			if vErr == nil {
-				// Secret already has versions, skip adding new version (create-once)
-				return nil
-			}
-			if vErr != iterator.Done {
-				// Actual error occurred while listing versions
-				return fmt.Errorf("failed to list secret versions: %w", vErr)
+			if createOnce {
+				// Secret already exists, check if it has versions (create-once policy)
+				listReq := &secretmanagerpb.ListSecretVersionsRequest{
+					Parent: secretPath,
+				}
+				versions := client.ListSecretVersions(ctx, listReq)
+				_, vErr := versions.Next()
+				if vErr == nil {
+					// Secret already has versions, skip adding new version (create-once)
+					return nil
+				}
+				if vErr != iterator.Done {
+					// Actual error occurred while listing versions
+					return fmt.Errorf("failed to list secret versions: %w", vErr)
+				}
+				// Iterator exhausted (no versions), continue to add version
 			}
-			// Iterator exhausted (no versions), continue to add version
 		} else {
 			return fmt.Errorf("failed to create secret: %w", err)
 		}
@@ -0,0 +1,371 @@
+package gitsops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// defaultBranch is used for the first commit to an empty remote repository without a branch
+const defaultBranch = "main"
+
+// commitFile commits the file with go-git, so the operator image does not need git or ssh. Local
+// repositories are updated in place, remote ones are cloned into memory and pushed. A commit that
+// lost the race against another writer is retried on top of the new head.
+func (m *GitSopsMedia) commitFile(ctx context.Context, path string, content []byte, message string) error {
+	if isSSH(m.Repository) {
+		return m.commitRemote(ctx, path, content, message)
+	}
+	return m.commitLocal(path, content, message)
+}
+
+// commitLocal commits to a bare repository below the local root. The branch is moved with a
+// compare-and-swap, so concurrent writers do not overwrite each other.
+func (m *GitSopsMedia) commitLocal(path string, content []byte, message string) error {
+	dir, err := resolveLocalRepository(m.Repository, m.LocalRoot)
+	if err != nil {
+		return err
+	}
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	if _, err := repo.Worktree(); err == nil {
+		return fmt.Errorf("repository %s has a working tree, local repositories must be bare", m.Repository)
+	}
+	branch, err := localBranch(repo, m.Branch)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		head, err := repo.Reference(branch, true)
+		if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return fmt.Errorf("failed to resolve %s: %w", branch.Short(), err)
+		}
+		commit, err := m.writeCommit(repo.Storer, head, path, content, message)
+		if err != nil {
+			return err
+		}
+		if m.beforeUpdate != nil {
+			m.beforeUpdate()
+		}
+		err = repo.Storer.CheckAndSetReference(plumbing.NewHashReference(branch, commit), head)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, storage.ErrReferenceHasChanged) || attempt == pushAttempts {
+			return fmt.Errorf("failed to update %s: %w", branch.Short(), err)
+		}
+	}
+}
+
+// localBranch returns the branch to commit to, the branch HEAD points to if none is configured.
+// HEAD may point to a branch without commits in a new repository.
+func localBranch(repo *git.Repository, branch string) (plumbing.ReferenceName, error) {
+	if branch != "" {
+		return plumbing.NewBranchReferenceName(branch), nil
+	}
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	if head.Type() != plumbing.SymbolicReference || !head.Target().IsBranch() {
+		return "", fmt.Errorf("HEAD of the repository is not a branch, set branch")
+	}
+	return head.Target(), nil
+}
+
+// commitRemote clones the repository into memory, commits and pushes
+func (m *GitSopsMedia) commitRemote(ctx context.Context, path string, content []byte, message string) error {
+	auth, err := m.sshAuth()
+	if err != nil {
+		return err
+	}
+	options := &git.CloneOptions{URL: m.Repository, Auth: auth, SingleBranch: true, NoCheckout: true, Tags: git.NoTags}
+	if m.Branch != "" {
+		options.ReferenceName = plumbing.NewBranchReferenceName(m.Branch)
+	}
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), nil, options)
+	var branch plumbing.ReferenceName
+	switch {
+	case errors.Is(err, transport.ErrEmptyRemoteRepository):
+		// The first commit creates the branch
+		if repo, err = git.Init(memory.NewStorage(), nil); err != nil {
+			return err
+		}
+		if _, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{m.Repository}}); err != nil {
+			return err
+		}
+		branch = plumbing.NewBranchReferenceName(defaultBranch)
+		if m.Branch != "" {
+			branch = plumbing.NewBranchReferenceName(m.Branch)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to clone repository: %w", err)
+	default:
+		head, err := repo.Head()
+		if err != nil {
+			return fmt.Errorf("failed to resolve the cloned branch: %w", err)
+		}
+		branch = head.Name()
+	}
+	remoteBranch := plumbing.NewRemoteReferenceName(git.DefaultRemoteName, branch.Short())
+
+	for attempt := 1; ; attempt++ {
+		head, err := repo.Reference(remoteBranch, true)
+		if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return fmt.Errorf("failed to resolve %s: %w", branch.Short(), err)
+		}
+		commit, err := m.writeCommit(repo.Storer, head, path, content, message)
+		if err != nil {
+			return err
+		}
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, commit)); err != nil {
+			return err
+		}
+		refSpec := gitconfig.RefSpec(fmt.Sprintf("%s:%s", branch, branch))
+		err = repo.PushContext(ctx, &git.PushOptions{RefSpecs: []gitconfig.RefSpec{refSpec}, Auth: auth})
+		if err == nil {
+			return nil
+		}
+		if attempt == pushAttempts {
+			return fmt.Errorf("failed to push %s: %w", path, err)
+		}
+
+		// Another writer pushed first, start over from the new head, which may contain the file
+		fetchSpec := gitconfig.RefSpec(fmt.Sprintf("+%s:%s", branch, remoteBranch))
+		err = repo.FetchContext(ctx, &git.FetchOptions{RefSpecs: []gitconfig.RefSpec{fetchSpec}, Auth: auth, Tags: git.NoTags})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return fmt.Errorf("failed to fetch %s: %w", branch.Short(), err)
+		}
+	}
+}
+
+// writeCommit stores a commit adding the file on top of head, or a root commit if head is nil
+func (m *GitSopsMedia) writeCommit(s storage.Storer, head *plumbing.Reference, path string, content []byte, message string) (plumbing.Hash, error) {
+	var tree *object.Tree
+	var parents []plumbing.Hash
+	if head != nil {
+		parent, err := object.GetCommit(s, head.Hash())
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to read commit %s: %w", head.Hash(), err)
+		}
+		if tree, err = parent.Tree(); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		parents = []plumbing.Hash{parent.Hash}
+	}
+
+	blob := s.NewEncodedObject()
+	blob.SetType(plumbing.BlobObject)
+	writer, err := blob.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := writer.Write(content); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := writer.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	blobHash, err := s.SetEncodedObject(blob)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	treeHash, err := insertFile(s, tree, strings.Split(path, "/"), blobHash)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+	signature := object.Signature{Name: m.authorName(), Email: m.authorEmail(), When: time.Now()}
+	commit := &object.Commit{
+		Author:       signature,
+		Committer:    signature,
+		Message:      message,
+		TreeHash:     treeHash,
+		ParentHashes: parents,
+	}
+	return storeObject(s, commit)
+}
+
+// insertFile stores the trees of tree with the blob added at the path given by parts and returns the
+// hash of the new root tree. A file that already exists is never replaced.
+func insertFile(s storer.EncodedObjectStorer, tree *object.Tree, parts []string, blob plumbing.Hash) (plumbing.Hash, error) {
+	var entries []object.TreeEntry
+	if tree != nil {
+		entries = append(entries, tree.Entries...)
+	}
+	name := parts[0]
+	index := -1
+	for i := range entries {
+		if entries[i].Name == name {
+			index = i
+			break
+		}
+	}
+
+	entry := object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: blob}
+	if len(parts) == 1 {
+		if index >= 0 {
+			return plumbing.ZeroHash, errFileExists
+		}
+	} else {
+		var subtree *object.Tree
+		if index >= 0 {
+			if entries[index].Mode != filemode.Dir {
+				return plumbing.ZeroHash, fmt.Errorf("%s exists and is not a directory", name)
+			}
+			var err error
+			if subtree, err = object.GetTree(s, entries[index].Hash); err != nil {
+				return plumbing.ZeroHash, err
+			}
+		}
+		hash, err := insertFile(s, subtree, parts[1:], blob)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entry = object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash}
+	}
+	if index >= 0 {
+		entries[index] = entry
+	} else {
+		entries = append(entries, entry)
+	}
+
+	// Git orders tree entries by name, with directories compared as if they ended in a slash
+	sortName := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+		return e.Name
+	}
+	sort.Slice(entries, func(i, j int) bool { return sortName(entries[i]) < sortName(entries[j]) })
+	return storeObject(s, &object.Tree{Entries: entries})
+}
+
+type encodable interface {
+	Encode(plumbing.EncodedObject) error
+}
+
+func storeObject(s storer.EncodedObjectStorer, o encodable) (plumbing.Hash, error) {
+	obj := s.NewEncodedObject()
+	if err := o.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return s.SetEncodedObject(obj)
+}
+
+// resolveLocalRepository returns the directory of a local repository. It must be below the root
+// configured by the operator, also after resolving symbolic links.
+func resolveLocalRepository(repository, root string) (string, error) {
+	if root == "" {
+		return "", fmt.Errorf("local repositories are disabled, the operator must be started with --git-repository-root")
+	}
+	dir := filepath.Clean(strings.TrimPrefix(repository, "file://"))
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve git repository root: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository: %w", err)
+	}
+	rel, err := filepath.Rel(resolvedRoot, resolved)
+	if err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("repository %s is outside the git repository root %s", repository, root)
+	}
+	return resolved, nil
+}
+
+// sshAuth returns the deploy key auth. KnownHosts is required unless InsecureIgnoreHostKey is set.
+func (m *GitSopsMedia) sshAuth() (transport.AuthMethod, error) {
+	if m.SSHPrivateKey == "" {
+		return nil, fmt.Errorf("ssh_private_key is required for SSH repositories")
+	}
+	var hostKeyCallback ssh.HostKeyCallback
+	switch {
+	case m.KnownHosts != "":
+		callback, err := knownHostsCallback(m.KnownHosts)
+		if err != nil {
+			return nil, err
+		}
+		hostKeyCallback = callback
+	case m.InsecureIgnoreHostKey:
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	default:
+		return nil, fmt.Errorf("known_hosts is required for SSH repositories unless insecure_ignore_host_key is set")
+	}
+
+	endpoint, err := transport.NewEndpoint(m.Repository)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository: %w", err)
+	}
+	user := endpoint.User
+	if user == "" {
+		user = "git"
+	}
+	auth, err := gitssh.NewPublicKeys(user, []byte(strings.TrimSpace(m.SSHPrivateKey)+"\n"), "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh_private_key: %w", err)
+	}
+	auth.HostKeyCallback = hostKeyCallback
+	return auth, nil
+}
+
+// knownHostsCallback verifies host keys against known_hosts lines. The knownhosts package reads
+// files, so the lines are written to a temporary file that is removed once parsed.
+func knownHostsCallback(knownHosts string) (ssh.HostKeyCallback, error) {
+	f, err := os.CreateTemp("", "secret-santa-known-hosts-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(knownHosts); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	callback, err := knownhosts.New(f.Name())
+	if err != nil {
+		return nil, fmt.Errorf("invalid known_hosts: %w", err)
+	}
+	return callback, nil
+}
+
+func (m *GitSopsMedia) authorName() string {
+	if m.AuthorName != "" {
+		return m.AuthorName
+	}
+	return "secret-santa"
+}
+
+func (m *GitSopsMedia) authorEmail() string {
+	if m.AuthorEmail != "" {
+		return m.AuthorEmail
+	}
+	return "secret-santa@localhost"
+}
@@ -0,0 +1,237 @@
+package gitsops
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+	"github.com/logicIQ/secret-santa/pkg/media"
+)
+
+const (
+	// DefaultPath is the file path in the repository
+	DefaultPath = "{{ .Namespace }}/{{ .SecretName }}.sops.yaml"
+	// DefaultCommitMessage is the commit message template
+	DefaultCommitMessage = "Add {{ .Path }}\n\nGenerated by secret-santa for {{ .Namespace }}/{{ .Name }}."
+	// manifestEncryptedRegex only encrypts the data of Secret manifests, as Flux expects
+	manifestEncryptedRegex = "^(data|stringData)$"
+
+	operationTimeout = 2 * time.Minute
+	pushAttempts     = 3
+)
+
+var (
+	// scpLikeURLRegex matches SSH URLs in the user@host:path form
+	scpLikeURLRegex = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[^/].*$`)
+	branchRegex     = regexp.MustCompile(`^[A-Za-z0-9._/-]+$`)
+	errFileExists   = errors.New("file already exists")
+)
+
+// GitSopsMedia commits secrets encrypted with SOPS to a Git repository
+type GitSopsMedia struct {
+	// Repository is the path of a bare repository, a file:// URL or an SSH URL
+	Repository string
+	// LocalRoot is the directory local repositories must be in. It is set by the operator, local
+	// repositories are rejected if it is empty.
+	LocalRoot string
+	// Branch is the branch to commit to, the default branch of the repository if empty
+	Branch string
+	// Path and CommitMessage are templates with .Namespace, .Name and .SecretName. CommitMessage also
+	// has .Path.
+	Path          string
+	CommitMessage string
+	AuthorName    string
+	AuthorEmail   string
+
+	// SSHPrivateKey is the deploy key for SSH URLs. KnownHosts is required unless
+	// InsecureIgnoreHostKey is set.
+	SSHPrivateKey         string
+	KnownHosts            string
+	InsecureIgnoreHostKey bool
+
+	// AgeRecipients and PGPPublicKeys (armored) can decrypt the file
+	AgeRecipients []string
+	PGPPublicKeys string
+
+	// SplitKeys stores each top-level key of a YAML or JSON template output as a separate key
+	SplitKeys bool
+	// Manifest writes a Kubernetes Secret manifest with only its data encrypted
+	Manifest bool
+
+	// beforeUpdate is called before a local branch is moved, tests use it to simulate another writer
+	beforeUpdate func()
+}
+
+// Store commits the encrypted secret. Existing files are never overwritten, so the media does not
+// support rotation.
+func (m *GitSopsMedia) Store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	if err := validateRepository(m.Repository); err != nil {
+		return err
+	}
+	if m.Branch != "" && (!branchRegex.MatchString(m.Branch) || strings.HasPrefix(m.Branch, "-") || strings.Contains(m.Branch, "..")) {
+		return fmt.Errorf("invalid branch %q", m.Branch)
+	}
+	encrypter, err := newSopsEncrypter(m.AgeRecipients, m.PGPPublicKeys)
+	if err != nil {
+		return err
+	}
+	fields := templateFields{Namespace: secretSanta.Namespace, Name: secretSanta.Name, SecretName: secretSanta.Spec.SecretName}
+	if fields.SecretName == "" {
+		fields.SecretName = secretSanta.Name
+	}
+	pathTemplate := m.Path
+	if pathTemplate == "" {
+		pathTemplate = DefaultPath
+	}
+	if fields.Path, err = render("path", pathTemplate, fields); err != nil {
+		return err
+	}
+	if !filepath.IsLocal(fields.Path) || strings.HasPrefix(fields.Path, ".git/") || fields.Path == ".git" {
+		return fmt.Errorf("path must be a file inside the repository, got: %q", fields.Path)
+	}
+	messageTemplate := m.CommitMessage
+	if messageTemplate == "" {
+		messageTemplate = DefaultCommitMessage
+	}
+	message, err := render("commit message", messageTemplate, fields)
+	if err != nil {
+		return err
+	}
+
+	tree, err := m.buildTree(secretSanta, data, enableMetadata, fields.SecretName)
+	if err != nil {
+		return err
+	}
+	if m.Manifest {
+		encrypter.encryptedRegex = regexp.MustCompile(manifestEncryptedRegex)
+	}
+	content, err := encrypter.encrypt(tree)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+	err = m.commitFile(ctx, fields.Path, content, message)
+	if errors.Is(err, errFileExists) {
+		return nil // File already exists, which is fine for create-once policy
+	}
+	return err
+}
+
+func (m *GitSopsMedia) GetType() string {
+	return "git-sops"
+}
+
+type templateFields struct {
+	Namespace  string
+	Name       string
+	SecretName string
+	Path       string
+}
+
+func render(name, text string, fields templateFields) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s template: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, fields); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// validateRepository only allows local repositories and SSH
+func validateRepository(repository string) error {
+	switch {
+	case repository == "":
+		return fmt.Errorf("repository is required")
+	case filepath.IsAbs(repository),
+		strings.HasPrefix(repository, "file:///"),
+		strings.HasPrefix(repository, "ssh://"),
+		scpLikeURLRegex.MatchString(repository):
+		return nil
+	default:
+		return fmt.Errorf("repository must be an absolute path, a file:// URL or an SSH URL, got: %q", repository)
+	}
+}
+
+func isSSH(repository string) bool {
+	return strings.HasPrefix(repository, "ssh://") || scpLikeURLRegex.MatchString(repository)
+}
+
+// buildTree lays out the template output as the document to encrypt
+func (m *GitSopsMedia) buildTree(secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool, secretName string) (map[string]interface{}, error) {
+	values := map[string]interface{}{"data": data}
+	if m.SplitKeys {
+		keys, err := media.ParseKeys(data)
+		if err != nil {
+			return nil, err
+		}
+		values = make(map[string]interface{}, len(keys))
+		for k, v := range keys {
+			values[k] = v
+		}
+	}
+	if !m.Manifest {
+		return values, nil
+	}
+
+	metadata := map[string]interface{}{"name": secretName, "namespace": secretSanta.Namespace}
+	if labels := toTree(secretSanta.Spec.Labels); len(labels) > 0 {
+		metadata["labels"] = labels
+	}
+	annotations := toTree(secretSanta.Spec.Annotations)
+	if enableMetadata {
+		annotations["secrets.secret-santa.io/created-at"] = time.Now().UTC().Format(time.RFC3339)
+		annotations["secrets.secret-santa.io/generator-types"] = getGeneratorTypes(secretSanta.Spec.Generators)
+		annotations["secrets.secret-santa.io/template-checksum"] = calculateTemplateChecksum(secretSanta.Spec.Template)
+		annotations["secrets.secret-santa.io/source-cr"] = fmt.Sprintf("%s/%s", secretSanta.Namespace, secretSanta.Name)
+	}
+	if len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+	secretType := secretSanta.Spec.SecretType
+	if secretType == "" {
+		secretType = "Opaque"
+	}
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   metadata,
+		"type":       secretType,
+		"stringData": values,
+	}, nil
+}
+
+func toTree(values map[string]string) map[string]interface{} {
+	tree := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		tree[k] = v
+	}
+	return tree
+}
+
+// getGeneratorTypes extracts generator types from the configuration
+func getGeneratorTypes(generators []secretsantav1alpha1.GeneratorConfig) string {
+	types := make([]string, len(generators))
+	for i, gen := range generators {
+		types[i] = gen.Type
+	}
+	return strings.Join(types, ",")
+}
+
+// calculateTemplateChecksum creates a SHA256 checksum of the template
+func calculateTemplateChecksum(template string) string {
+	hash := sha256.Sum256([]byte(template))
+	return fmt.Sprintf("%x", hash)[:16]
+}
@@ -0,0 +1,344 @@
+package gitsops
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+)
+
+// newBareRepo creates a bare repository with an initial commit on main below a new local root
+func newBareRepo(t *testing.T) (root, bare string) {
+	t.Helper()
+	root = t.TempDir()
+	bare = filepath.Join(root, "config.git")
+	repo, err := git.PlainInitWithOptions(bare, &git.PlainInitOptions{
+		Bare:        true,
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	require.NoError(t, err)
+	seed := &GitSopsMedia{AuthorName: "test", AuthorEmail: "test@example.com"}
+	commit, err := seed.writeCommit(repo.Storer, nil, "README.md", []byte("config\n"), "Initial commit")
+	require.NoError(t, err)
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("main"), commit)))
+	return root, bare
+}
+
+// headCommit returns the commit a branch of the repository points to
+func headCommit(t *testing.T, bare, branch string) *object.Commit {
+	t.Helper()
+	repo, err := git.PlainOpen(bare)
+	require.NoError(t, err)
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(ref.Hash())
+	require.NoError(t, err)
+	return commit
+}
+
+func fileContents(t *testing.T, commit *object.Commit, path string) string {
+	t.Helper()
+	file, err := commit.File(path)
+	require.NoError(t, err)
+	contents, err := file.Contents()
+	require.NoError(t, err)
+	return contents
+}
+
+func commitCount(t *testing.T, commit *object.Commit) int {
+	t.Helper()
+	count := 0
+	require.NoError(t, object.NewCommitPreorderIter(commit, nil, nil).ForEach(func(*object.Commit) error {
+		count++
+		return nil
+	}))
+	return count
+}
+
+func testSecretSanta() *secretsantav1alpha1.SecretSanta {
+	return &secretsantav1alpha1.SecretSanta{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "default"},
+		Spec: secretsantav1alpha1.SecretSantaSpec{
+			Template:   "{{ .pass.value }}",
+			Labels:     map[string]string{"app": "web"},
+			Generators: []secretsantav1alpha1.GeneratorConfig{{Name: "pass", Type: "random_password"}},
+		},
+	}
+}
+
+func TestGitSopsMediaStore(t *testing.T) {
+	root, bare := newBareRepo(t)
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	media := &GitSopsMedia{
+		Repository:    bare,
+		LocalRoot:     root,
+		CommitMessage: "Add {{ .Namespace }}/{{ .Name }} at {{ .Path }}",
+		AuthorName:    "Bot",
+		AuthorEmail:   "bot@example.com",
+		AgeRecipients: []string{identity.Recipient().String()},
+	}
+
+	require.NoError(t, media.Store(context.Background(), testSecretSanta(), "password123", true))
+
+	commit := headCommit(t, bare, "main")
+	assert.Equal(t, "Add default/test-secret at default/test-secret.sops.yaml\n", commit.Message)
+	assert.Equal(t, "Bot", commit.Author.Name)
+	assert.Equal(t, "bot@example.com", commit.Author.Email)
+	assert.Equal(t, "config\n", fileContents(t, commit, "README.md"))
+	document := fileContents(t, commit, "default/test-secret.sops.yaml")
+	assert.NotContains(t, document, "password123")
+	tree := decryptSops(t, []byte(document), ageDataKey(t, []byte(document), identity))
+	assert.Equal(t, "password123", tree["data"])
+
+	// Existing files are not overwritten
+	require.NoError(t, media.Store(context.Background(), testSecretSanta(), "other", true))
+	commit = headCommit(t, bare, "main")
+	assert.Equal(t, 2, commitCount(t, commit))
+	assert.Equal(t, document, fileContents(t, commit, "default/test-secret.sops.yaml"))
+}
+
+func TestGitSopsMediaManifest(t *testing.T) {
+	root, bare := newBareRepo(t)
+	repo, err := git.PlainOpen(bare)
+	require.NoError(t, err)
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("secrets"), headCommit(t, bare, "main").Hash)))
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	media := &GitSopsMedia{
+		Repository:    "file://" + bare,
+		LocalRoot:     root,
+		Branch:        "secrets",
+		Path:          "apps/{{ .SecretName }}/secret.yaml",
+		AgeRecipients: []string{identity.Recipient().String()},
+		SplitKeys:     true,
+		Manifest:      true,
+	}
+	ss := testSecretSanta()
+	ss.Spec.SecretName = "db"
+
+	require.NoError(t, media.Store(context.Background(), ss, "user: admin\npassword: s3cret\n", true))
+
+	assert.Equal(t, 1, commitCount(t, headCommit(t, bare, "main")))
+	commit := headCommit(t, bare, "secrets")
+	assert.Contains(t, commit.Message, "Add apps/db/secret.yaml")
+	document := fileContents(t, commit, "apps/db/secret.yaml")
+	assert.NotContains(t, document, "s3cret")
+	tree := decryptSops(t, []byte(document), ageDataKey(t, []byte(document), identity))
+
+	var secret struct {
+		Kind     string
+		Type     string
+		Metadata struct {
+			Name        string
+			Namespace   string
+			Labels      map[string]string
+			Annotations map[string]string
+		}
+		StringData map[string]string `json:"stringData"`
+	}
+	encoded, err := yaml.Marshal(tree)
+	require.NoError(t, err)
+	require.NoError(t, yaml.Unmarshal(encoded, &secret))
+	assert.Equal(t, "Secret", secret.Kind)
+	assert.Equal(t, "Opaque", secret.Type)
+	assert.Equal(t, "db", secret.Metadata.Name)
+	assert.Equal(t, "default", secret.Metadata.Namespace)
+	assert.Equal(t, "web", secret.Metadata.Labels["app"])
+	assert.Equal(t, "default/test-secret", secret.Metadata.Annotations["secrets.secret-santa.io/source-cr"])
+	assert.Equal(t, map[string]string{"user": "admin", "password": "s3cret"}, secret.StringData)
+}
+
+func TestGitSopsMediaUpdateRetry(t *testing.T) {
+	root, bare := newBareRepo(t)
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	repo, err := git.PlainOpen(bare)
+	require.NoError(t, err)
+
+	// Another writer moves main before the first update
+	media := &GitSopsMedia{Repository: bare, LocalRoot: root, AgeRecipients: []string{identity.Recipient().String()}}
+	media.beforeUpdate = func() {
+		media.beforeUpdate = nil
+		main := plumbing.NewBranchReferenceName("main")
+		head, err := repo.Reference(main, true)
+		require.NoError(t, err)
+		commit, err := media.writeCommit(repo.Storer, head, "other.yaml", []byte("other\n"), "Add other")
+		require.NoError(t, err)
+		require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(main, commit)))
+	}
+	require.NoError(t, media.Store(context.Background(), testSecretSanta(), "password123", false))
+
+	commit := headCommit(t, bare, "main")
+	assert.Equal(t, 3, commitCount(t, commit))
+	assert.Equal(t, "other\n", fileContents(t, commit, "other.yaml"))
+	assert.NotEmpty(t, fileContents(t, commit, "default/test-secret.sops.yaml"))
+}
+
+func TestGitSopsMediaSSH(t *testing.T) {
+	_, bare := newBareRepo(t)
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	// Serve repositories in-process for the ssh scheme
+	client.InstallProtocol("ssh", server.NewClient(server.NewFilesystemLoader(osfs.New(filepath.Dir(bare)))))
+	t.Cleanup(func() { client.InstallProtocol("ssh", gitssh.DefaultClient) })
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKey(key, "")
+	require.NoError(t, err)
+	media := &GitSopsMedia{
+		Repository:            "ssh://git@example.com/" + filepath.Base(bare),
+		SSHPrivateKey:         string(pem.EncodeToMemory(block)),
+		InsecureIgnoreHostKey: true,
+		AgeRecipients:         []string{identity.Recipient().String()},
+	}
+	require.NoError(t, media.Store(context.Background(), testSecretSanta(), "password123", false))
+
+	commit := headCommit(t, bare, "main")
+	assert.Equal(t, 2, commitCount(t, commit))
+	assert.NotEmpty(t, fileContents(t, commit, "default/test-secret.sops.yaml"))
+}
+
+func TestGitSopsMediaErrors(t *testing.T) {
+	recipient := "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"
+	tests := []struct {
+		name    string
+		media   GitSopsMedia
+		wantErr string
+	}{
+		{
+			name:    "missing repository",
+			media:   GitSopsMedia{AgeRecipients: []string{recipient}},
+			wantErr: "repository is required",
+		},
+		{
+			name:    "https repository",
+			media:   GitSopsMedia{Repository: "https://github.com/example/config.git", AgeRecipients: []string{recipient}},
+			wantErr: "must be an absolute path, a file:// URL or an SSH URL",
+		},
+		{
+			name:    "remote helper",
+			media:   GitSopsMedia{Repository: "ext::sh -c touch% /tmp/pwned", AgeRecipients: []string{recipient}},
+			wantErr: "must be an absolute path, a file:// URL or an SSH URL",
+		},
+		{
+			name:    "invalid branch",
+			media:   GitSopsMedia{Repository: "/tmp/repo", Branch: "--upload-pack=x", AgeRecipients: []string{recipient}},
+			wantErr: "invalid branch",
+		},
+		{
+			name:    "missing recipients",
+			media:   GitSopsMedia{Repository: "/tmp/repo"},
+			wantErr: "at least one age recipient or pgp public key is required",
+		},
+		{
+			name:    "path traversal",
+			media:   GitSopsMedia{Repository: "/tmp/repo", Path: "../{{ .Name }}.yaml", AgeRecipients: []string{recipient}},
+			wantErr: "path must be a file inside the repository",
+		},
+		{
+			name:    "git directory",
+			media:   GitSopsMedia{Repository: "/tmp/repo", Path: ".git/hooks/post-checkout", AgeRecipients: []string{recipient}},
+			wantErr: "path must be a file inside the repository",
+		},
+		{
+			name:    "ssh without deploy key",
+			media:   GitSopsMedia{Repository: "git@github.com:example/config.git", AgeRecipients: []string{recipient}},
+			wantErr: "ssh_private_key is required",
+		},
+		{
+			name:    "ssh without known hosts",
+			media:   GitSopsMedia{Repository: "ssh://git@github.com/example/config.git", SSHPrivateKey: "key", AgeRecipients: []string{recipient}},
+			wantErr: "known_hosts is required",
+		},
+		{
+			name:    "ssh with invalid known hosts",
+			media:   GitSopsMedia{Repository: "ssh://git@github.com/example/config.git", SSHPrivateKey: "key", KnownHosts: "github.com", AgeRecipients: []string{recipient}},
+			wantErr: "invalid known_hosts",
+		},
+		{
+			name:    "ssh with invalid deploy key",
+			media:   GitSopsMedia{Repository: "ssh://git@github.com/example/config.git", SSHPrivateKey: "key", InsecureIgnoreHostKey: true, AgeRecipients: []string{recipient}},
+			wantErr: "invalid ssh_private_key",
+		},
+		{
+			name:    "local repositories disabled",
+			media:   GitSopsMedia{Repository: "/tmp/repo", AgeRecipients: []string{recipient}},
+			wantErr: "local repositories are disabled",
+		},
+		{
+			name:    "missing repository directory",
+			media:   GitSopsMedia{Repository: "/nonexistent/config.git", LocalRoot: "/", AgeRecipients: []string{recipient}},
+			wantErr: "failed to open repository",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.media.Store(context.Background(), testSecretSanta(), "password123", false)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestGitSopsMediaGetType(t *testing.T) {
+	assert.Equal(t, "git-sops", (&GitSopsMedia{}).GetType())
+}
+
+func TestResolveLocalRepository(t *testing.T) {
+	root, bare := newBareRepo(t)
+	outside := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "link")))
+
+	dir, err := resolveLocalRepository("file://"+bare, root)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Base(bare), filepath.Base(dir))
+
+	for _, repository := range []string{outside, root, filepath.Join(root, "link"), filepath.Join(root, "..", filepath.Base(outside))} {
+		_, err := resolveLocalRepository(repository, root)
+		require.Error(t, err, repository)
+		assert.Contains(t, err.Error(), "outside the git repository root", repository)
+	}
+
+	// Working trees are rejected, the checked out branch must not move under them
+	work := filepath.Join(root, "work")
+	_, err = git.PlainInit(work, false)
+	require.NoError(t, err)
+	media := &GitSopsMedia{Repository: work, LocalRoot: root}
+	err = media.commitFile(context.Background(), "secret.yaml", []byte("data"), "Add secret")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "local repositories must be bare")
+}
+
+func TestValidateRepository(t *testing.T) {
+	for _, valid := range []string{"/srv/git/config.git", "file:///srv/git/config.git", "ssh://git@example.com:2222/config.git", "git@github.com:org/config.git"} {
+		assert.NoError(t, validateRepository(valid), valid)
+	}
+	for _, invalid := range []string{"config.git", "-uconfig", "http://example.com/config.git", "git@github.com:/config.git"} {
+		assert.Error(t, validateRepository(invalid), invalid)
+	}
+	assert.True(t, isSSH("git@github.com:org/config.git"))
+	assert.False(t, isSSH("/srv/git/config.git"))
+}
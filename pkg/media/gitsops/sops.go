@@ -0,0 +1,258 @@
+package gitsops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgparmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// sopsVersion is the SOPS release whose file format is written
+	sopsVersion = "3.9.0"
+	// sopsNonceSize is the AES-GCM nonce size SOPS uses for values
+	sopsNonceSize = 32
+	// unencryptedSuffix leaves values of keys with this suffix in plaintext, the SOPS default
+	unencryptedSuffix = "_unencrypted"
+)
+
+// sopsEncrypter encrypts YAML documents in the SOPS format for age and OpenPGP recipients, so they
+// can be decrypted with sops, Flux or the SOPS Argo CD plugins
+type sopsEncrypter struct {
+	ageRecipients []*age.X25519Recipient
+	pgpKeys       openpgp.EntityList
+	// encryptedRegex limits encryption to values below matching keys, all values are encrypted if nil
+	encryptedRegex *regexp.Regexp
+	random         io.Reader
+}
+
+type sopsMetadata struct {
+	Age               []sopsAgeKey `json:"age,omitempty"`
+	PGP               []sopsPGPKey `json:"pgp,omitempty"`
+	LastModified      string       `json:"lastmodified"`
+	MAC               string       `json:"mac"`
+	UnencryptedSuffix string       `json:"unencrypted_suffix,omitempty"`
+	EncryptedRegex    string       `json:"encrypted_regex,omitempty"`
+	Version           string       `json:"version"`
+}
+
+type sopsAgeKey struct {
+	Recipient string `json:"recipient"`
+	Enc       string `json:"enc"`
+}
+
+type sopsPGPKey struct {
+	CreatedAt string `json:"created_at"`
+	Enc       string `json:"enc"`
+	FP        string `json:"fp"`
+}
+
+// newSopsEncrypter parses age recipients and armored OpenPGP public keys
+func newSopsEncrypter(ageRecipients []string, pgpPublicKeys string) (*sopsEncrypter, error) {
+	e := &sopsEncrypter{random: rand.Reader}
+	for _, recipient := range ageRecipients {
+		parsed, err := age.ParseX25519Recipient(strings.TrimSpace(recipient))
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient %q: %w", recipient, err)
+		}
+		e.ageRecipients = append(e.ageRecipients, parsed)
+	}
+	if strings.TrimSpace(pgpPublicKeys) != "" {
+		keys, err := openpgp.ReadArmoredKeyRing(strings.NewReader(pgpPublicKeys))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pgp public keys: %w", err)
+		}
+		e.pgpKeys = keys
+	}
+	if len(e.ageRecipients) == 0 && len(e.pgpKeys) == 0 {
+		return nil, fmt.Errorf("at least one age recipient or pgp public key is required")
+	}
+	return e, nil
+}
+
+// encrypt returns the SOPS encrypted YAML of tree. Values must be strings or nested maps of strings.
+func (e *sopsEncrypter) encrypt(tree map[string]interface{}) ([]byte, error) {
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(e.random, dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	// The MAC covers all values in document order. Keys are walked sorted, matching the YAML encoder.
+	hash := sha512.New()
+	encrypted, err := e.encryptBranch(tree, nil, dataKey, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	lastModified := time.Now().UTC().Format(time.RFC3339)
+	metadata := sopsMetadata{LastModified: lastModified, Version: sopsVersion}
+	if metadata.MAC, err = e.encryptValue(fmt.Sprintf("%X", hash.Sum(nil)), dataKey, lastModified); err != nil {
+		return nil, err
+	}
+	if e.encryptedRegex != nil {
+		metadata.EncryptedRegex = e.encryptedRegex.String()
+	} else {
+		metadata.UnencryptedSuffix = unencryptedSuffix
+	}
+	for _, recipient := range e.ageRecipients {
+		enc, err := encryptAge(recipient, dataKey)
+		if err != nil {
+			return nil, err
+		}
+		metadata.Age = append(metadata.Age, sopsAgeKey{Recipient: recipient.String(), Enc: enc})
+	}
+	for _, entity := range e.pgpKeys {
+		enc, err := encryptPGP(entity, dataKey)
+		if err != nil {
+			return nil, err
+		}
+		metadata.PGP = append(metadata.PGP, sopsPGPKey{
+			CreatedAt: lastModified,
+			Enc:       enc,
+			FP:        strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint)),
+		})
+	}
+
+	// The sops key is written last, as sops does
+	document, err := yaml.Marshal(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	sops, err := yaml.Marshal(map[string]interface{}{"sops": metadata})
+	if err != nil {
+		return nil, err
+	}
+	return append(document, sops...), nil
+}
+
+func (e *sopsEncrypter) encryptBranch(branch map[string]interface{}, path []string, dataKey []byte, hash io.Writer) (map[string]interface{}, error) {
+	keys := make([]string, 0, len(branch))
+	for key := range branch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make(map[string]interface{}, len(branch))
+	for _, key := range keys {
+		keyPath := append(append([]string{}, path...), key)
+		switch value := branch[key].(type) {
+		case map[string]interface{}:
+			encrypted, err := e.encryptBranch(value, keyPath, dataKey, hash)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = encrypted
+		case string:
+			_, _ = io.WriteString(hash, value)
+			if !e.shouldEncrypt(keyPath) {
+				out[key] = value
+				continue
+			}
+			// Additional data binds each value to its location in the document
+			encrypted, err := e.encryptValue(value, dataKey, strings.Join(keyPath, ":")+":")
+			if err != nil {
+				return nil, err
+			}
+			out[key] = encrypted
+		default:
+			return nil, fmt.Errorf("unsupported value type %T for key %s", value, strings.Join(keyPath, "."))
+		}
+	}
+	return out, nil
+}
+
+func (e *sopsEncrypter) shouldEncrypt(path []string) bool {
+	for _, key := range path {
+		if e.encryptedRegex != nil && e.encryptedRegex.MatchString(key) {
+			return true
+		}
+		if e.encryptedRegex == nil && strings.HasSuffix(key, unencryptedSuffix) {
+			return false
+		}
+	}
+	return e.encryptedRegex == nil
+}
+
+// encryptValue encrypts a string value with AES-256-GCM in the SOPS ENC[...] format
+func (e *sopsEncrypter) encryptValue(value string, dataKey []byte, additionalData string) (string, error) {
+	// Empty values are stored as is, like sops does
+	if value == "" {
+		return "", nil
+	}
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, sopsNonceSize)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, sopsNonceSize)
+	if _, err := io.ReadFull(e.random, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nil, nonce, []byte(value), []byte(additionalData))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]",
+		base64.StdEncoding.EncodeToString(ciphertext),
+		base64.StdEncoding.EncodeToString(nonce),
+		base64.StdEncoding.EncodeToString(tag)), nil
+}
+
+// encryptAge encrypts the data key to an age recipient as an armored age file
+func encryptAge(recipient *age.X25519Recipient, dataKey []byte) (string, error) {
+	var buf bytes.Buffer
+	armorWriter := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armorWriter, recipient)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt data key for %s: %w", recipient, err)
+	}
+	if _, err := w.Write(dataKey); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	if err := armorWriter.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// encryptPGP encrypts the data key to an OpenPGP public key as an armored message
+func encryptPGP(entity *openpgp.Entity, dataKey []byte) (string, error) {
+	var buf bytes.Buffer
+	armorWriter, err := pgparmor.Encode(&buf, "PGP MESSAGE", nil)
+	if err != nil {
+		return "", err
+	}
+	w, err := openpgp.Encrypt(armorWriter, []*openpgp.Entity{entity}, nil, nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt data key for %X: %w", entity.PrimaryKey.Fingerprint, err)
+	}
+	if _, err := w.Write(dataKey); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	if err := armorWriter.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
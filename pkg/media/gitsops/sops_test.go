@@ -0,0 +1,193 @@
+package gitsops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgparmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+var encRegex = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.+),iv:(.+),tag:(.+),type:str\]$`)
+
+// decryptSops decrypts a SOPS document the way sops does, verifying the MAC
+func decryptSops(t *testing.T, document []byte, dataKey []byte) map[string]interface{} {
+	t.Helper()
+	var tree map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(document, &tree))
+	metadata := tree["sops"].(map[string]interface{})
+	delete(tree, "sops")
+
+	hash := sha512.New()
+	var walk func(branch map[string]interface{}, path []string)
+	walk = func(branch map[string]interface{}, path []string) {
+		keys := make([]string, 0, len(branch))
+		for k := range branch {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			keyPath := append(append([]string{}, path...), k)
+			switch v := branch[k].(type) {
+			case map[string]interface{}:
+				walk(v, keyPath)
+			case string:
+				if encRegex.MatchString(v) {
+					v = decryptValue(t, v, dataKey, strings.Join(keyPath, ":")+":")
+					branch[k] = v
+				}
+				hash.Write([]byte(v))
+			}
+		}
+	}
+	walk(tree, nil)
+
+	mac := decryptValue(t, metadata["mac"].(string), dataKey, metadata["lastmodified"].(string))
+	assert.Equal(t, fmt.Sprintf("%X", hash.Sum(nil)), mac, "MAC mismatch")
+	return tree
+}
+
+func decryptValue(t *testing.T, value string, dataKey []byte, additionalData string) string {
+	t.Helper()
+	match := encRegex.FindStringSubmatch(value)
+	require.NotNil(t, match, value)
+	ciphertext, err := base64.StdEncoding.DecodeString(match[1])
+	require.NoError(t, err)
+	nonce, err := base64.StdEncoding.DecodeString(match[2])
+	require.NoError(t, err)
+	tag, err := base64.StdEncoding.DecodeString(match[3])
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(dataKey)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(nonce))
+	require.NoError(t, err)
+	plaintext, err := gcm.Open(nil, nonce, append(ciphertext, tag...), []byte(additionalData))
+	require.NoError(t, err)
+	return string(plaintext)
+}
+
+// ageDataKey decrypts the data key of the first age recipient
+func ageDataKey(t *testing.T, document []byte, identity *age.X25519Identity) []byte {
+	t.Helper()
+	var parsed struct {
+		Sops sopsMetadata `json:"sops"`
+	}
+	require.NoError(t, yaml.Unmarshal(document, &parsed))
+	require.NotEmpty(t, parsed.Sops.Age)
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(parsed.Sops.Age[0].Enc)), identity)
+	require.NoError(t, err)
+	dataKey, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return dataKey
+}
+
+func TestSopsEncryptAge(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	encrypter, err := newSopsEncrypter([]string{identity.Recipient().String()}, "")
+	require.NoError(t, err)
+
+	document, err := encrypter.encrypt(map[string]interface{}{
+		"password":           "s3cret",
+		"empty":              "",
+		"note_unencrypted":   "visible",
+		"nested":             map[string]interface{}{"user": "admin"},
+		"nested_unencrypted": map[string]interface{}{"host": "db"},
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, string(document), "s3cret")
+	assert.NotContains(t, string(document), "admin")
+	assert.Contains(t, string(document), "note_unencrypted: visible")
+	assert.Contains(t, string(document), "unencrypted_suffix: _unencrypted")
+	assert.Contains(t, string(document), "version: "+sopsVersion)
+	assert.Contains(t, string(document), "recipient: "+identity.Recipient().String())
+	// The sops metadata is the last key of the document
+	assert.True(t, strings.Index(string(document), "sops:") > strings.Index(string(document), "password:"))
+
+	tree := decryptSops(t, document, ageDataKey(t, document, identity))
+	assert.Equal(t, "s3cret", tree["password"])
+	assert.Equal(t, "", tree["empty"])
+	assert.Equal(t, "visible", tree["note_unencrypted"])
+	assert.Equal(t, map[string]interface{}{"user": "admin"}, tree["nested"])
+	assert.Equal(t, map[string]interface{}{"host": "db"}, tree["nested_unencrypted"])
+}
+
+func TestSopsEncryptedRegex(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	encrypter, err := newSopsEncrypter([]string{identity.Recipient().String()}, "")
+	require.NoError(t, err)
+	encrypter.encryptedRegex = regexp.MustCompile(manifestEncryptedRegex)
+
+	document, err := encrypter.encrypt(map[string]interface{}{
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "app"},
+		"stringData": map[string]interface{}{"password": "s3cret"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, string(document), "kind: Secret")
+	assert.Contains(t, string(document), "name: app")
+	assert.NotContains(t, string(document), "s3cret")
+	assert.Contains(t, string(document), "encrypted_regex: ^(data|stringData)$")
+
+	tree := decryptSops(t, document, ageDataKey(t, document, identity))
+	assert.Equal(t, map[string]interface{}{"password": "s3cret"}, tree["stringData"])
+}
+
+func TestSopsEncryptPGP(t *testing.T) {
+	entity, err := openpgp.NewEntity("Flux", "", "flux@example.com", nil)
+	require.NoError(t, err)
+	var publicKey bytes.Buffer
+	w, err := pgparmor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+
+	encrypter, err := newSopsEncrypter(nil, publicKey.String())
+	require.NoError(t, err)
+	document, err := encrypter.encrypt(map[string]interface{}{"password": "s3cret"})
+	require.NoError(t, err)
+
+	var parsed struct {
+		Sops sopsMetadata `json:"sops"`
+	}
+	require.NoError(t, yaml.Unmarshal(document, &parsed))
+	require.Len(t, parsed.Sops.PGP, 1)
+	assert.Equal(t, fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint), parsed.Sops.PGP[0].FP)
+
+	block, err := pgparmor.Decode(strings.NewReader(parsed.Sops.PGP[0].Enc))
+	require.NoError(t, err)
+	message, err := openpgp.ReadMessage(block.Body, openpgp.EntityList{entity}, nil, nil)
+	require.NoError(t, err)
+	dataKey, err := io.ReadAll(message.UnverifiedBody)
+	require.NoError(t, err)
+
+	tree := decryptSops(t, document, dataKey)
+	assert.Equal(t, "s3cret", tree["password"])
+}
+
+func TestNewSopsEncrypterErrors(t *testing.T) {
+	_, err := newSopsEncrypter(nil, "")
+	assert.ErrorContains(t, err, "at least one age recipient or pgp public key is required")
+
+	_, err = newSopsEncrypter([]string{"age1invalid"}, "")
+	assert.ErrorContains(t, err, "invalid age recipient")
+
+	_, err = newSopsEncrypter(nil, "not a key")
+	assert.ErrorContains(t, err, "invalid pgp public keys")
+}
@@ -11,6 +11,12 @@ type Media interface {
 	GetType() string
 }
 
+// Rotator is implemented by media that can replace previously stored data. The controller uses it to
+// regenerate secrets when a time_rotating generator window elapses.
+type Rotator interface {
+	Rotate(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error
+}
+
 // MediaConfig defines configuration for media destinations
 type MediaConfig struct {
 	Type   string                 `json:"type"`
@@ -3,34 +3,122 @@ package k8s
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+	"github.com/logicIQ/secret-santa/pkg/media"
 )
 
 // K8sSecretsMedia stores secrets as Kubernetes secrets
 type K8sSecretsMedia struct {
-	Client     client.Client
+	Client client.Client
+	// Reader reads namespaces and replicas from the API server, as they may be outside the
+	// namespaces the manager caches. Client is used if it is nil.
+	Reader     client.Reader
 	SecretName string
+	// Namespace overrides the namespace of the SecretSanta, e.g. for a secret on a remote cluster
+	Namespace string
+	// SplitKeys stores each top-level key of a YAML or JSON template output as a separate secret key
+	SplitKeys bool
+	// BinaryKeys lists secret keys whose base64 values are decoded and stored as raw bytes
+	BinaryKeys []string
+	// Namespaces lists namespaces that receive a replica of the secret
+	Namespaces []string
+	// NamespaceSelector selects namespaces that receive a replica of the secret
+	NamespaceSelector labels.Selector
 }
 
 func (m *K8sSecretsMedia) Store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
-	secretName := m.SecretName
-	if secretName == "" {
-		if secretSanta.Spec.SecretName != "" {
-			secretName = secretSanta.Spec.SecretName
-		} else {
-			secretName = secretSanta.Name
+	secret, err := m.buildSecret(secretSanta, data, enableMetadata)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Client.Create(ctx, secret); err != nil {
+		// Check if secret already exists
+		if client.IgnoreAlreadyExists(err) != nil {
+			return fmt.Errorf("failed to create secret %s/%s: %w", secret.Namespace, secret.Name, err)
 		}
+		// Secret already exists, which is fine for create-once policy if it is ours
+		var existing corev1.Secret
+		if err := m.Client.Get(ctx, client.ObjectKeyFromObject(secret), &existing); err != nil {
+			return fmt.Errorf("failed to get secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+		if !media.IsOwnedBy(existing.Annotations, secretSanta) {
+			return media.NotOwnedError("secret", secret.Namespace, secret.Name, secretSanta)
+		}
+	}
+	if m.replicating() {
+		return m.replicate(ctx, secretSanta, client.ObjectKeyFromObject(secret))
+	}
+	return nil
+}
+
+// Rotate replaces the data of an existing secret, creating it if it does not exist. Secrets that
+// were not created for the SecretSanta are never replaced.
+func (m *K8sSecretsMedia) Rotate(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	secret, err := m.buildSecret(secretSanta, data, enableMetadata)
+	if err != nil {
+		return err
 	}
 
+	var existing corev1.Secret
+	if err := m.Client.Get(ctx, client.ObjectKeyFromObject(secret), &existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+		if err := m.Client.Create(ctx, secret); err != nil {
+			return fmt.Errorf("failed to create secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+	} else {
+		if !media.IsOwnedBy(existing.Annotations, secretSanta) {
+			return media.NotOwnedError("secret", secret.Namespace, secret.Name, secretSanta)
+		}
+		// The secret type is immutable, so only labels, annotations and data are replaced
+		existing.Labels = secret.Labels
+		existing.Annotations = secret.Annotations
+		existing.Data = secret.Data
+		existing.StringData = secret.StringData
+		if err := m.Client.Update(ctx, &existing); err != nil {
+			return fmt.Errorf("failed to update secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+	}
+	if m.replicating() {
+		return m.replicate(ctx, secretSanta, client.ObjectKeyFromObject(secret))
+	}
+	return nil
+}
+
+// secretKey returns the name and namespace of the secret of a SecretSanta
+func (m *K8sSecretsMedia) secretKey(secretSanta *secretsantav1alpha1.SecretSanta) client.ObjectKey {
+	key := client.ObjectKey{Name: m.SecretName, Namespace: m.Namespace}
+	if key.Name == "" {
+		key.Name = secretSanta.Spec.SecretName
+	}
+	if key.Name == "" {
+		key.Name = secretSanta.Name
+	}
+	if key.Namespace == "" {
+		key.Namespace = secretSanta.Namespace
+	}
+	return key
+}
+
+// buildSecret renders the secret object for the template output
+func (m *K8sSecretsMedia) buildSecret(secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) (*corev1.Secret, error) {
+	key := m.secretKey(secretSanta)
+	secretName, namespace := key.Name, key.Namespace
+
 	// Handle TLS secrets specially
 	stringData := map[string]string{}
 	if secretSanta.Spec.SecretType == "kubernetes.io/tls" {
@@ -66,12 +154,23 @@ func (m *K8sSecretsMedia) Store(ctx context.Context, secretSanta *secretsantav1a
 		}
 		// If we don't have the required fields, return error for TLS secrets
 		if stringData["tls.crt"] == "" || stringData["tls.key"] == "" {
-			return fmt.Errorf("TLS secret requires both tls.crt and tls.key fields")
+			return nil, fmt.Errorf("TLS secret requires both tls.crt and tls.key fields")
+		}
+	} else if m.SplitKeys {
+		keys, err := media.ParseSecretKeys(data)
+		if err != nil {
+			return nil, err
 		}
+		stringData = keys
 	} else {
 		stringData["data"] = data
 	}
 
+	binaryData, err := m.decodeBinaryKeys(stringData)
+	if err != nil {
+		return nil, err
+	}
+
 	// Merge user annotations with metadata annotations
 	annotations := make(map[string]string)
 	for k, v := range secretSanta.Spec.Annotations {
@@ -83,29 +182,46 @@ func (m *K8sSecretsMedia) Store(ctx context.Context, secretSanta *secretsantav1a
 		annotations["secrets.secret-santa.io/created-at"] = time.Now().UTC().Format(time.RFC3339)
 		annotations["secrets.secret-santa.io/generator-types"] = m.getGeneratorTypes(secretSanta.Spec.Generators)
 		annotations["secrets.secret-santa.io/template-checksum"] = m.calculateTemplateChecksum(secretSanta.Spec.Template)
-		annotations["secrets.secret-santa.io/source-cr"] = fmt.Sprintf("%s/%s", secretSanta.Namespace, secretSanta.Name)
+		annotations[media.SourceCRAnnotation] = media.Owner(secretSanta)
 	}
+	annotations[media.OwnerAnnotation] = media.Owner(secretSanta)
 
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        secretName,
-			Namespace:   secretSanta.Namespace,
+			Namespace:   namespace,
 			Labels:      secretSanta.Spec.Labels,
 			Annotations: annotations,
 		},
 		Type:       corev1.SecretType(secretSanta.Spec.SecretType),
 		StringData: stringData,
+		Data:       binaryData,
 	}
 
-	err := m.Client.Create(ctx, secret)
-	if err != nil {
-		// Check if secret already exists
-		if client.IgnoreAlreadyExists(err) != nil {
-			return fmt.Errorf("failed to create secret %s/%s: %w", secretSanta.Namespace, secretName, err)
+	return secret, nil
+}
+
+// decodeBinaryKeys moves the configured binary keys out of stringData into raw secret data
+func (m *K8sSecretsMedia) decodeBinaryKeys(stringData map[string]string) (map[string][]byte, error) {
+	if len(m.BinaryKeys) == 0 {
+		return nil, nil
+	}
+
+	binaryData := make(map[string][]byte, len(m.BinaryKeys))
+	for _, key := range m.BinaryKeys {
+		value, ok := stringData[key]
+		if !ok {
+			return nil, fmt.Errorf("binary key %q not found in template output", key)
 		}
-		return nil // Secret already exists, which is fine for create-once policy
+		// Block scalars and wrapped base64 may contain line breaks
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+		if err != nil {
+			return nil, fmt.Errorf("binary key %q is not valid base64: %w", key, err)
+		}
+		binaryData[key] = decoded
+		delete(stringData, key)
 	}
-	return nil
+	return binaryData, nil
 }
 
 func (m *K8sSecretsMedia) GetType() string {
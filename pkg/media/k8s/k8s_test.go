@@ -2,6 +2,7 @@ package k8s
 
 import (
 	"context"
+	"encoding/base64"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,6 +14,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+	"github.com/logicIQ/secret-santa/pkg/media"
 )
 
 func TestK8sSecretsMedia_Store(t *testing.T) {
@@ -292,3 +294,166 @@ func TestK8sSecretsMedia_MetadataDisabled(t *testing.T) {
 	assert.NotContains(t, secret.Annotations, "secrets.secret-santa.io/template-checksum")
 	assert.NotContains(t, secret.Annotations, "secrets.secret-santa.io/source-cr")
 }
+
+func TestK8sSecretsMedia_SplitAndBinaryKeys(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, secretsantav1alpha1.AddToScheme(scheme))
+
+	png := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0xff}
+	encoded := base64.StdEncoding.EncodeToString(png)
+
+	tests := []struct {
+		name         string
+		splitKeys    bool
+		binaryKeys   []string
+		data         string
+		expectError  bool
+		expectedData map[string]string
+		expectedBin  map[string][]byte
+	}{
+		{
+			name:         "split yaml keys",
+			splitKeys:    true,
+			data:         "secret: JBSWY3DPEHPK3PXP\nuri: \"otpauth://totp/Example:admin?secret=JBSWY3DPEHPK3PXP\"\ndigits: 6",
+			expectedData: map[string]string{"secret": "JBSWY3DPEHPK3PXP", "uri": "otpauth://totp/Example:admin?secret=JBSWY3DPEHPK3PXP", "digits": "6"},
+		},
+		{
+			name:         "split with binary key",
+			splitKeys:    true,
+			binaryKeys:   []string{"qr.png"},
+			data:         "secret: JBSWY3DPEHPK3PXP\nqr.png: |\n  " + encoded[:8] + "\n  " + encoded[8:] + "\n",
+			expectedData: map[string]string{"secret": "JBSWY3DPEHPK3PXP"},
+			expectedBin:  map[string][]byte{"qr.png": png},
+		},
+		{
+			name:        "whole output as binary",
+			binaryKeys:  []string{"data"},
+			data:        encoded + "\n",
+			expectedBin: map[string][]byte{"data": png},
+		},
+		{
+			name:        "missing binary key",
+			splitKeys:   true,
+			binaryKeys:  []string{"qr.png"},
+			data:        "secret: abc",
+			expectError: true,
+		},
+		{
+			name:        "invalid base64",
+			splitKeys:   true,
+			binaryKeys:  []string{"qr.png"},
+			data:        "qr.png: not-base64!",
+			expectError: true,
+		},
+		{
+			name:        "nested value",
+			splitKeys:   true,
+			data:        "nested:\n  key: value",
+			expectError: true,
+		},
+		{
+			name:        "invalid key name",
+			splitKeys:   true,
+			data:        "bad/key: value",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secretSanta := &secretsantav1alpha1.SecretSanta{
+				ObjectMeta: metav1.ObjectMeta{Name: "otp", Namespace: "default"},
+				Spec:       secretsantav1alpha1.SecretSantaSpec{SecretType: "Opaque"},
+			}
+			client := fake.NewClientBuilder().WithScheme(scheme).Build()
+			media := &K8sSecretsMedia{Client: client, SplitKeys: tt.splitKeys, BinaryKeys: tt.binaryKeys}
+
+			err := media.Store(context.Background(), secretSanta, tt.data, false)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var secret corev1.Secret
+			err = client.Get(context.Background(),
+				types.NamespacedName{Name: "otp", Namespace: "default"}, &secret)
+			require.NoError(t, err)
+
+			assert.Len(t, secret.StringData, len(tt.expectedData))
+			for key, value := range tt.expectedData {
+				assert.Equal(t, value, secret.StringData[key])
+			}
+			for key, value := range tt.expectedBin {
+				assert.Equal(t, value, secret.Data[key])
+			}
+		})
+	}
+}
+
+func TestK8sSecretsMedia_Rotate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, secretsantav1alpha1.AddToScheme(scheme))
+
+	secretSanta := &secretsantav1alpha1.SecretSanta{
+		ObjectMeta: metav1.ObjectMeta{Name: "rotating", Namespace: "default"},
+		Spec: secretsantav1alpha1.SecretSantaSpec{
+			SecretType: "Opaque",
+			Labels:     map[string]string{"app": "test"},
+		},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).Build()
+	media := &K8sSecretsMedia{Client: client}
+	key := types.NamespacedName{Name: "rotating", Namespace: "default"}
+
+	// Rotate creates the secret when it does not exist yet
+	require.NoError(t, media.Rotate(context.Background(), secretSanta, "first", false))
+	var secret corev1.Secret
+	require.NoError(t, client.Get(context.Background(), key, &secret))
+	assert.Equal(t, "first", secret.StringData["data"])
+
+	// Store keeps the existing data (create-once)
+	require.NoError(t, media.Store(context.Background(), secretSanta, "ignored", false))
+	require.NoError(t, client.Get(context.Background(), key, &secret))
+	assert.Equal(t, "first", secret.StringData["data"])
+
+	// Rotate replaces the existing data
+	require.NoError(t, media.Rotate(context.Background(), secretSanta, "second", false))
+	require.NoError(t, client.Get(context.Background(), key, &secret))
+	assert.Equal(t, "second", secret.StringData["data"])
+	assert.Equal(t, "test", secret.Labels["app"])
+	assert.Equal(t, corev1.SecretTypeOpaque, secret.Type)
+	assert.Equal(t, "default/rotating", secret.Annotations["secrets.secret-santa.io/owner"])
+}
+
+func TestK8sSecretsMedia_ForeignSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	secretSanta := &secretsantav1alpha1.SecretSanta{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}}
+	foreign := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Annotations: map[string]string{media.OwnerAnnotation: "default/other"}},
+		Data:       map[string][]byte{"password": []byte("theirs")},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(foreign).Build()
+	k8sMedia := &K8sSecretsMedia{Client: client}
+
+	err := k8sMedia.Store(context.Background(), secretSanta, "ours", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "secret default/db already exists and is not managed by SecretSanta default/db")
+
+	err = k8sMedia.Rotate(context.Background(), secretSanta, "ours", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not managed by SecretSanta default/db")
+
+	var secret corev1.Secret
+	require.NoError(t, client.Get(context.Background(), types.NamespacedName{Name: "db", Namespace: "default"}, &secret))
+	assert.Equal(t, "theirs", string(secret.Data["password"]))
+	assert.Equal(t, "default/other", secret.Annotations[media.OwnerAnnotation])
+
+	// Secrets created before the owner annotation are recognized by their source-cr annotation
+	secret.Annotations = map[string]string{media.SourceCRAnnotation: "default/db"}
+	require.NoError(t, client.Update(context.Background(), &secret))
+	require.NoError(t, k8sMedia.Rotate(context.Background(), secretSanta, "ours", false))
+}
@@ -0,0 +1,271 @@
+package k8s
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/logicIQ/secret-santa/pkg/metrics"
+)
+
+const (
+	// DefaultRemoteQPS and DefaultRemoteBurst limit the requests from each namespace to each remote cluster
+	DefaultRemoteQPS   = 5
+	DefaultRemoteBurst = 10
+
+	remoteTimeout = 30 * time.Second
+	// Unhealthy clusters fail fast until the backoff expires, doubling up to the maximum
+	remoteInitialBackoff = 10 * time.Second
+	remoteMaxBackoff     = 5 * time.Minute
+	// maxClientsPerCluster bounds the clients cached for different kubeconfigs of one cluster and namespace
+	maxClientsPerCluster = 8
+	// maxClusters bounds the clusters tracked across namespaces, the least recently used is evicted
+	maxClusters = 256
+)
+
+// ClusterManager caches the clients of remote clusters, keyed by the namespace of the SecretSanta and
+// the API server, and tracks their health. All clients of a cluster in one namespace share a rate
+// limiter with the limits set by the operator, so a namespace cannot use up the requests of another.
+type ClusterManager struct {
+	mu        sync.Mutex
+	clusters  map[clusterKey]*clusterState
+	scheme    *runtime.Scheme
+	qps       float32
+	burst     int
+	newClient func(*rest.Config, client.Options) (client.Client, error)
+	now       func() time.Time
+}
+
+type clusterKey struct {
+	namespace string
+	host      string
+}
+
+type clusterState struct {
+	limiter flowcontrol.RateLimiter
+	// clients are keyed by the checksum of the kubeconfig
+	clients  map[string]*clientState
+	lastUsed time.Time
+}
+
+// clientState is the health of the client of one kubeconfig, so a kubeconfig with invalid
+// credentials or a wrong address does not fail the other kubeconfigs of the cluster
+type clientState struct {
+	client    client.Client
+	healthy   bool
+	failures  int
+	lastError error
+	retryAt   time.Time
+}
+
+// RemoteCluster describes how to reach a remote cluster
+type RemoteCluster struct {
+	// Namespace is the namespace of the SecretSanta, clusters are tracked separately per namespace
+	Namespace  string
+	Kubeconfig []byte
+}
+
+// NewClusterManager returns a manager creating clients with the given scheme, or the client-go scheme if nil.
+// qps and burst limit the requests to each cluster per namespace and default to DefaultRemoteQPS and
+// DefaultRemoteBurst.
+func NewClusterManager(scheme *runtime.Scheme, qps float32, burst int) *ClusterManager {
+	if scheme == nil {
+		scheme = clientgoscheme.Scheme
+	}
+	if qps <= 0 {
+		qps = DefaultRemoteQPS
+	}
+	if burst <= 0 {
+		burst = DefaultRemoteBurst
+	}
+	return &ClusterManager{
+		clusters:  make(map[clusterKey]*clusterState),
+		scheme:    scheme,
+		qps:       qps,
+		burst:     burst,
+		newClient: client.New,
+		now:       time.Now,
+	}
+}
+
+// Client returns the cached client of a remote cluster. Kubeconfigs that failed recently return an error
+// until their backoff expires.
+func (m *ClusterManager) Client(cluster RemoteCluster) (client.Client, error) {
+	config, err := restConfigFromKubeconfig(cluster.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	key := clusterKey{namespace: cluster.Namespace, host: config.Host}
+	checksum := fmt.Sprintf("%x", sha256.Sum256(cluster.Kubeconfig))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.clusters[key]
+	if !ok {
+		if len(m.clusters) >= maxClusters {
+			m.evictLeastRecentlyUsed()
+		}
+		state = &clusterState{
+			limiter: flowcontrol.NewTokenBucketRateLimiter(m.qps, m.burst),
+			clients: make(map[string]*clientState),
+		}
+		m.clusters[key] = state
+	}
+	state.lastUsed = m.now()
+
+	if cached, ok := state.clients[checksum]; ok {
+		if !cached.healthy && m.now().Before(cached.retryAt) {
+			return nil, fmt.Errorf("cluster %s is unhealthy after %d consecutive failures, retrying after %s: %w",
+				key.host, cached.failures, cached.retryAt.UTC().Format(time.RFC3339), cached.lastError)
+		}
+		return cached.client, nil
+	}
+	config.RateLimiter = state.limiter
+	c, err := m.newClient(config, client.Options{Scheme: m.scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for cluster %s: %w", key.host, err)
+	}
+	if len(state.clients) >= maxClientsPerCluster {
+		state.clients = make(map[string]*clientState)
+	}
+	cached := &clientState{healthy: true}
+	cached.client = &trackingClient{Client: c, manager: m, key: key, state: cached}
+	state.clients[checksum] = cached
+	return cached.client, nil
+}
+
+// evictLeastRecentlyUsed forgets the cluster that was used least recently, with its metric
+func (m *ClusterManager) evictLeastRecentlyUsed() {
+	var oldest clusterKey
+	var oldestUsed time.Time
+	first := true
+	for key, state := range m.clusters {
+		if first || state.lastUsed.Before(oldestUsed) {
+			oldest, oldestUsed, first = key, state.lastUsed, false
+		}
+	}
+	delete(m.clusters, oldest)
+	metrics.DeleteRemoteCluster(oldest.namespace, oldest.host)
+}
+
+// record updates the health of a client with the result of a request. API errors such as NotFound
+// come from a reachable API server, so only other errors count as failures.
+func (m *ClusterManager) record(key clusterKey, cached *clientState, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	var status apierrors.APIStatus
+	failed := err != nil && !errors.As(err, &status)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.clusters[key]
+	if !ok {
+		return // Evicted, its metric is gone as well
+	}
+	if !failed {
+		cached.healthy, cached.failures, cached.lastError = true, 0, nil
+	} else {
+		cached.failures++
+		cached.healthy = false
+		cached.lastError = err
+		backoff := remoteInitialBackoff
+		for i := 1; i < cached.failures && backoff < remoteMaxBackoff; i++ {
+			backoff *= 2
+		}
+		if backoff > remoteMaxBackoff {
+			backoff = remoteMaxBackoff
+		}
+		cached.retryAt = m.now().Add(backoff)
+	}
+
+	// The cluster is up for the namespace while all of its cached kubeconfigs work
+	up := true
+	for _, c := range state.clients {
+		up = up && c.healthy
+	}
+	metrics.SetRemoteClusterUp(key.namespace, key.host, up)
+}
+
+// restConfigFromKubeconfig builds the client config of a kubeconfig. Kubeconfigs come from Secrets
+// users control, so credential plugins and references to files of the controller are rejected.
+func restConfigFromKubeconfig(kubeconfig []byte) (*rest.Config, error) {
+	if len(kubeconfig) == 0 {
+		return nil, fmt.Errorf("kubeconfig is empty")
+	}
+	raw, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	for name, authInfo := range raw.AuthInfos {
+		if authInfo.Exec != nil || authInfo.AuthProvider != nil {
+			return nil, fmt.Errorf("kubeconfig user %s uses a credential plugin, which is not supported", name)
+		}
+		if authInfo.TokenFile != "" || authInfo.ClientCertificate != "" || authInfo.ClientKey != "" {
+			return nil, fmt.Errorf("kubeconfig user %s must embed its credentials instead of referencing files", name)
+		}
+	}
+	for name, cluster := range raw.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return nil, fmt.Errorf("kubeconfig cluster %s must embed its certificate authority instead of referencing a file", name)
+		}
+	}
+
+	config, err := clientcmd.NewDefaultClientConfig(*raw, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	if config.Timeout == 0 {
+		config.Timeout = remoteTimeout
+	}
+	return config, nil
+}
+
+// trackingClient records the result of each request in the health of its kubeconfig
+type trackingClient struct {
+	client.Client
+	manager *ClusterManager
+	key     clusterKey
+	state   *clientState
+}
+
+func (c *trackingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	err := c.Client.Get(ctx, key, obj, opts...)
+	c.manager.record(c.key, c.state, err)
+	return err
+}
+
+func (c *trackingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	err := c.Client.List(ctx, list, opts...)
+	c.manager.record(c.key, c.state, err)
+	return err
+}
+
+func (c *trackingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	err := c.Client.Create(ctx, obj, opts...)
+	c.manager.record(c.key, c.state, err)
+	return err
+}
+
+func (c *trackingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	err := c.Client.Update(ctx, obj, opts...)
+	c.manager.record(c.key, c.state, err)
+	return err
+}
+
+func (c *trackingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	err := c.Client.Delete(ctx, obj, opts...)
+	c.manager.record(c.key, c.state, err)
+	return err
+}
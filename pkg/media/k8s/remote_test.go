@@ -0,0 +1,275 @@
+package k8s
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+	"github.com/logicIQ/secret-santa/pkg/metrics"
+)
+
+// kubeconfig returns a kubeconfig with a single user with the given fields in YAML flow style
+func kubeconfig(server, user string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: workload
+  cluster:
+    server: %s
+    certificate-authority-data: ""
+contexts:
+- name: workload
+  context:
+    cluster: workload
+    user: admin
+current-context: workload
+users:
+- name: admin
+  user: {%s}
+`, server, user))
+}
+
+// newTestClusterManager returns a manager creating fake clients and recording their configs
+func newTestClusterManager(t *testing.T, funcs interceptor.Funcs) (*ClusterManager, *[]*rest.Config) {
+	t.Helper()
+	var configs []*rest.Config
+	m := NewClusterManager(nil, 0, 0)
+	m.newClient = func(config *rest.Config, options client.Options) (client.Client, error) {
+		configs = append(configs, config)
+		return interceptor.NewClient(newReplicaClient(t), funcs), nil
+	}
+	return m, &configs
+}
+
+func TestClusterManager_ClientCache(t *testing.T) {
+	m, configs := newTestClusterManager(t, interceptor.Funcs{})
+	admin := kubeconfig("https://workload-a:6443", "token: abc")
+	deployer := kubeconfig("https://workload-a:6443", "token: def")
+
+	first, err := m.Client(RemoteCluster{Namespace: "team-a", Kubeconfig: admin})
+	require.NoError(t, err)
+	second, err := m.Client(RemoteCluster{Namespace: "team-a", Kubeconfig: admin})
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	require.Len(t, *configs, 1)
+	assert.Equal(t, "https://workload-a:6443", (*configs)[0].Host)
+	assert.Equal(t, "abc", (*configs)[0].BearerToken)
+	assert.Equal(t, remoteTimeout, (*configs)[0].Timeout)
+	assert.Equal(t, float32(DefaultRemoteQPS), (*configs)[0].RateLimiter.QPS())
+
+	// Another kubeconfig of the same cluster and namespace shares its rate limiter
+	_, err = m.Client(RemoteCluster{Namespace: "team-a", Kubeconfig: deployer})
+	require.NoError(t, err)
+	require.Len(t, *configs, 2)
+	assert.Same(t, (*configs)[0].RateLimiter, (*configs)[1].RateLimiter)
+
+	// Other namespaces get their own client and rate limiter
+	third, err := m.Client(RemoteCluster{Namespace: "team-b", Kubeconfig: admin})
+	require.NoError(t, err)
+	assert.NotSame(t, first, third)
+	require.Len(t, *configs, 3)
+	assert.NotSame(t, (*configs)[0].RateLimiter, (*configs)[2].RateLimiter)
+
+	// The limits are set by the operator
+	m, configs = newTestClusterManager(t, interceptor.Funcs{})
+	m.qps, m.burst = 20, 40
+	_, err = m.Client(RemoteCluster{Namespace: "team-a", Kubeconfig: admin})
+	require.NoError(t, err)
+	require.Len(t, *configs, 1)
+	assert.Equal(t, float32(20), (*configs)[0].RateLimiter.QPS())
+}
+
+func TestClusterManager_Health(t *testing.T) {
+	unreachable := true
+	m := NewClusterManager(nil, 0, 0)
+	m.newClient = func(config *rest.Config, options client.Options) (client.Client, error) {
+		return interceptor.NewClient(newReplicaClient(t), interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				// Only the kubeconfig with the wrong token fails
+				if unreachable && config.BearerToken == "wrong" {
+					return errors.New("dial tcp: connection refused")
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}), nil
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	broken := RemoteCluster{Namespace: "team-a", Kubeconfig: kubeconfig("https://workload-a:6443", "token: wrong")}
+	working := RemoteCluster{Namespace: "team-b", Kubeconfig: kubeconfig("https://workload-a:6443", "token: abc")}
+	key := types.NamespacedName{Namespace: "apps", Name: "db"}
+	health := func(cluster RemoteCluster) *clientState {
+		checksum := fmt.Sprintf("%x", sha256.Sum256(cluster.Kubeconfig))
+		return m.clusters[clusterKey{namespace: cluster.Namespace, host: "https://workload-a:6443"}].clients[checksum]
+	}
+
+	c, err := m.Client(broken)
+	require.NoError(t, err)
+	err = c.Get(context.Background(), key, &corev1.Secret{})
+	require.Error(t, err)
+
+	// The kubeconfig fails fast until the backoff expires
+	_, err = m.Client(broken)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cluster https://workload-a:6443 is unhealthy after 1 consecutive failures")
+	assert.Contains(t, err.Error(), "connection refused")
+
+	// Other kubeconfigs of the same cluster are not affected
+	c, err = m.Client(working)
+	require.NoError(t, err)
+	assert.True(t, apierrors.IsNotFound(c.Get(context.Background(), key, &corev1.Secret{})))
+	assert.True(t, health(working).healthy)
+	_, err = m.Client(RemoteCluster{Namespace: "team-a", Kubeconfig: working.Kubeconfig})
+	require.NoError(t, err)
+
+	now = now.Add(remoteInitialBackoff)
+	c, err = m.Client(broken)
+	require.NoError(t, err)
+	require.Error(t, c.Get(context.Background(), key, &corev1.Secret{}))
+	assert.Equal(t, now.Add(2*remoteInitialBackoff), health(broken).retryAt)
+
+	// API errors such as NotFound come from a reachable cluster
+	now = now.Add(remoteMaxBackoff)
+	unreachable = false
+	c, err = m.Client(broken)
+	require.NoError(t, err)
+	require.Error(t, c.Get(context.Background(), key, &corev1.Secret{}))
+	assert.True(t, health(broken).healthy)
+	assert.Zero(t, health(broken).failures)
+}
+
+func TestClusterManager_Eviction(t *testing.T) {
+	m, _ := newTestClusterManager(t, interceptor.Funcs{})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	admin := kubeconfig("https://workload-a:6443", "token: abc")
+
+	key := types.NamespacedName{Namespace: "apps", Name: "db"}
+	for i := 0; i < maxClusters; i++ {
+		now = now.Add(time.Second)
+		c, err := m.Client(RemoteCluster{Namespace: fmt.Sprintf("team-%d", i), Kubeconfig: admin})
+		require.NoError(t, err)
+		require.Error(t, c.Get(context.Background(), key, &corev1.Secret{}))
+	}
+	// team-0 is used again, so team-1 is the least recently used cluster
+	now = now.Add(time.Second)
+	c, err := m.Client(RemoteCluster{Namespace: "team-0", Kubeconfig: admin})
+	require.NoError(t, err)
+	_, err = m.Client(RemoteCluster{Namespace: "team-new", Kubeconfig: admin})
+	require.NoError(t, err)
+
+	assert.Len(t, m.clusters, maxClusters)
+	assert.Contains(t, m.clusters, clusterKey{namespace: "team-0", host: "https://workload-a:6443"})
+	assert.NotContains(t, m.clusters, clusterKey{namespace: "team-1", host: "https://workload-a:6443"})
+	require.Error(t, c.Get(context.Background(), key, &corev1.Secret{}))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.RemoteClusterUp.WithLabelValues("team-0", "https://workload-a:6443")))
+	// The metric of the evicted cluster is gone, so deleting it again finds nothing
+	assert.False(t, metrics.RemoteClusterUp.DeleteLabelValues("team-1", "https://workload-a:6443"))
+}
+
+func TestClusterManager_StoreInRemoteNamespace(t *testing.T) {
+	m, _ := newTestClusterManager(t, interceptor.Funcs{})
+	c, err := m.Client(RemoteCluster{Kubeconfig: kubeconfig("https://workload-a:6443", "token: abc")})
+	require.NoError(t, err)
+
+	media := &K8sSecretsMedia{Client: c, Namespace: "apps"}
+	secretSanta := &secretsantav1alpha1.SecretSanta{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "management"},
+		Spec:       secretsantav1alpha1.SecretSantaSpec{SecretType: "Opaque"},
+	}
+	require.NoError(t, media.Store(context.Background(), secretSanta, "password123", true))
+
+	var secret corev1.Secret
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Namespace: "apps", Name: "db"}, &secret))
+	assert.Equal(t, "password123", secret.StringData["data"])
+	assert.Equal(t, "management/db", secret.Annotations["secrets.secret-santa.io/source-cr"])
+	for _, state := range m.clusters[clusterKey{host: "https://workload-a:6443"}].clients {
+		assert.True(t, state.healthy)
+	}
+}
+
+func TestRestConfigFromKubeconfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		kubeconfig []byte
+		wantErr    string
+	}{
+		{
+			name:       "empty",
+			kubeconfig: nil,
+			wantErr:    "kubeconfig is empty",
+		},
+		{
+			name:       "invalid",
+			kubeconfig: []byte("clusters: {"),
+			wantErr:    "failed to parse kubeconfig",
+		},
+		{
+			name:       "exec plugin",
+			kubeconfig: kubeconfig("https://workload-a:6443", "exec: {apiVersion: client.authentication.k8s.io/v1, command: /bin/sh}"),
+			wantErr:    "uses a credential plugin",
+		},
+		{
+			name:       "auth provider",
+			kubeconfig: kubeconfig("https://workload-a:6443", "auth-provider: {name: gcp}"),
+			wantErr:    "uses a credential plugin",
+		},
+		{
+			name:       "token file",
+			kubeconfig: kubeconfig("https://workload-a:6443", "tokenFile: /var/run/secrets/kubernetes.io/serviceaccount/token"),
+			wantErr:    "must embed its credentials",
+		},
+		{
+			name:       "client certificate file",
+			kubeconfig: kubeconfig("https://workload-a:6443", "client-certificate: /etc/tls.crt, client-key: /etc/tls.key"),
+			wantErr:    "must embed its credentials",
+		},
+		{
+			name: "certificate authority file",
+			kubeconfig: []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: workload
+  cluster:
+    server: https://workload-a:6443
+    certificate-authority: /etc/ca.crt
+contexts:
+- name: workload
+  context: {cluster: workload, user: admin}
+current-context: workload
+users:
+- name: admin
+  user: {token: abc}
+`),
+			wantErr: "must embed its certificate authority",
+		},
+		{
+			name:       "no current context",
+			kubeconfig: []byte("apiVersion: v1\nkind: Config\n"),
+			wantErr:    "invalid kubeconfig",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := restConfigFromKubeconfig(tt.kubeconfig)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
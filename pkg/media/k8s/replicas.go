@@ -0,0 +1,245 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+)
+
+const (
+	// AcceptReplicasAnnotation on a namespace lists the source namespaces allowed to replicate secrets
+	// into it, separated by commas, or "*" for any namespace
+	AcceptReplicasAnnotation = "secrets.secret-santa.io/accept-replicas-from"
+	// ReplicaOfUIDLabel holds the UID of the SecretSanta that owns a replica
+	ReplicaOfUIDLabel = "secrets.secret-santa.io/replica-of-uid"
+	// ReplicaOfAnnotation holds the namespace/name of the source secret of a replica
+	ReplicaOfAnnotation = "secrets.secret-santa.io/replica-of"
+)
+
+// replicating reports whether replicas are configured
+func (m *K8sSecretsMedia) replicating() bool {
+	return len(m.Namespaces) > 0 || m.NamespaceSelector != nil
+}
+
+// reader returns the reader for namespaces and replicas
+func (m *K8sSecretsMedia) reader() client.Reader {
+	if m.Reader != nil {
+		return m.Reader
+	}
+	return m.Client
+}
+
+// ReconcileReplicas brings the replicas in line with the stored secret and the current target
+// namespaces. The controller calls it on every pass, also when the secret itself is not rewritten,
+// so namespaces that start or stop accepting replicas are picked up. Nothing is done while the
+// source secret does not exist.
+func (m *K8sSecretsMedia) ReconcileReplicas(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta) error {
+	key := m.secretKey(secretSanta)
+	if !m.replicating() {
+		if secretSanta.UID == "" {
+			return nil
+		}
+		// Replicas may be left from an earlier configuration
+		return m.pruneReplicas(ctx, secretSanta, key.Name, nil)
+	}
+	err := m.reader().Get(ctx, key, &corev1.Secret{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get secret %s/%s: %w", key.Namespace, key.Name, err)
+	}
+	return m.replicate(ctx, secretSanta, key)
+}
+
+// replicate copies the source secret into every target namespace and removes replicas
+// from namespaces that are no longer targeted
+func (m *K8sSecretsMedia) replicate(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, key client.ObjectKey) error {
+	if secretSanta.UID == "" {
+		return fmt.Errorf("secret santa %s/%s has no UID, replicas cannot be tracked", secretSanta.Namespace, secretSanta.Name)
+	}
+	// The replicas copy the stored secret, so they carry the same value even if it already existed
+	var source corev1.Secret
+	if err := m.reader().Get(ctx, key, &source); err != nil {
+		return fmt.Errorf("failed to get secret %s/%s: %w", key.Namespace, key.Name, err)
+	}
+
+	targets, err := m.targetNamespaces(ctx, source.Namespace)
+	if err != nil {
+		return err
+	}
+	for _, namespace := range targets {
+		if err := m.storeReplica(ctx, secretSanta, &source, namespace); err != nil {
+			return err
+		}
+	}
+	return m.pruneReplicas(ctx, secretSanta, source.Name, targets)
+}
+
+// targetNamespaces returns the sorted namespaces that receive a replica. Explicitly listed
+// namespaces must exist and accept replicas, selected namespaces that do not are skipped.
+func (m *K8sSecretsMedia) targetNamespaces(ctx context.Context, sourceNamespace string) ([]string, error) {
+	targets := map[string]bool{}
+	for _, name := range m.Namespaces {
+		if name == sourceNamespace {
+			continue
+		}
+		var namespace corev1.Namespace
+		if err := m.reader().Get(ctx, client.ObjectKey{Name: name}, &namespace); err != nil {
+			return nil, fmt.Errorf("failed to get target namespace %s: %w", name, err)
+		}
+		if !acceptsReplicas(&namespace, sourceNamespace) {
+			return nil, fmt.Errorf("namespace %s does not accept replicas from %s, add %s to its %s annotation",
+				name, sourceNamespace, sourceNamespace, AcceptReplicasAnnotation)
+		}
+		targets[name] = true
+	}
+
+	if m.NamespaceSelector != nil {
+		var namespaces corev1.NamespaceList
+		if err := m.reader().List(ctx, &namespaces, client.MatchingLabelsSelector{Selector: m.NamespaceSelector}); err != nil {
+			return nil, fmt.Errorf("failed to list target namespaces: %w", err)
+		}
+		for i := range namespaces.Items {
+			namespace := &namespaces.Items[i]
+			if namespace.Name != sourceNamespace && acceptsReplicas(namespace, sourceNamespace) {
+				targets[namespace.Name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// acceptsReplicas checks the allow-list annotation of a target namespace
+func acceptsReplicas(namespace *corev1.Namespace, sourceNamespace string) bool {
+	if namespace.DeletionTimestamp != nil {
+		return false
+	}
+	for _, allowed := range strings.Split(namespace.Annotations[AcceptReplicasAnnotation], ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || allowed == sourceNamespace {
+			return true
+		}
+	}
+	return false
+}
+
+// storeReplica creates or updates the replica of the source secret in a namespace. Secrets
+// that are not replicas of this SecretSanta are never overwritten.
+func (m *K8sSecretsMedia) storeReplica(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, source *corev1.Secret, namespace string) error {
+	replicaLabels := map[string]string{}
+	for k, v := range source.Labels {
+		replicaLabels[k] = v
+	}
+	replicaLabels[ReplicaOfUIDLabel] = string(secretSanta.UID)
+	annotations := map[string]string{}
+	for k, v := range source.Annotations {
+		annotations[k] = v
+	}
+	annotations[ReplicaOfAnnotation] = fmt.Sprintf("%s/%s", source.Namespace, source.Name)
+	data := make(map[string][]byte, len(source.Data)+len(source.StringData))
+	for k, v := range source.Data {
+		data[k] = v
+	}
+	for k, v := range source.StringData {
+		data[k] = []byte(v)
+	}
+
+	var existing corev1.Secret
+	err := m.reader().Get(ctx, client.ObjectKey{Namespace: namespace, Name: source.Name}, &existing)
+	if apierrors.IsNotFound(err) {
+		replica := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        source.Name,
+				Namespace:   namespace,
+				Labels:      replicaLabels,
+				Annotations: annotations,
+			},
+			Type: source.Type,
+			Data: data,
+		}
+		if err := m.Client.Create(ctx, replica); err != nil {
+			return fmt.Errorf("failed to create replica %s/%s: %w", namespace, source.Name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get replica %s/%s: %w", namespace, source.Name, err)
+	}
+	if existing.Labels[ReplicaOfUIDLabel] != string(secretSanta.UID) {
+		return fmt.Errorf("secret %s/%s already exists and is not a replica of %s/%s", namespace, source.Name, secretSanta.Namespace, secretSanta.Name)
+	}
+
+	existing.Labels = replicaLabels
+	existing.Annotations = annotations
+	existing.Data = data
+	existing.StringData = nil
+	if err := m.Client.Update(ctx, &existing); err != nil {
+		return fmt.Errorf("failed to update replica %s/%s: %w", namespace, source.Name, err)
+	}
+	return nil
+}
+
+// pruneReplicas deletes replicas of the SecretSanta outside the target namespaces
+func (m *K8sSecretsMedia) pruneReplicas(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, secretName string, targets []string) error {
+	replicas, err := listReplicas(ctx, m.reader(), secretSanta)
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]bool, len(targets))
+	for _, namespace := range targets {
+		keep[namespace] = true
+	}
+	for i := range replicas {
+		replica := &replicas[i]
+		if keep[replica.Namespace] && replica.Name == secretName {
+			continue
+		}
+		if err := client.IgnoreNotFound(m.Client.Delete(ctx, replica)); err != nil {
+			return fmt.Errorf("failed to delete replica %s/%s: %w", replica.Namespace, replica.Name, err)
+		}
+	}
+	return nil
+}
+
+// DeleteReplicas deletes the replicas of a SecretSanta in all namespaces. Owner references
+// cannot cross namespaces, so replicas are found by the UID label instead, listed with reader.
+func DeleteReplicas(ctx context.Context, c client.Client, reader client.Reader, secretSanta *secretsantav1alpha1.SecretSanta) error {
+	if secretSanta.UID == "" {
+		return nil
+	}
+	replicas, err := listReplicas(ctx, reader, secretSanta)
+	if err != nil {
+		return err
+	}
+	for i := range replicas {
+		if err := client.IgnoreNotFound(c.Delete(ctx, &replicas[i])); err != nil {
+			return fmt.Errorf("failed to delete replica %s/%s: %w", replicas[i].Namespace, replicas[i].Name, err)
+		}
+	}
+	return nil
+}
+
+func listReplicas(ctx context.Context, c client.Reader, secretSanta *secretsantav1alpha1.SecretSanta) ([]corev1.Secret, error) {
+	var secrets corev1.SecretList
+	selector := labels.SelectorFromSet(labels.Set{ReplicaOfUIDLabel: string(secretSanta.UID)})
+	if err := c.List(ctx, &secrets, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list replicas: %w", err)
+	}
+	return secrets.Items, nil
+}
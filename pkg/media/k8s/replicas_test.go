@@ -0,0 +1,193 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+)
+
+func namespace(name string, nsLabels map[string]string, acceptFrom string) *corev1.Namespace {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nsLabels}}
+	if acceptFrom != "" {
+		ns.Annotations = map[string]string{AcceptReplicasAnnotation: acceptFrom}
+	}
+	return ns
+}
+
+func newReplicaClient(t *testing.T, objects ...client.Object) client.WithWatch {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, secretsantav1alpha1.AddToScheme(scheme))
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func replicaSecretSanta() *secretsantav1alpha1.SecretSanta {
+	return &secretsantav1alpha1.SecretSanta{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "platform", UID: types.UID("uid-1")},
+		Spec: secretsantav1alpha1.SecretSantaSpec{
+			SecretType: "Opaque",
+			Labels:     map[string]string{"app": "shared"},
+		},
+	}
+}
+
+func replicaData(t *testing.T, c client.Client, namespace string) string {
+	t.Helper()
+	var secret corev1.Secret
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: "shared"}, &secret))
+	assert.Equal(t, "uid-1", secret.Labels[ReplicaOfUIDLabel])
+	assert.Equal(t, "platform/shared", secret.Annotations[ReplicaOfAnnotation])
+	assert.Equal(t, "shared", secret.Labels["app"])
+	return string(secret.Data["data"])
+}
+
+func TestK8sSecretsMedia_Replicas(t *testing.T) {
+	c := newReplicaClient(t,
+		namespace("platform", nil, ""),
+		namespace("team-a", nil, "platform"),
+		namespace("team-b", map[string]string{"shared-db": "true"}, "*"),
+		namespace("team-c", map[string]string{"shared-db": "true"}, "other"),
+	)
+	media := &K8sSecretsMedia{
+		Client:            c,
+		Namespaces:        []string{"team-a", "platform"},
+		NamespaceSelector: labels.SelectorFromSet(labels.Set{"shared-db": "true"}),
+	}
+	ss := replicaSecretSanta()
+
+	require.NoError(t, media.Store(context.Background(), ss, "first", true))
+	assert.Equal(t, "first", replicaData(t, c, "team-a"))
+	assert.Equal(t, "first", replicaData(t, c, "team-b"))
+	// team-c does not accept replicas from platform
+	err := c.Get(context.Background(), types.NamespacedName{Namespace: "team-c", Name: "shared"}, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	// Store keeps the value of the existing source secret
+	require.NoError(t, media.Store(context.Background(), ss, "ignored", true))
+	assert.Equal(t, "first", replicaData(t, c, "team-a"))
+
+	require.NoError(t, media.Rotate(context.Background(), ss, "second", true))
+	assert.Equal(t, "second", replicaData(t, c, "team-a"))
+	assert.Equal(t, "second", replicaData(t, c, "team-b"))
+
+	// Replicas in namespaces that are no longer targeted are removed
+	media.NamespaceSelector = nil
+	require.NoError(t, media.Rotate(context.Background(), ss, "third", true))
+	assert.Equal(t, "third", replicaData(t, c, "team-a"))
+	err = c.Get(context.Background(), types.NamespacedName{Namespace: "team-b", Name: "shared"}, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	require.NoError(t, DeleteReplicas(context.Background(), c, c, ss))
+	var secrets corev1.SecretList
+	require.NoError(t, c.List(context.Background(), &secrets))
+	require.Len(t, secrets.Items, 1)
+	assert.Equal(t, "platform", secrets.Items[0].Namespace)
+}
+
+func TestK8sSecretsMedia_ReconcileReplicas(t *testing.T) {
+	teamA := namespace("team-a", nil, "")
+	c := newReplicaClient(t, namespace("platform", nil, ""), teamA)
+	media := &K8sSecretsMedia{Client: c, NamespaceSelector: labels.Everything()}
+	ss := replicaSecretSanta()
+
+	// Nothing to replicate before the secret exists
+	require.NoError(t, media.ReconcileReplicas(context.Background(), ss))
+	require.NoError(t, media.Store(context.Background(), ss, "first", true))
+	err := c.Get(context.Background(), types.NamespacedName{Namespace: "team-a", Name: "shared"}, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	// The namespace opts in after the secret was stored
+	teamA.Annotations = map[string]string{AcceptReplicasAnnotation: "platform"}
+	require.NoError(t, c.Update(context.Background(), teamA))
+	require.NoError(t, media.ReconcileReplicas(context.Background(), ss))
+	assert.Equal(t, "first", replicaData(t, c, "team-a"))
+
+	// Replicas are removed once none are configured
+	media.NamespaceSelector = nil
+	require.NoError(t, media.ReconcileReplicas(context.Background(), ss))
+	err = c.Get(context.Background(), types.NamespacedName{Namespace: "team-a", Name: "shared"}, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestK8sSecretsMedia_ReplicaReader(t *testing.T) {
+	// Namespaces and replicas are read with the reader, writes go through the client
+	c := newReplicaClient(t, namespace("platform", nil, ""), namespace("team-a", nil, "platform"))
+	media := &K8sSecretsMedia{Client: c, Reader: newReplicaClient(t), Namespaces: []string{"team-a"}}
+	err := media.Store(context.Background(), replicaSecretSanta(), "value", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to get secret platform/shared")
+
+	media.Reader = c
+	require.NoError(t, media.Store(context.Background(), replicaSecretSanta(), "value", false))
+	assert.Equal(t, "value", replicaData(t, c, "team-a"))
+}
+
+func TestK8sSecretsMedia_ReplicaErrors(t *testing.T) {
+	foreign := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "team-a"}}
+	tests := []struct {
+		name       string
+		objects    []client.Object
+		namespaces []string
+		uid        types.UID
+		wantErr    string
+	}{
+		{
+			name:       "missing namespace",
+			namespaces: []string{"team-a"},
+			uid:        "uid-1",
+			wantErr:    "failed to get target namespace team-a",
+		},
+		{
+			name:       "namespace without opt-in",
+			objects:    []client.Object{namespace("team-a", nil, "")},
+			namespaces: []string{"team-a"},
+			uid:        "uid-1",
+			wantErr:    "namespace team-a does not accept replicas from platform",
+		},
+		{
+			name:       "foreign secret",
+			objects:    []client.Object{namespace("team-a", nil, "platform"), foreign},
+			namespaces: []string{"team-a"},
+			uid:        "uid-1",
+			wantErr:    "secret team-a/shared already exists and is not a replica of platform/shared",
+		},
+		{
+			name:       "missing uid",
+			objects:    []client.Object{namespace("team-a", nil, "platform")},
+			namespaces: []string{"team-a"},
+			wantErr:    "has no UID",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newReplicaClient(t, tt.objects...)
+			media := &K8sSecretsMedia{Client: c, Namespaces: tt.namespaces}
+			ss := replicaSecretSanta()
+			ss.UID = tt.uid
+			err := media.Store(context.Background(), ss, "value", false)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestAcceptsReplicas(t *testing.T) {
+	assert.True(t, acceptsReplicas(namespace("a", nil, "*"), "platform"))
+	assert.True(t, acceptsReplicas(namespace("a", nil, "infra, platform"), "platform"))
+	assert.False(t, acceptsReplicas(namespace("a", nil, "platform-dev"), "platform"))
+	assert.False(t, acceptsReplicas(namespace("a", nil, ""), "platform"))
+}
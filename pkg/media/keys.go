@@ -0,0 +1,79 @@
+package media
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ParseKeys splits a YAML or JSON object of scalar values into keys. Values keep the text of the
+// template output, so numbers such as 012345 or 0x1F and words such as yes are not converted.
+func ParseKeys(data string) (map[string]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse template output as key/value data: %w", err)
+	}
+	if len(doc.Content) == 0 || isNull(doc.Content[0]) {
+		return nil, fmt.Errorf("template output contains no keys")
+	}
+	root := resolveAlias(doc.Content[0])
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse template output as key/value data: expected an object, line %d", root.Line)
+	}
+	if len(root.Content) == 0 {
+		return nil, fmt.Errorf("template output contains no keys")
+	}
+
+	keys := make(map[string]string, len(root.Content)/2)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		keyNode, valueNode := resolveAlias(root.Content[i]), resolveAlias(root.Content[i+1])
+		if keyNode.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("template output keys must be scalars, line %d", keyNode.Line)
+		}
+		if keyNode.ShortTag() == "!!merge" {
+			return nil, fmt.Errorf("template output merge keys are not supported, line %d", keyNode.Line)
+		}
+		key := keyNode.Value
+		if _, exists := keys[key]; exists {
+			return nil, fmt.Errorf("key %q is defined more than once", key)
+		}
+		if valueNode.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("key %q must have a scalar value", key)
+		}
+		if isNull(valueNode) {
+			keys[key] = ""
+			continue
+		}
+		keys[key] = valueNode.Value
+	}
+	return keys, nil
+}
+
+// ParseSecretKeys parses the keys like ParseKeys and checks that they are valid secret keys
+func ParseSecretKeys(data string) (map[string]string, error) {
+	keys, err := ParseKeys(data)
+	if err != nil {
+		return nil, err
+	}
+	for key := range keys {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid secret key %q: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	return keys, nil
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// isNull reports whether a node is an implicit or explicit null, such as ~, null or an empty value
+func isNull(node *yaml.Node) bool {
+	node = resolveAlias(node)
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null"
+}
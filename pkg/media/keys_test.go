@@ -0,0 +1,96 @@
+package media
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "scalars keep their text",
+			data: "zip: 012345\nhex: 0x1F\nbig: 12345678901234567890\nflag: yes\nfloat: 1.50\nname: db\n",
+			want: map[string]string{"zip": "012345", "hex": "0x1F", "big": "12345678901234567890", "flag": "yes", "float": "1.50", "name": "db"},
+		},
+		{
+			name: "json",
+			data: `{"user": "admin", "port": 5432, "tls": true}`,
+			want: map[string]string{"user": "admin", "port": "5432", "tls": "true"},
+		},
+		{
+			name: "null and quoted values",
+			data: "empty:\ntilde: ~\nquoted: \"null\"\nblock: |\n  line1\n  line2\n",
+			want: map[string]string{"empty": "", "tilde": "", "quoted": "null", "block": "line1\nline2\n"},
+		},
+		{
+			name: "aliases",
+			data: "password: &pw s3cr3t\ncopy: *pw\n",
+			want: map[string]string{"password": "s3cr3t", "copy": "s3cr3t"},
+		},
+		{
+			name:    "empty",
+			data:    "",
+			wantErr: "contains no keys",
+		},
+		{
+			name:    "empty object",
+			data:    "{}",
+			wantErr: "contains no keys",
+		},
+		{
+			name:    "not an object",
+			data:    "- a\n- b\n",
+			wantErr: "failed to parse template output as key/value data: expected an object",
+		},
+		{
+			name:    "nested value",
+			data:    "db:\n  user: admin\n",
+			wantErr: `key "db" must have a scalar value`,
+		},
+		{
+			name:    "duplicate key",
+			data:    "user: a\nuser: b\n",
+			wantErr: `key "user" is defined more than once`,
+		},
+		{
+			name:    "merge key",
+			data:    "<<: {user: a}\n",
+			wantErr: "merge keys are not supported",
+		},
+		{
+			name:    "invalid yaml",
+			data:    "user: [",
+			wantErr: "failed to parse template output",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKeys(tt.data)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseSecretKeys(t *testing.T) {
+	keys, err := ParseSecretKeys("tls.crt: abc\nDB_PASSWORD: 007\n")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"tls.crt": "abc", "DB_PASSWORD": "007"}, keys)
+
+	_, err = ParseSecretKeys("bad/key: value\n")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid secret key "bad/key"`)
+}
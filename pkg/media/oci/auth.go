@@ -0,0 +1,338 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultServiceAccountTokenFile and DefaultServiceAccountCAFile are used for OKE workload identity
+	DefaultServiceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	DefaultServiceAccountCAFile    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+
+	metadataEndpoint = "http://169.254.169.254/opc/v2"
+	// workloadIdentityPort is the port of the OKE resource principal token proxy on the API server host
+	workloadIdentityPort = "12250"
+	sessionKeyBits       = 2048
+)
+
+// keyProvider returns the key ID and private key that sign OCI API requests
+type keyProvider interface {
+	signingKey(ctx context.Context) (string, *rsa.PrivateKey, error)
+}
+
+// userPrincipal signs with the API key of an IAM user
+type userPrincipal struct {
+	keyID string
+	key   *rsa.PrivateKey
+}
+
+func newUserPrincipal(tenancyID, userID, fingerprint, privateKey string) (*userPrincipal, error) {
+	if tenancyID == "" || userID == "" || fingerprint == "" || privateKey == "" {
+		return nil, fmt.Errorf("tenancy_id, user_id, fingerprint and private_key are required for user_principal auth")
+	}
+	key, err := parsePrivateKey([]byte(privateKey))
+	if err != nil {
+		return nil, err
+	}
+	return &userPrincipal{keyID: fmt.Sprintf("%s/%s/%s", tenancyID, userID, fingerprint), key: key}, nil
+}
+
+func (p *userPrincipal) signingKey(ctx context.Context) (string, *rsa.PrivateKey, error) {
+	return p.keyID, p.key, nil
+}
+
+// sessionPrincipal signs with a session key whose public half was exchanged for a security token.
+// Instance principals and OKE workload identity both work this way and only differ in the exchange.
+type sessionPrincipal struct {
+	exchange func(ctx context.Context, publicKey *rsa.PublicKey) (string, error)
+
+	mu    sync.Mutex
+	token string
+	key   *rsa.PrivateKey
+}
+
+func (p *sessionPrincipal) signingKey(ctx context.Context) (string, *rsa.PrivateKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token == "" {
+		key, err := rsa.GenerateKey(rand.Reader, sessionKeyBits)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to generate session key: %w", err)
+		}
+		token, err := p.exchange(ctx, &key.PublicKey)
+		if err != nil {
+			return "", nil, err
+		}
+		p.token, p.key = token, key
+	}
+	return "ST$" + p.token, p.key, nil
+}
+
+// newInstancePrincipal exchanges the instance certificate from the metadata service for a token at
+// the federation endpoint of the region
+func newInstancePrincipal(httpClient *http.Client, metadataURL, authURL string) *sessionPrincipal {
+	return &sessionPrincipal{exchange: func(ctx context.Context, publicKey *rsa.PublicKey) (string, error) {
+		fetch := func(name string) ([]byte, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL+"/identity/"+name, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer Oracle")
+			return send(httpClient, req)
+		}
+		certPEM, err := fetch("cert.pem")
+		if err != nil {
+			return "", fmt.Errorf("failed to get instance certificate: %w", err)
+		}
+		intermediatePEM, err := fetch("intermediate.pem")
+		if err != nil {
+			return "", fmt.Errorf("failed to get instance intermediate certificate: %w", err)
+		}
+		keyPEM, err := fetch("key.pem")
+		if err != nil {
+			return "", fmt.Errorf("failed to get instance key: %w", err)
+		}
+
+		cert, err := parseCertificate(certPEM)
+		if err != nil {
+			return "", err
+		}
+		intermediate, err := parseCertificate(intermediatePEM)
+		if err != nil {
+			return "", err
+		}
+		instanceKey, err := parsePrivateKey(keyPEM)
+		if err != nil {
+			return "", err
+		}
+		tenancyID := tenancyFromCertificate(cert)
+		if tenancyID == "" {
+			return "", fmt.Errorf("instance certificate does not contain a tenancy")
+		}
+		publicKeyDER, err := x509.MarshalPKIXPublicKey(publicKey)
+		if err != nil {
+			return "", err
+		}
+
+		body, err := json.Marshal(map[string]interface{}{
+			"certificate":              base64.StdEncoding.EncodeToString(cert.Raw),
+			"publicKey":                base64.StdEncoding.EncodeToString(publicKeyDER),
+			"intermediateCertificates": []string{base64.StdEncoding.EncodeToString(intermediate.Raw)},
+			"purpose":                  "DEFAULT",
+		})
+		if err != nil {
+			return "", err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, authURL+"/v1/x509", bytes.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		keyID := fmt.Sprintf("%s/fed-x509/%s", tenancyID, fingerprint(cert.Raw))
+		if err := signRequest(req, body, keyID, instanceKey); err != nil {
+			return "", err
+		}
+		payload, err := send(httpClient, req)
+		if err != nil {
+			return "", fmt.Errorf("failed to get instance principal token: %w", err)
+		}
+		var response struct {
+			Token string `json:"token"`
+		}
+		if err := json.Unmarshal(payload, &response); err != nil || response.Token == "" {
+			return "", fmt.Errorf("failed to get instance principal token: invalid response")
+		}
+		return response.Token, nil
+	}}
+}
+
+// newWorkloadIdentity exchanges the service account token of the operator for a resource principal
+// token at the OKE proxy on the API server host
+func newWorkloadIdentity(endpoint, tokenFile, caFile string) (*sessionPrincipal, error) {
+	if endpoint == "" {
+		host := os.Getenv("KUBERNETES_SERVICE_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("KUBERNETES_SERVICE_HOST is not set, workload_identity requires OKE")
+		}
+		endpoint = "https://" + net.JoinHostPort(host, workloadIdentityPort)
+	}
+	if tokenFile == "" {
+		tokenFile = DefaultServiceAccountTokenFile
+	}
+	if caFile == "" {
+		caFile = DefaultServiceAccountCAFile
+	}
+
+	return &sessionPrincipal{exchange: func(ctx context.Context, publicKey *rsa.PublicKey) (string, error) {
+		serviceAccountToken, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read service account token: %w", err)
+		}
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read service account CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return "", fmt.Errorf("no certificates found in %s", caFile)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+		httpClient := &http.Client{Transport: transport, Timeout: requestTimeout}
+
+		publicKeyDER, err := x509.MarshalPKIXPublicKey(publicKey)
+		if err != nil {
+			return "", err
+		}
+		body, err := json.Marshal(map[string]string{"podKey": base64.StdEncoding.EncodeToString(publicKeyDER)})
+		if err != nil {
+			return "", err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/resourcePrincipalSessionTokens", bytes.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(serviceAccountToken)))
+		payload, err := send(httpClient, req)
+		if err != nil {
+			return "", fmt.Errorf("failed to get workload identity token: %w", err)
+		}
+
+		// The proxy returns a quoted base64 JSON document whose token carries the ST$ prefix
+		decoded, err := base64.StdEncoding.DecodeString(strings.Trim(strings.TrimSpace(string(payload)), `"`))
+		if err != nil {
+			return "", fmt.Errorf("failed to decode workload identity token: %w", err)
+		}
+		var response struct {
+			Token string `json:"token"`
+		}
+		if err := json.Unmarshal(decoded, &response); err != nil || response.Token == "" {
+			return "", fmt.Errorf("failed to get workload identity token: invalid response")
+		}
+		return strings.TrimPrefix(response.Token, "ST$"), nil
+	}}, nil
+}
+
+// signRequest adds the OCI HTTP signature (draft-cavage-http-signatures with rsa-sha256) to req
+func signRequest(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey) error {
+	if req.Header.Get("Date") == "" {
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	headers := []string{"date", "(request-target)", "host"}
+	if req.Method == http.MethodPost || req.Method == http.MethodPut || req.Method == http.MethodPatch {
+		sum := sha256.Sum256(body)
+		if req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Content-Length", fmt.Sprint(len(body)))
+		req.Header.Set("X-Content-Sha256", base64.StdEncoding.EncodeToString(sum[:]))
+		headers = append(headers, "content-length", "content-type", "x-content-sha256")
+	}
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, signingDigest(req, headers))
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(`Signature version="1",keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// signingDigest hashes the signing string of the listed headers
+func signingDigest(req *http.Request, headers []string) []byte {
+	lines := make([]string, len(headers))
+	for i, header := range headers {
+		switch header {
+		case "(request-target)":
+			lines[i] = fmt.Sprintf("(request-target): %s %s", strings.ToLower(req.Method), req.URL.RequestURI())
+		case "host":
+			lines[i] = "host: " + req.URL.Host
+		default:
+			lines[i] = fmt.Sprintf("%s: %s", header, req.Header.Get(header))
+		}
+	}
+	digest := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return digest[:]
+}
+
+func send(httpClient *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, &apiError{status: resp.StatusCode, body: payload}
+	}
+	return payload, nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key must be an RSA key")
+	}
+	return key, nil
+}
+
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("certificate is not PEM encoded")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// tenancyFromCertificate returns the tenancy OCID of an instance certificate
+func tenancyFromCertificate(cert *x509.Certificate) string {
+	for _, unit := range cert.Subject.OrganizationalUnit {
+		if strings.HasPrefix(unit, "opc-tenant:") {
+			return strings.TrimPrefix(unit, "opc-tenant:")
+		}
+	}
+	return ""
+}
+
+// fingerprint formats the SHA-1 fingerprint of a certificate like the OCI console
+func fingerprint(der []byte) string {
+	sum := sha1.Sum(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":")
+}
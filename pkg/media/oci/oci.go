@@ -0,0 +1,317 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+)
+
+const (
+	tagKeyCreatedAt        = "secrets_secret-santa_io_created-at"
+	tagKeyGeneratorTypes   = "secrets_secret-santa_io_generator-types"
+	tagKeyTemplateChecksum = "secrets_secret-santa_io_template-checksum"
+	tagKeySourceCR         = "secrets_secret-santa_io_source-cr"
+
+	secretsAPI     = "/20180608/secrets"
+	requestTimeout = 30 * time.Second
+)
+
+var (
+	ocidRegex            = regexp.MustCompile(`^ocid1\.[a-z0-9]+\.[a-z0-9-]+\.[a-z0-9-]*(\.[a-z0-9-]+)+$`)
+	regionRegex          = regexp.MustCompile(`^[a-z]{2,}-[a-z]+-[0-9]+$`)
+	secretNameRegex      = regexp.MustCompile(`^[A-Za-z0-9._-]{1,255}$`)
+	tagKeyInvalidChars   = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+	errSecretNotFound    = errors.New("secret not found")
+	errSecretUnavailable = errors.New("secret is pending deletion")
+)
+
+// OCIVaultMedia stores secrets in Oracle Cloud Infrastructure Vault
+type OCIVaultMedia struct {
+	Region        string
+	CompartmentID string
+	VaultID       string
+	// KeyID is the OCID of the master encryption key of the vault
+	KeyID      string
+	SecretName string
+
+	// AuthMethod is instance_principal (default), workload_identity or user_principal
+	AuthMethod string
+	// TenancyID, UserID, Fingerprint and PrivateKey (PEM) are the API key of a user principal
+	TenancyID   string
+	UserID      string
+	Fingerprint string
+	PrivateKey  string
+
+	// Endpoint overrides the vault secrets endpoint of the region
+	Endpoint string
+	// HTTPClient overrides the client used for requests
+	HTTPClient *http.Client
+
+	// The identity endpoints and service account files are overridden in tests
+	metadataURL             string
+	authURL                 string
+	workloadIdentityURL     string
+	serviceAccountTokenFile string
+	serviceAccountCAFile    string
+}
+
+func (m *OCIVaultMedia) Store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	return m.store(ctx, secretSanta, data, enableMetadata, true)
+}
+
+// Rotate adds a new version to the secret, creating the secret if it does not exist
+func (m *OCIVaultMedia) Rotate(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	return m.store(ctx, secretSanta, data, enableMetadata, false)
+}
+
+func (m *OCIVaultMedia) GetType() string {
+	return "oci-vault"
+}
+
+func (m *OCIVaultMedia) store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool, createOnce bool) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	secretName := m.resolveSecretName(secretSanta)
+	if !secretNameRegex.MatchString(secretName) {
+		return fmt.Errorf("invalid OCI secret name %q", secretName)
+	}
+	c, err := m.newClient()
+	if err != nil {
+		return err
+	}
+
+	content := map[string]string{
+		"contentType": "BASE64",
+		"content":     base64.StdEncoding.EncodeToString([]byte(data)),
+		"stage":       "CURRENT",
+	}
+	secretID, err := c.findSecret(ctx, m.CompartmentID, m.VaultID, secretName)
+	switch {
+	case err == nil:
+		if createOnce {
+			return nil // Secret already exists, which is fine for create-once policy
+		}
+		body := map[string]interface{}{"secretContent": content}
+		if err := c.do(ctx, http.MethodPut, secretsAPI+"/"+url.PathEscape(secretID), nil, body, nil); err != nil {
+			return fmt.Errorf("failed to update secret %s: %w", secretName, err)
+		}
+		return nil
+	case !errors.Is(err, errSecretNotFound):
+		return fmt.Errorf("failed to look up secret %s: %w", secretName, err)
+	}
+
+	body := map[string]interface{}{
+		"compartmentId": m.CompartmentID,
+		"vaultId":       m.VaultID,
+		"keyId":         m.KeyID,
+		"secretName":    secretName,
+		"secretContent": content,
+	}
+	if tags := createFreeformTags(secretSanta, enableMetadata); len(tags) > 0 {
+		body["freeformTags"] = tags
+	}
+	if err := c.do(ctx, http.MethodPost, secretsAPI, nil, body, nil); err != nil {
+		var apiErr *apiError
+		if createOnce && errors.As(err, &apiErr) && apiErr.status == http.StatusConflict {
+			return nil // Created concurrently, which is fine for create-once policy
+		}
+		return fmt.Errorf("failed to create secret %s: %w", secretName, err)
+	}
+	return nil
+}
+
+func (m *OCIVaultMedia) validate() error {
+	if !regionRegex.MatchString(m.Region) {
+		return fmt.Errorf("a valid OCI region is required, got: %q", m.Region)
+	}
+	for name, value := range map[string]string{"compartment_id": m.CompartmentID, "vault_id": m.VaultID, "key_id": m.KeyID} {
+		if !ocidRegex.MatchString(value) {
+			return fmt.Errorf("%s must be an OCID, got: %q", name, value)
+		}
+	}
+	return nil
+}
+
+// resolveSecretName resolves the secret name with namespace prefix
+func (m *OCIVaultMedia) resolveSecretName(secretSanta *secretsantav1alpha1.SecretSanta) string {
+	name := m.SecretName
+	if name == "" {
+		name = secretSanta.Spec.SecretName
+	}
+	if name == "" {
+		name = secretSanta.Name
+	}
+	return fmt.Sprintf("%s-%s", secretSanta.Namespace, name)
+}
+
+// createFreeformTags creates freeform tags from labels, annotations and metadata. Freeform tag keys
+// cannot contain periods or slashes, so they are replaced with underscores.
+func createFreeformTags(secretSanta *secretsantav1alpha1.SecretSanta, enableMetadata bool) map[string]string {
+	tags := make(map[string]string)
+	for k, v := range secretSanta.Spec.Labels {
+		tags[tagKeyInvalidChars.ReplaceAllString(k, "_")] = v
+	}
+	for k, v := range secretSanta.Spec.Annotations {
+		tags[tagKeyInvalidChars.ReplaceAllString(k, "_")] = v
+	}
+	if enableMetadata {
+		tags[tagKeyCreatedAt] = time.Now().UTC().Format(time.RFC3339)
+		tags[tagKeyGeneratorTypes] = getGeneratorTypes(secretSanta.Spec.Generators)
+		tags[tagKeyTemplateChecksum] = calculateTemplateChecksum(secretSanta.Spec.Template)
+		tags[tagKeySourceCR] = fmt.Sprintf("%s/%s", secretSanta.Namespace, secretSanta.Name)
+	}
+	return tags
+}
+
+// getGeneratorTypes extracts generator types from the configuration
+func getGeneratorTypes(generators []secretsantav1alpha1.GeneratorConfig) string {
+	types := make([]string, len(generators))
+	for i, gen := range generators {
+		types[i] = gen.Type
+	}
+	return strings.Join(types, ",")
+}
+
+// calculateTemplateChecksum creates a SHA256 checksum of the template
+func calculateTemplateChecksum(template string) string {
+	hash := sha256.Sum256([]byte(template))
+	return fmt.Sprintf("%x", hash)[:16]
+}
+
+// apiError is an error response of an OCI API
+type apiError struct {
+	status int
+	body   []byte
+}
+
+func (e *apiError) Error() string {
+	var response struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(e.body, &response); err == nil && response.Code != "" {
+		return fmt.Sprintf("OCI returned %d %s: %s", e.status, response.Code, response.Message)
+	}
+	return fmt.Sprintf("OCI returned %d", e.status)
+}
+
+// client signs requests to the vault secrets API
+type client struct {
+	http     *http.Client
+	endpoint string
+	keys     keyProvider
+}
+
+func (m *OCIVaultMedia) newClient() (*client, error) {
+	httpClient := m.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: requestTimeout}
+	}
+	endpoint := m.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://vaults.%s.oci.oraclecloud.com", m.Region)
+	}
+
+	var keys keyProvider
+	switch m.AuthMethod {
+	case "", "instance_principal":
+		metadataURL, authURL := m.metadataURL, m.authURL
+		if metadataURL == "" {
+			metadataURL = metadataEndpoint
+		}
+		if authURL == "" {
+			authURL = fmt.Sprintf("https://auth.%s.oraclecloud.com", m.Region)
+		}
+		keys = newInstancePrincipal(httpClient, metadataURL, authURL)
+	case "workload_identity":
+		principal, err := newWorkloadIdentity(m.workloadIdentityURL, m.serviceAccountTokenFile, m.serviceAccountCAFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = principal
+	case "user_principal":
+		principal, err := newUserPrincipal(m.TenancyID, m.UserID, m.Fingerprint, m.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = principal
+	default:
+		return nil, fmt.Errorf("unsupported OCI auth method: %s", m.AuthMethod)
+	}
+	return &client{http: httpClient, endpoint: strings.TrimSuffix(endpoint, "/"), keys: keys}, nil
+}
+
+// findSecret returns the OCID of the named secret in the vault
+func (c *client) findSecret(ctx context.Context, compartmentID, vaultID, name string) (string, error) {
+	query := url.Values{"compartmentId": {compartmentID}, "vaultId": {vaultID}, "name": {name}}
+	var secrets []struct {
+		ID             string `json:"id"`
+		SecretName     string `json:"secretName"`
+		LifecycleState string `json:"lifecycleState"`
+	}
+	if err := c.do(ctx, http.MethodGet, secretsAPI, query, nil, &secrets); err != nil {
+		return "", err
+	}
+	for _, secret := range secrets {
+		if secret.SecretName != name {
+			continue
+		}
+		switch secret.LifecycleState {
+		case "DELETED":
+			continue
+		case "PENDING_DELETION", "SCHEDULING_DELETION", "DELETING":
+			return "", errSecretUnavailable
+		}
+		return secret.ID, nil
+	}
+	return "", errSecretNotFound
+}
+
+// do sends a signed request and decodes the response into out if it is not nil
+func (c *client) do(ctx context.Context, method, path string, query url.Values, body interface{}, out interface{}) error {
+	target := c.endpoint + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	keyID, key, err := c.keys.signingKey(ctx)
+	if err != nil {
+		return err
+	}
+	if err := signRequest(req, encoded, keyID, key); err != nil {
+		return err
+	}
+
+	payload, err := send(c.http, req)
+	if err != nil {
+		return err
+	}
+	if out != nil && len(payload) > 0 {
+		if err := json.Unmarshal(payload, out); err != nil {
+			return fmt.Errorf("failed to decode OCI response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package oci
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+)
+
+const (
+	testCompartment = "ocid1.compartment.oc1..aaaatest"
+	testVault       = "ocid1.vault.oc1.iad.bbbbtest"
+	testKey         = "ocid1.key.oc1.iad.bbbbtest.cccctest"
+)
+
+var authorizationRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// fakeOCI is a stand-in for the vault secrets API, the instance metadata service, the federation
+// endpoint and the OKE workload identity proxy. Requests must be signed by a known key.
+type fakeOCI struct {
+	t       *testing.T
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	secrets map[string]*fakeSecret
+
+	instanceCert, instanceIntermediate, instanceKey []byte
+}
+
+type fakeSecret struct {
+	id       string
+	versions []string
+	tags     map[string]string
+}
+
+func newFakeOCI(t *testing.T) *fakeOCI {
+	return &fakeOCI{t: t, keys: map[string]*rsa.PublicKey{}, secrets: map[string]*fakeSecret{}}
+}
+
+// verify checks the OCI signature of a request and returns its key ID
+func (f *fakeOCI) verify(r *http.Request, body []byte) (string, error) {
+	params := map[string]string{}
+	for _, match := range authorizationRegex.FindAllStringSubmatch(r.Header.Get("Authorization"), -1) {
+		params[match[1]] = match[2]
+	}
+	key, ok := f.keys[params["keyId"]]
+	if !ok {
+		return "", fmt.Errorf("unknown key %q", params["keyId"])
+	}
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		sum := sha256.Sum256(body)
+		if r.Header.Get("X-Content-Sha256") != base64.StdEncoding.EncodeToString(sum[:]) {
+			return "", fmt.Errorf("body hash mismatch")
+		}
+		if !strings.Contains(params["headers"], "x-content-sha256") {
+			return "", fmt.Errorf("body is not signed")
+		}
+	}
+	signature, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return "", err
+	}
+	r.URL.Host = r.Host
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, signingDigest(r, strings.Split(params["headers"], " ")), signature); err != nil {
+		return "", fmt.Errorf("invalid signature: %w", err)
+	}
+	return params["keyId"], nil
+}
+
+func (f *fakeOCI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, err := io.ReadAll(r.Body)
+	require.NoError(f.t, err)
+
+	fail := func(status int, code, message string) {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]string{"code": code, "message": message})
+	}
+	addSessionKey := func(keyID, publicKey string) {
+		der, err := base64.StdEncoding.DecodeString(publicKey)
+		require.NoError(f.t, err)
+		key, err := x509.ParsePKIXPublicKey(der)
+		require.NoError(f.t, err)
+		f.keys[keyID] = key.(*rsa.PublicKey)
+	}
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/opc/v2/identity/"):
+		if r.Header.Get("Authorization") != "Bearer Oracle" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		files := map[string][]byte{"cert.pem": f.instanceCert, "intermediate.pem": f.instanceIntermediate, "key.pem": f.instanceKey}
+		_, _ = w.Write(files[strings.TrimPrefix(r.URL.Path, "/opc/v2/identity/")])
+		return
+	case r.URL.Path == "/v1/x509":
+		if _, err := f.verify(r, body); err != nil {
+			fail(http.StatusUnauthorized, "NotAuthenticated", err.Error())
+			return
+		}
+		var request map[string]interface{}
+		require.NoError(f.t, json.Unmarshal(body, &request))
+		addSessionKey("ST$instance-token", request["publicKey"].(string))
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "instance-token"})
+		return
+	case r.URL.Path == "/resourcePrincipalSessionTokens":
+		if r.Header.Get("Authorization") != "Bearer sa-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var request map[string]string
+		require.NoError(f.t, json.Unmarshal(body, &request))
+		addSessionKey("ST$workload-token", request["podKey"])
+		payload, _ := json.Marshal(map[string]string{"token": "ST$workload-token"})
+		_, _ = fmt.Fprintf(w, "%q", base64.StdEncoding.EncodeToString(payload))
+		return
+	}
+
+	if _, err := f.verify(r, body); err != nil {
+		fail(http.StatusUnauthorized, "NotAuthenticated", err.Error())
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == secretsAPI:
+		query := r.URL.Query()
+		summaries := []map[string]string{}
+		if query.Get("compartmentId") == testCompartment && query.Get("vaultId") == testVault {
+			if secret, ok := f.secrets[query.Get("name")]; ok {
+				summaries = append(summaries, map[string]string{"id": secret.id, "secretName": query.Get("name"), "lifecycleState": "ACTIVE"})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(summaries)
+	case r.Method == http.MethodPost && r.URL.Path == secretsAPI:
+		var request struct {
+			SecretName    string            `json:"secretName"`
+			KeyID         string            `json:"keyId"`
+			FreeformTags  map[string]string `json:"freeformTags"`
+			SecretContent map[string]string `json:"secretContent"`
+		}
+		require.NoError(f.t, json.Unmarshal(body, &request))
+		if _, exists := f.secrets[request.SecretName]; exists {
+			fail(http.StatusConflict, "Conflict", "secret already exists")
+			return
+		}
+		if request.KeyID != testKey || request.SecretContent["contentType"] != "BASE64" {
+			fail(http.StatusBadRequest, "InvalidParameter", "invalid secret")
+			return
+		}
+		f.secrets[request.SecretName] = &fakeSecret{
+			id:       "ocid1.vaultsecret.oc1.iad." + strings.ToLower(strings.ReplaceAll(request.SecretName, "-", "")),
+			versions: []string{request.SecretContent["content"]},
+			tags:     request.FreeformTags,
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id": f.secrets[request.SecretName].id})
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, secretsAPI+"/"):
+		var request struct {
+			SecretContent map[string]string `json:"secretContent"`
+		}
+		require.NoError(f.t, json.Unmarshal(body, &request))
+		for _, secret := range f.secrets {
+			if secret.id == strings.TrimPrefix(r.URL.Path, secretsAPI+"/") {
+				secret.versions = append(secret.versions, request.SecretContent["content"])
+				_ = json.NewEncoder(w).Encode(map[string]string{"id": secret.id})
+				return
+			}
+		}
+		fail(http.StatusNotFound, "NotAuthorizedOrNotFound", "secret not found")
+	default:
+		fail(http.StatusNotFound, "NotFound", r.URL.Path)
+	}
+}
+
+func (f *fakeOCI) version(name string, i int) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	decoded, _ := base64.StdEncoding.DecodeString(f.secrets[name].versions[i])
+	return string(decoded)
+}
+
+func newSecretSanta() *secretsantav1alpha1.SecretSanta {
+	return &secretsantav1alpha1.SecretSanta{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "prod"},
+		Spec: secretsantav1alpha1.SecretSantaSpec{
+			Template:    "{{ .pass.value }}",
+			Labels:      map[string]string{"app.kubernetes.io/name": "db"},
+			Generators:  []secretsantav1alpha1.GeneratorConfig{{Name: "pass", Type: "random_password"}},
+			Annotations: map[string]string{"team": "payments"},
+		},
+	}
+}
+
+func newMedia(endpoint string) *OCIVaultMedia {
+	return &OCIVaultMedia{
+		Region:        "us-ashburn-1",
+		CompartmentID: testCompartment,
+		VaultID:       testVault,
+		KeyID:         testKey,
+		Endpoint:      endpoint,
+	}
+}
+
+func TestOCIVaultMedia_GetType(t *testing.T) {
+	media := &OCIVaultMedia{}
+	assert.Equal(t, "oci-vault", media.GetType())
+}
+
+func TestOCIVaultMedia_UserPrincipal(t *testing.T) {
+	fake := newFakeOCI(t)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	fake.keys["ocid1.tenancy.oc1..t/ocid1.user.oc1..u/aa:bb"] = &key.PublicKey
+
+	media := newMedia(server.URL)
+	media.AuthMethod = "user_principal"
+	media.TenancyID = "ocid1.tenancy.oc1..t"
+	media.UserID = "ocid1.user.oc1..u"
+	media.Fingerprint = "aa:bb"
+	media.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	ctx := context.Background()
+
+	require.NoError(t, media.Store(ctx, newSecretSanta(), "first", true))
+	require.Contains(t, fake.secrets, "prod-db")
+	assert.Equal(t, "first", fake.version("prod-db", 0))
+	tags := fake.secrets["prod-db"].tags
+	assert.Equal(t, "db", tags["app_kubernetes_io_name"])
+	assert.Equal(t, "payments", tags["team"])
+	assert.Equal(t, "prod/db", tags[tagKeySourceCR])
+	assert.Equal(t, "random_password", tags[tagKeyGeneratorTypes])
+
+	// Create-once: a second Store does not add a version
+	require.NoError(t, media.Store(ctx, newSecretSanta(), "second", true))
+	assert.Len(t, fake.secrets["prod-db"].versions, 1)
+
+	require.NoError(t, media.Rotate(ctx, newSecretSanta(), "rotated", true))
+	require.Len(t, fake.secrets["prod-db"].versions, 2)
+	assert.Equal(t, "rotated", fake.version("prod-db", 1))
+
+	// Requests signed with another key are rejected
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	media.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(other)}))
+	err = media.Store(ctx, newSecretSanta(), "value", true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "NotAuthenticated")
+}
+
+func TestOCIVaultMedia_InstancePrincipal(t *testing.T) {
+	fake := newFakeOCI(t)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	instanceKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ocid1.instance.oc1.iad.i", OrganizationalUnit: []string{"opc-certtype:instance", "opc-tenant:ocid1.tenancy.oc1..t"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &instanceKey.PublicKey, instanceKey)
+	require.NoError(t, err)
+	fake.instanceCert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	fake.instanceIntermediate = fake.instanceCert
+	fake.instanceKey = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(instanceKey)})
+	fake.keys["ocid1.tenancy.oc1..t/fed-x509/"+fingerprint(der)] = &instanceKey.PublicKey
+
+	media := newMedia(server.URL)
+	media.metadataURL = server.URL + "/opc/v2"
+	media.authURL = server.URL
+
+	require.NoError(t, media.Store(context.Background(), newSecretSanta(), "value", false))
+	assert.Equal(t, "value", fake.version("prod-db", 0))
+	assert.Empty(t, fake.secrets["prod-db"].tags[tagKeySourceCR])
+}
+
+func TestOCIVaultMedia_WorkloadIdentity(t *testing.T) {
+	fake := newFakeOCI(t)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	proxy := httptest.NewTLSServer(fake)
+	defer proxy.Close()
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("sa-token"), 0o600))
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: proxy.Certificate().Raw}), 0o600))
+
+	media := newMedia(server.URL)
+	media.AuthMethod = "workload_identity"
+	media.workloadIdentityURL = proxy.URL
+	media.serviceAccountTokenFile = tokenFile
+	media.serviceAccountCAFile = caFile
+
+	require.NoError(t, media.Store(context.Background(), newSecretSanta(), "value", true))
+	assert.Equal(t, "value", fake.version("prod-db", 0))
+}
+
+func TestOCIVaultMedia_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(m *OCIVaultMedia)
+		wantErr string
+	}{
+		{name: "missing region", modify: func(m *OCIVaultMedia) { m.Region = "" }, wantErr: "region"},
+		{name: "invalid compartment", modify: func(m *OCIVaultMedia) { m.CompartmentID = "compartment" }, wantErr: "compartment_id"},
+		{name: "missing key", modify: func(m *OCIVaultMedia) { m.KeyID = "" }, wantErr: "key_id"},
+		{name: "invalid secret name", modify: func(m *OCIVaultMedia) { m.SecretName = "db/password" }, wantErr: "invalid OCI secret name"},
+		{name: "unknown auth method", modify: func(m *OCIVaultMedia) { m.AuthMethod = "api_key" }, wantErr: "unsupported OCI auth method"},
+		{name: "incomplete user principal", modify: func(m *OCIVaultMedia) { m.AuthMethod = "user_principal" }, wantErr: "required for user_principal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			media := newMedia("http://127.0.0.1:0")
+			tt.modify(media)
+			err := media.Store(context.Background(), newSecretSanta(), "value", false)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package media
+
+import (
+	"fmt"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+)
+
+const (
+	// OwnerAnnotation holds the namespace/name of the SecretSanta that created a Kubernetes object.
+	// It is set even when metadata is disabled, as existing objects are only reused or replaced when
+	// they carry it.
+	OwnerAnnotation = "secrets.secret-santa.io/owner"
+	// SourceCRAnnotation is the metadata annotation with the namespace/name of the SecretSanta
+	SourceCRAnnotation = "secrets.secret-santa.io/source-cr"
+)
+
+// Owner returns the value of the owner annotation for a SecretSanta
+func Owner(secretSanta *secretsantav1alpha1.SecretSanta) string {
+	return fmt.Sprintf("%s/%s", secretSanta.Namespace, secretSanta.Name)
+}
+
+// IsOwnedBy reports whether an object with the given annotations was created for the SecretSanta.
+// Objects created before the owner annotation existed are recognized by their source-cr annotation.
+func IsOwnedBy(annotations map[string]string, secretSanta *secretsantav1alpha1.SecretSanta) bool {
+	owner := Owner(secretSanta)
+	return annotations[OwnerAnnotation] == owner || annotations[SourceCRAnnotation] == owner
+}
+
+// NotOwnedError reports an existing object of the same name that was not created for the SecretSanta
+func NotOwnedError(kind, namespace, name string, secretSanta *secretsantav1alpha1.SecretSanta) error {
+	return fmt.Errorf("%s %s/%s already exists and is not managed by SecretSanta %s", kind, namespace, name, Owner(secretSanta))
+}
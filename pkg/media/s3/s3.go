@@ -0,0 +1,355 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+)
+
+const (
+	tagKeyCreatedAt        = "secrets.secret-santa.io/created-at"
+	tagKeyGeneratorTypes   = "secrets.secret-santa.io/generator-types"
+	tagKeyTemplateChecksum = "secrets.secret-santa.io/template-checksum"
+	tagKeySourceCR         = "secrets.secret-santa.io/source-cr"
+
+	// DefaultKey stores objects per namespace in the bucket
+	DefaultKey = "{{ .Namespace }}/{{ .SecretName }}"
+	// maxObjectTags is the S3 limit of tags per object
+	maxObjectTags  = 10
+	requestTimeout = 30 * time.Second
+)
+
+// S3ObjectMedia stores secrets as objects in S3 or S3-compatible storage such as MinIO,
+// DigitalOcean Spaces, Linode or Vultr Object Storage
+type S3ObjectMedia struct {
+	// Endpoint overrides the AWS S3 endpoint of the region, e.g. https://nyc3.digitaloceanspaces.com
+	Endpoint string
+	Region   string
+	// Bucket and Key are templates with .Namespace, .Name and .SecretName
+	Bucket string
+	Key    string
+	// ForcePathStyle addresses buckets as endpoint/bucket instead of bucket.endpoint
+	ForcePathStyle bool
+	ContentType    string
+
+	// AccessKeyID and SecretAccessKey are static credentials
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// AmbientCredentials uses the AWS default credential chain of the operator when no static
+	// credentials are set. It is an operator setting and only applies to the AWS endpoints.
+	AmbientCredentials bool
+
+	// SSE is AES256 (SSE-S3), aws:kms (SSE-KMS) or SSE-C
+	SSE      string
+	KMSKeyID string
+	// CustomerKey is the base64 encoded 256-bit key for SSE-C
+	CustomerKey string
+
+	// HTTPClient overrides the client used for requests
+	HTTPClient *http.Client
+}
+
+func (m *S3ObjectMedia) Store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	return m.store(ctx, secretSanta, data, enableMetadata, true)
+}
+
+// Rotate overwrites the object. Versioned buckets keep the previous data as a noncurrent version.
+func (m *S3ObjectMedia) Rotate(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	return m.store(ctx, secretSanta, data, enableMetadata, false)
+}
+
+func (m *S3ObjectMedia) GetType() string {
+	return "s3-object"
+}
+
+func (m *S3ObjectMedia) store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool, createOnce bool) error {
+	region := m.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	bucket, err := renderName("bucket", m.Bucket, secretSanta)
+	if err != nil {
+		return err
+	}
+	if err := validateBucket(bucket); err != nil {
+		return err
+	}
+	keyTemplate := m.Key
+	if keyTemplate == "" {
+		keyTemplate = DefaultKey
+	}
+	key, err := renderName("key", keyTemplate, secretSanta)
+	if err != nil {
+		return err
+	}
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	objectURL, err := m.objectURL(region, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	body := []byte(data)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	contentType := m.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	req.Header.Set("Content-Type", contentType)
+	if createOnce {
+		// The write fails with 412 if the key exists, so concurrent writers cannot overwrite it
+		req.Header.Set("If-None-Match", "*")
+	}
+	if err := m.setEncryptionHeaders(req.Header, objectURL); err != nil {
+		return err
+	}
+	tagging, err := createTagging(secretSanta, enableMetadata)
+	if err != nil {
+		return err
+	}
+	if tagging != "" {
+		req.Header.Set("X-Amz-Tagging", tagging)
+	}
+
+	if err := m.sign(ctx, req, body, region); err != nil {
+		return err
+	}
+	httpClient := m.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: requestTimeout}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to put object %s/%s: %w", bucket, key, err)
+	}
+	defer resp.Body.Close()
+	payload, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case createOnce && resp.StatusCode == http.StatusPreconditionFailed:
+		return nil // Object already exists, which is fine for create-once policy
+	default:
+		return fmt.Errorf("failed to put object %s/%s: %s", bucket, key, s3Error(resp.StatusCode, payload))
+	}
+}
+
+// objectURL addresses the object with virtual-hosted or path-style URLs
+func (m *S3ObjectMedia) objectURL(region, bucket, key string) (*url.URL, error) {
+	endpoint := m.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	escapedKey := strings.Join(segments, "/")
+
+	u := &url.URL{Scheme: base.Scheme, Host: base.Host}
+	if m.ForcePathStyle || strings.Contains(bucket, ".") {
+		u.RawPath = "/" + bucket + "/" + escapedKey
+		u.Path = "/" + bucket + "/" + key
+	} else {
+		u.Host = bucket + "." + base.Host
+		u.RawPath = "/" + escapedKey
+		u.Path = "/" + key
+	}
+	return u, nil
+}
+
+// setEncryptionHeaders requests server-side encryption of the object
+func (m *S3ObjectMedia) setEncryptionHeaders(header http.Header, objectURL *url.URL) error {
+	switch m.SSE {
+	case "":
+		if m.KMSKeyID != "" || m.CustomerKey != "" {
+			return fmt.Errorf("kms_key_id and sse_customer_key require sse")
+		}
+	case "AES256":
+		header.Set("X-Amz-Server-Side-Encryption", "AES256")
+	case "aws:kms":
+		header.Set("X-Amz-Server-Side-Encryption", "aws:kms")
+		if m.KMSKeyID != "" {
+			header.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", m.KMSKeyID)
+		}
+	case "SSE-C":
+		// The key is sent with every request, so it must not travel in plaintext
+		if objectURL.Scheme != "https" {
+			return fmt.Errorf("SSE-C requires an https endpoint")
+		}
+		customerKey, err := base64.StdEncoding.DecodeString(m.CustomerKey)
+		if err != nil || len(customerKey) != 32 {
+			return fmt.Errorf("sse_customer_key must be a base64 encoded 256-bit key")
+		}
+		sum := md5.Sum(customerKey)
+		header.Set("X-Amz-Server-Side-Encryption-Customer-Algorithm", "AES256")
+		header.Set("X-Amz-Server-Side-Encryption-Customer-Key", m.CustomerKey)
+		header.Set("X-Amz-Server-Side-Encryption-Customer-Key-Md5", base64.StdEncoding.EncodeToString(sum[:]))
+	default:
+		return fmt.Errorf("unsupported sse %q, must be AES256, aws:kms or SSE-C", m.SSE)
+	}
+	return nil
+}
+
+// sign adds the AWS Signature Version 4 to req
+func (m *S3ObjectMedia) sign(ctx context.Context, req *http.Request, body []byte, region string) error {
+	var credentials aws.Credentials
+	if m.AccessKeyID != "" || m.SecretAccessKey != "" {
+		if m.AccessKeyID == "" || m.SecretAccessKey == "" {
+			return fmt.Errorf("access_key_id and secret_access_key must be set together")
+		}
+		credentials = aws.Credentials{AccessKeyID: m.AccessKeyID, SecretAccessKey: m.SecretAccessKey, SessionToken: m.SessionToken}
+	} else {
+		if !m.AmbientCredentials {
+			return fmt.Errorf("access_key_id and secret_access_key are required, the operator does not share its AWS credentials")
+		}
+		if m.Endpoint != "" {
+			return fmt.Errorf("access_key_id and secret_access_key are required for a custom endpoint")
+		}
+		cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+		if err != nil {
+			return fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		if credentials, err = cfg.Credentials.Retrieve(ctx); err != nil {
+			return fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+		}
+	}
+
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signer := v4.NewSigner(func(o *v4.SignerOptions) {
+		// S3 signs the path as sent rather than escaping it twice
+		o.DisableURIPathEscaping = true
+	})
+	if err := signer.SignHTTP(ctx, credentials, req, payloadHash, "s3", region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	return nil
+}
+
+// renderName renders a bucket or key template for the SecretSanta
+func renderName(name, text string, secretSanta *secretsantav1alpha1.SecretSanta) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s template: %w", name, err)
+	}
+	secretName := secretSanta.Spec.SecretName
+	if secretName == "" {
+		secretName = secretSanta.Name
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct {
+		Namespace  string
+		Name       string
+		SecretName string
+	}{secretSanta.Namespace, secretSanta.Name, secretName})
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func validateBucket(bucket string) error {
+	if len(bucket) < 3 || len(bucket) > 63 {
+		return fmt.Errorf("bucket name must be between 3 and 63 characters, got: %q", bucket)
+	}
+	for _, c := range bucket {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.') {
+			return fmt.Errorf("invalid bucket name %q", bucket)
+		}
+	}
+	return nil
+}
+
+func validateKey(key string) error {
+	if key == "" || len(key) > 1024 {
+		return fmt.Errorf("object key must be between 1 and 1024 bytes, got: %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid object key %q", key)
+		}
+	}
+	return nil
+}
+
+// createTagging returns the X-Amz-Tagging header for labels and metadata. Annotations are not added
+// because objects are limited to ten tags.
+func createTagging(secretSanta *secretsantav1alpha1.SecretSanta, enableMetadata bool) (string, error) {
+	tags := url.Values{}
+	for k, v := range secretSanta.Spec.Labels {
+		tags.Set(k, v)
+	}
+	if enableMetadata {
+		tags.Set(tagKeyCreatedAt, time.Now().UTC().Format(time.RFC3339))
+		tags.Set(tagKeyGeneratorTypes, getGeneratorTypes(secretSanta.Spec.Generators))
+		tags.Set(tagKeyTemplateChecksum, calculateTemplateChecksum(secretSanta.Spec.Template))
+		tags.Set(tagKeySourceCR, fmt.Sprintf("%s/%s", secretSanta.Namespace, secretSanta.Name))
+	}
+	if len(tags) > maxObjectTags {
+		keys := make([]string, 0, len(tags))
+		for k := range tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return "", fmt.Errorf("objects support at most %d tags, got %d: %s", maxObjectTags, len(tags), strings.Join(keys, ", "))
+	}
+	return tags.Encode(), nil
+}
+
+// getGeneratorTypes extracts generator types from the configuration
+func getGeneratorTypes(generators []secretsantav1alpha1.GeneratorConfig) string {
+	types := make([]string, len(generators))
+	for i, gen := range generators {
+		types[i] = gen.Type
+	}
+	return strings.Join(types, ",")
+}
+
+// calculateTemplateChecksum creates a SHA256 checksum of the template
+func calculateTemplateChecksum(template string) string {
+	hash := sha256.Sum256([]byte(template))
+	return fmt.Sprintf("%x", hash)[:16]
+}
+
+// s3Error formats an S3 XML error response
+func s3Error(status int, payload []byte) string {
+	var response struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.Unmarshal(payload, &response); err == nil && response.Code != "" {
+		return fmt.Sprintf("%d %s: %s", status, response.Code, response.Message)
+	}
+	return fmt.Sprintf("%d %s", status, http.StatusText(status))
+}
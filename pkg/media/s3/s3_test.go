@@ -0,0 +1,339 @@
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+)
+
+// fakeS3 is an in-memory stand-in for the S3 PutObject API
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]string
+	headers map[string]http.Header
+}
+
+func newFakeS3(t *testing.T, tls bool) (*fakeS3, *httptest.Server, *http.Client) {
+	s := &fakeS3{objects: map[string]string{}, headers: map[string]http.Header{}}
+	var server *httptest.Server
+	if tls {
+		server = httptest.NewTLSServer(s)
+	} else {
+		server = httptest.NewServer(s)
+	}
+	t.Cleanup(server.Close)
+
+	// Route virtual-hosted bucket names to the test server
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	return s, server, &http.Client{Transport: transport}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	if r.Method != http.MethodPut ||
+		!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+		r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>bad signature</Message></Error>")
+		return
+	}
+
+	object := r.Host + r.URL.EscapedPath()
+	if _, exists := s.objects[object]; exists && r.Header.Get("If-None-Match") == "*" {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		_, _ = io.WriteString(w, "<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>")
+		return
+	}
+	s.objects[object] = string(body)
+	s.headers[object] = r.Header.Clone()
+}
+
+func (s *fakeS3) object(name string) (string, http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects[name], s.headers[name]
+}
+
+func testSecretSanta() *secretsantav1alpha1.SecretSanta {
+	return &secretsantav1alpha1.SecretSanta{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "default"},
+		Spec: secretsantav1alpha1.SecretSantaSpec{
+			Template:   "{{ .pass.value }}",
+			Labels:     map[string]string{"app": "web"},
+			Generators: []secretsantav1alpha1.GeneratorConfig{{Name: "pass", Type: "random_password"}},
+		},
+	}
+}
+
+func TestS3ObjectMediaStore(t *testing.T) {
+	s, server, client := newFakeS3(t, false)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name           string
+		media          S3ObjectMedia
+		enableMetadata bool
+		object         string
+		headers        map[string]string
+	}{
+		{
+			name:    "virtual-hosted style with default key",
+			media:   S3ObjectMedia{Bucket: "secrets"},
+			object:  "secrets." + host + "/default/test-secret",
+			headers: map[string]string{"Content-Type": "application/octet-stream", "X-Amz-Tagging": "app=web"},
+		},
+		{
+			name:    "path style with templates",
+			media:   S3ObjectMedia{Bucket: "{{ .Namespace }}-secrets", Key: "apps/{{ .Name }}.env", ForcePathStyle: true, ContentType: "text/plain"},
+			object:  host + "/default-secrets/apps/test-secret.env",
+			headers: map[string]string{"Content-Type": "text/plain"},
+		},
+		{
+			name:    "SSE-S3",
+			media:   S3ObjectMedia{Bucket: "secrets", Key: "sse-s3", SSE: "AES256"},
+			object:  "secrets." + host + "/sse-s3",
+			headers: map[string]string{"X-Amz-Server-Side-Encryption": "AES256"},
+		},
+		{
+			name:   "SSE-KMS",
+			media:  S3ObjectMedia{Bucket: "secrets", Key: "sse-kms", SSE: "aws:kms", KMSKeyID: "alias/secrets"},
+			object: "secrets." + host + "/sse-kms",
+			headers: map[string]string{
+				"X-Amz-Server-Side-Encryption":                "aws:kms",
+				"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id": "alias/secrets",
+			},
+		},
+		{
+			name:           "metadata tags",
+			media:          S3ObjectMedia{Bucket: "secrets", Key: "metadata"},
+			enableMetadata: true,
+			object:         "secrets." + host + "/metadata",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			media := tt.media
+			media.Endpoint = server.URL
+			media.AccessKeyID = "AKID"
+			media.SecretAccessKey = "SECRET"
+			media.HTTPClient = client
+
+			err := media.Store(context.Background(), testSecretSanta(), "password123", tt.enableMetadata)
+			require.NoError(t, err)
+
+			data, headers := s.object(tt.object)
+			assert.Equal(t, "password123", data)
+			for k, v := range tt.headers {
+				assert.Equal(t, v, headers.Get(k), k)
+			}
+			if tt.enableMetadata {
+				tags, err := url.ParseQuery(headers.Get("X-Amz-Tagging"))
+				require.NoError(t, err)
+				assert.Equal(t, "web", tags.Get("app"))
+				assert.Equal(t, "random_password", tags.Get(tagKeyGeneratorTypes))
+				assert.Equal(t, "default/test-secret", tags.Get(tagKeySourceCR))
+				assert.NotEmpty(t, tags.Get(tagKeyCreatedAt))
+				assert.Len(t, tags.Get(tagKeyTemplateChecksum), 16)
+			}
+		})
+	}
+}
+
+func TestS3ObjectMediaCreateOnceAndRotate(t *testing.T) {
+	s, server, client := newFakeS3(t, false)
+	media := &S3ObjectMedia{
+		Endpoint:        server.URL,
+		Bucket:          "secrets",
+		ForcePathStyle:  true,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+		HTTPClient:      client,
+	}
+	object := strings.TrimPrefix(server.URL, "http://") + "/secrets/default/test-secret"
+	ss := testSecretSanta()
+
+	require.NoError(t, media.Store(context.Background(), ss, "first", false))
+	require.NoError(t, media.Store(context.Background(), ss, "second", false))
+	data, headers := s.object(object)
+	assert.Equal(t, "first", data)
+	assert.Equal(t, "*", headers.Get("If-None-Match"))
+
+	require.NoError(t, media.Rotate(context.Background(), ss, "rotated", false))
+	data, headers = s.object(object)
+	assert.Equal(t, "rotated", data)
+	assert.Empty(t, headers.Get("If-None-Match"))
+}
+
+func TestS3ObjectMediaSSEC(t *testing.T) {
+	s, server, client := newFakeS3(t, true)
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	encodedKey := base64.StdEncoding.EncodeToString(key)
+	media := &S3ObjectMedia{
+		Endpoint:        server.URL,
+		Bucket:          "secrets",
+		ForcePathStyle:  true,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+		SSE:             "SSE-C",
+		CustomerKey:     encodedKey,
+		HTTPClient:      client,
+	}
+
+	require.NoError(t, media.Store(context.Background(), testSecretSanta(), "password123", false))
+	_, headers := s.object(strings.TrimPrefix(server.URL, "https://") + "/secrets/default/test-secret")
+	assert.Equal(t, "AES256", headers.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm"))
+	assert.Equal(t, encodedKey, headers.Get("X-Amz-Server-Side-Encryption-Customer-Key"))
+	assert.NotEmpty(t, headers.Get("X-Amz-Server-Side-Encryption-Customer-Key-Md5"))
+	assert.Empty(t, headers.Get("X-Amz-Server-Side-Encryption"))
+}
+
+func TestS3ObjectMediaErrors(t *testing.T) {
+	_, server, client := newFakeS3(t, false)
+	tooManyLabels := testSecretSanta()
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		tooManyLabels.Spec.Labels[k] = k
+	}
+
+	tests := []struct {
+		name           string
+		media          S3ObjectMedia
+		secretSanta    *secretsantav1alpha1.SecretSanta
+		enableMetadata bool
+		wantErr        string
+	}{
+		{
+			name:    "missing bucket",
+			media:   S3ObjectMedia{},
+			wantErr: "bucket name must be between 3 and 63 characters",
+		},
+		{
+			name:    "invalid bucket",
+			media:   S3ObjectMedia{Bucket: "My_Bucket"},
+			wantErr: "invalid bucket name",
+		},
+		{
+			name:    "key traversal",
+			media:   S3ObjectMedia{Bucket: "secrets", Key: "../{{ .Name }}"},
+			wantErr: "invalid object key",
+		},
+		{
+			name:    "unknown template field",
+			media:   S3ObjectMedia{Bucket: "secrets", Key: "{{ .Missing }}"},
+			wantErr: "failed to render key template",
+		},
+		{
+			name:    "unsupported sse",
+			media:   S3ObjectMedia{Bucket: "secrets", SSE: "aws:kms:dsse"},
+			wantErr: "unsupported sse",
+		},
+		{
+			name:    "kms key without sse",
+			media:   S3ObjectMedia{Bucket: "secrets", KMSKeyID: "alias/secrets"},
+			wantErr: "require sse",
+		},
+		{
+			name:    "SSE-C over http",
+			media:   S3ObjectMedia{Bucket: "secrets", SSE: "SSE-C", CustomerKey: base64.StdEncoding.EncodeToString(make([]byte, 32))},
+			wantErr: "SSE-C requires an https endpoint",
+		},
+		{
+			name:           "too many tags",
+			media:          S3ObjectMedia{Bucket: "secrets"},
+			secretSanta:    tooManyLabels,
+			enableMetadata: true,
+			wantErr:        "at most 10 tags",
+		},
+		{
+			name:    "partial credentials",
+			media:   S3ObjectMedia{Bucket: "secrets", AccessKeyID: "AKID"},
+			wantErr: "must be set together",
+		},
+		{
+			name:    "rejected credentials",
+			media:   S3ObjectMedia{Bucket: "secrets", AccessKeyID: "OTHER", SecretAccessKey: "SECRET"},
+			wantErr: "403 SignatureDoesNotMatch: bad signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			media := tt.media
+			media.Endpoint = server.URL
+			media.HTTPClient = client
+			if media.AccessKeyID == "" {
+				media.AccessKeyID, media.SecretAccessKey = "AKID", "SECRET"
+			}
+			secretSanta := tt.secretSanta
+			if secretSanta == nil {
+				secretSanta = testSecretSanta()
+			}
+
+			err := media.Store(context.Background(), secretSanta, "password123", tt.enableMetadata)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestS3ObjectMediaAmbientCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		media   S3ObjectMedia
+		wantErr string
+	}{
+		{
+			name:    "disabled",
+			media:   S3ObjectMedia{},
+			wantErr: "the operator does not share its AWS credentials",
+		},
+		{
+			name:    "custom endpoint",
+			media:   S3ObjectMedia{AmbientCredentials: true, Endpoint: "https://minio.example.com"},
+			wantErr: "required for a custom endpoint",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, "https://secrets.s3.amazonaws.com/db", nil)
+			require.NoError(t, err)
+			err = tt.media.sign(context.Background(), req, nil, "us-east-1")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestS3ObjectMediaGetType(t *testing.T) {
+	assert.Equal(t, "s3-object", (&S3ObjectMedia{}).GetType())
+}
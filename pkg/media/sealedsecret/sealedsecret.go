@@ -0,0 +1,300 @@
+package sealedsecret
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+	"github.com/logicIQ/secret-santa/pkg/media"
+)
+
+const (
+	ScopeStrict        = "strict"
+	ScopeNamespaceWide = "namespace-wide"
+	ScopeClusterWide   = "cluster-wide"
+
+	namespaceWideAnnotation = "sealedsecrets.bitnami.com/namespace-wide"
+	clusterWideAnnotation   = "sealedsecrets.bitnami.com/cluster-wide"
+
+	// sessionKeyBytes is the AES-256 key size of the sealed secrets hybrid encryption
+	sessionKeyBytes = 32
+)
+
+// SealedSecretGVK is the kind created by the media
+var SealedSecretGVK = schema.GroupVersionKind{Group: "bitnami.com", Version: "v1alpha1", Kind: "SealedSecret"}
+
+// SealedSecretMedia seals secrets with the public certificate of the Sealed Secrets controller and
+// creates a SealedSecret or writes its manifest to another media
+type SealedSecretMedia struct {
+	Client client.Client
+	// Certificate is the PEM encoded certificate of the sealing controller
+	Certificate string
+	// Scope is strict (default), namespace-wide or cluster-wide
+	Scope      string
+	SecretName string
+	// SplitKeys stores each top-level key of a YAML or JSON template output as a separate secret key
+	SplitKeys bool
+	// Output receives the SealedSecret manifest as YAML instead of creating the object
+	Output media.Media
+}
+
+func (m *SealedSecretMedia) Store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	sealed, err := m.buildSealedSecret(secretSanta, data, enableMetadata)
+	if err != nil {
+		return err
+	}
+	if m.Output != nil {
+		manifest, err := yaml.Marshal(sealed.Object)
+		if err != nil {
+			return err
+		}
+		return m.Output.Store(ctx, secretSanta, string(manifest), enableMetadata)
+	}
+
+	if err := m.Client.Create(ctx, sealed); err != nil {
+		if client.IgnoreAlreadyExists(err) != nil {
+			return fmt.Errorf("failed to create sealed secret %s/%s: %w", sealed.GetNamespace(), sealed.GetName(), err)
+		}
+		// An existing SealedSecret is only fine for create-once policy if it was created for this SecretSanta
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(SealedSecretGVK)
+		if err := m.Client.Get(ctx, client.ObjectKeyFromObject(sealed), existing); err != nil {
+			return fmt.Errorf("failed to get sealed secret %s/%s: %w", sealed.GetNamespace(), sealed.GetName(), err)
+		}
+		if !isOwnedBy(existing, secretSanta) {
+			return media.NotOwnedError("SealedSecret", sealed.GetNamespace(), sealed.GetName(), secretSanta)
+		}
+	}
+	return nil
+}
+
+// Rotate reseals the data and replaces the SealedSecret, or rotates the output media
+func (m *SealedSecretMedia) Rotate(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	sealed, err := m.buildSealedSecret(secretSanta, data, enableMetadata)
+	if err != nil {
+		return err
+	}
+	if m.Output != nil {
+		rotator, ok := m.Output.(media.Rotator)
+		if !ok {
+			return fmt.Errorf("output media %s does not support rotation", m.Output.GetType())
+		}
+		manifest, err := yaml.Marshal(sealed.Object)
+		if err != nil {
+			return err
+		}
+		return rotator.Rotate(ctx, secretSanta, string(manifest), enableMetadata)
+	}
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(SealedSecretGVK)
+	if err := m.Client.Get(ctx, client.ObjectKeyFromObject(sealed), existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get sealed secret %s/%s: %w", sealed.GetNamespace(), sealed.GetName(), err)
+		}
+		if err := m.Client.Create(ctx, sealed); err != nil {
+			return fmt.Errorf("failed to create sealed secret %s/%s: %w", sealed.GetNamespace(), sealed.GetName(), err)
+		}
+		return nil
+	}
+
+	if !isOwnedBy(existing, secretSanta) {
+		return media.NotOwnedError("SealedSecret", sealed.GetNamespace(), sealed.GetName(), secretSanta)
+	}
+	existing.SetAnnotations(sealed.GetAnnotations())
+	existing.Object["spec"] = sealed.Object["spec"]
+	if err := m.Client.Update(ctx, existing); err != nil {
+		return fmt.Errorf("failed to update sealed secret %s/%s: %w", sealed.GetNamespace(), sealed.GetName(), err)
+	}
+	return nil
+}
+
+func (m *SealedSecretMedia) GetType() string {
+	return "sealed-secret"
+}
+
+// buildSealedSecret seals each secret key and renders the SealedSecret object
+func (m *SealedSecretMedia) buildSealedSecret(secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) (*unstructured.Unstructured, error) {
+	publicKey, err := parseCertificate(m.Certificate)
+	if err != nil {
+		return nil, err
+	}
+	secretName := m.SecretName
+	if secretName == "" {
+		secretName = secretSanta.Spec.SecretName
+	}
+	if secretName == "" {
+		secretName = secretSanta.Name
+	}
+
+	scopeAnnotations := map[string]interface{}{}
+	var label string
+	switch m.Scope {
+	case "", ScopeStrict:
+		label = fmt.Sprintf("%s/%s", secretSanta.Namespace, secretName)
+	case ScopeNamespaceWide:
+		label = secretSanta.Namespace
+		scopeAnnotations[namespaceWideAnnotation] = "true"
+	case ScopeClusterWide:
+		scopeAnnotations[clusterWideAnnotation] = "true"
+	default:
+		return nil, fmt.Errorf("unsupported scope %q, must be strict, namespace-wide or cluster-wide", m.Scope)
+	}
+
+	values := map[string]string{"data": data}
+	if m.SplitKeys {
+		if values, err = media.ParseSecretKeys(data); err != nil {
+			return nil, err
+		}
+	}
+	encryptedData := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		ciphertext, err := hybridEncrypt(rand.Reader, publicKey, []byte(value), []byte(label))
+		if err != nil {
+			return nil, fmt.Errorf("failed to seal key %s: %w", key, err)
+		}
+		encryptedData[key] = base64.StdEncoding.EncodeToString(ciphertext)
+	}
+
+	// The Secret created by the sealed secrets controller gets the labels, annotations and metadata
+	annotations := map[string]interface{}{}
+	for k, v := range secretSanta.Spec.Annotations {
+		annotations[k] = v
+	}
+	if enableMetadata {
+		annotations["secrets.secret-santa.io/created-at"] = time.Now().UTC().Format(time.RFC3339)
+		annotations["secrets.secret-santa.io/generator-types"] = getGeneratorTypes(secretSanta.Spec.Generators)
+		annotations["secrets.secret-santa.io/template-checksum"] = calculateTemplateChecksum(secretSanta.Spec.Template)
+		annotations[media.SourceCRAnnotation] = media.Owner(secretSanta)
+	}
+	for k, v := range scopeAnnotations {
+		annotations[k] = v
+	}
+	templateMetadata := map[string]interface{}{"name": secretName, "namespace": secretSanta.Namespace}
+	if len(annotations) > 0 {
+		templateMetadata["annotations"] = annotations
+	}
+	if len(secretSanta.Spec.Labels) > 0 {
+		labels := map[string]interface{}{}
+		for k, v := range secretSanta.Spec.Labels {
+			labels[k] = v
+		}
+		templateMetadata["labels"] = labels
+	}
+	template := map[string]interface{}{"metadata": templateMetadata}
+	if secretSanta.Spec.SecretType != "" {
+		template["type"] = secretSanta.Spec.SecretType
+	}
+
+	sealed := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"encryptedData": encryptedData,
+			"template":      template,
+		},
+	}}
+	sealed.SetGroupVersionKind(SealedSecretGVK)
+	sealed.SetName(secretName)
+	sealed.SetNamespace(secretSanta.Namespace)
+	// The controller reads the scope from the annotations of the SealedSecret
+	sealedAnnotations := map[string]string{media.OwnerAnnotation: media.Owner(secretSanta)}
+	for k := range scopeAnnotations {
+		sealedAnnotations[k] = "true"
+	}
+	sealed.SetAnnotations(sealedAnnotations)
+	return sealed, nil
+}
+
+// isOwnedBy reports whether an existing SealedSecret was created for the SecretSanta. SealedSecrets
+// created before the owner annotation existed only carry the source-cr annotation in their template.
+func isOwnedBy(sealed *unstructured.Unstructured, secretSanta *secretsantav1alpha1.SecretSanta) bool {
+	if media.IsOwnedBy(sealed.GetAnnotations(), secretSanta) {
+		return true
+	}
+	templateAnnotations, _, _ := unstructured.NestedStringMap(sealed.Object, "spec", "template", "metadata", "annotations")
+	return media.IsOwnedBy(templateAnnotations, secretSanta)
+}
+
+// parseCertificate returns the RSA public key of a PEM encoded certificate
+func parseCertificate(certificate string) (*rsa.PublicKey, error) {
+	if strings.TrimSpace(certificate) == "" {
+		return nil, fmt.Errorf("certificate is required")
+	}
+	block, _ := pem.Decode([]byte(certificate))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("certificate must be a PEM encoded certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("certificate must have an RSA public key, got %T", cert.PublicKey)
+	}
+	return publicKey, nil
+}
+
+// hybridEncrypt encrypts like kubeseal: a random AES-256-GCM session key encrypts the plaintext and
+// is itself encrypted with RSA-OAEP, labeled with the scope. The output is the 2 byte length of the
+// RSA ciphertext, the RSA ciphertext and the AES-GCM ciphertext.
+func hybridEncrypt(random io.Reader, publicKey *rsa.PublicKey, plaintext, label []byte) ([]byte, error) {
+	sessionKey := make([]byte, sessionKeyBytes)
+	if _, err := io.ReadFull(random, sessionKey); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(label) == 0 {
+		label = nil
+	}
+	rsaCiphertext, err := rsa.EncryptOAEP(sha256.New(), random, publicKey, sessionKey, label)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := make([]byte, 2, 2+len(rsaCiphertext)+len(plaintext)+aead.Overhead())
+	binary.BigEndian.PutUint16(ciphertext, uint16(len(rsaCiphertext)))
+	ciphertext = append(ciphertext, rsaCiphertext...)
+	// The session key is only used once, so a zero nonce is safe
+	zeroNonce := make([]byte, aead.NonceSize())
+	return aead.Seal(ciphertext, zeroNonce, plaintext, nil), nil
+}
+
+// getGeneratorTypes extracts generator types from the configuration
+func getGeneratorTypes(generators []secretsantav1alpha1.GeneratorConfig) string {
+	types := make([]string, len(generators))
+	for i, gen := range generators {
+		types[i] = gen.Type
+	}
+	return strings.Join(types, ",")
+}
+
+// calculateTemplateChecksum creates a SHA256 checksum of the template
+func calculateTemplateChecksum(template string) string {
+	hash := sha256.Sum256([]byte(template))
+	return fmt.Sprintf("%x", hash)[:16]
+}
@@ -0,0 +1,312 @@
+package sealedsecret
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+)
+
+// newCertificate returns a self-signed sealing certificate like the one of the controller
+func newCertificate(t *testing.T, key interface{}, public interface{}) string {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sealed-secret"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, public, key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func newSealingKey(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key, newCertificate(t, key, &key.PublicKey)
+}
+
+// hybridDecrypt decrypts like the sealed secrets controller
+func hybridDecrypt(t *testing.T, key *rsa.PrivateKey, encoded string, label string) string {
+	t.Helper()
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	rsaLen := int(binary.BigEndian.Uint16(ciphertext))
+	var oaepLabel []byte
+	if label != "" {
+		oaepLabel = []byte(label)
+	}
+	sessionKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, ciphertext[2:2+rsaLen], oaepLabel)
+	require.NoError(t, err)
+	block, err := aes.NewCipher(sessionKey)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	plaintext, err := aead.Open(nil, make([]byte, aead.NonceSize()), ciphertext[2+rsaLen:], nil)
+	require.NoError(t, err)
+	return string(plaintext)
+}
+
+func testSecretSanta() *secretsantav1alpha1.SecretSanta {
+	return &secretsantav1alpha1.SecretSanta{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "default"},
+		Spec: secretsantav1alpha1.SecretSantaSpec{
+			Template:   "{{ .pass.value }}",
+			Labels:     map[string]string{"app": "web"},
+			Generators: []secretsantav1alpha1.GeneratorConfig{{Name: "pass", Type: "random_password"}},
+		},
+	}
+}
+
+func newFakeClient() client.Client {
+	return fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build()
+}
+
+func getSealedSecret(t *testing.T, c client.Client, name string) *unstructured.Unstructured {
+	t.Helper()
+	sealed := &unstructured.Unstructured{}
+	sealed.SetGroupVersionKind(SealedSecretGVK)
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, sealed))
+	return sealed
+}
+
+func TestSealedSecretMediaScopes(t *testing.T) {
+	key, certificate := newSealingKey(t)
+
+	tests := []struct {
+		scope      string
+		label      string
+		annotation string
+	}{
+		{scope: "", label: "default/test-secret"},
+		{scope: ScopeStrict, label: "default/test-secret"},
+		{scope: ScopeNamespaceWide, label: "default", annotation: namespaceWideAnnotation},
+		{scope: ScopeClusterWide, label: "", annotation: clusterWideAnnotation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			c := newFakeClient()
+			media := &SealedSecretMedia{Client: c, Certificate: certificate, Scope: tt.scope}
+			require.NoError(t, media.Store(context.Background(), testSecretSanta(), "password123", false))
+
+			sealed := getSealedSecret(t, c, "test-secret")
+			encrypted, _, _ := unstructured.NestedString(sealed.Object, "spec", "encryptedData", "data")
+			assert.Equal(t, "password123", hybridDecrypt(t, key, encrypted, tt.label))
+			if tt.annotation != "" {
+				assert.Equal(t, "true", sealed.GetAnnotations()[tt.annotation])
+			} else {
+				assert.NotContains(t, sealed.GetAnnotations(), namespaceWideAnnotation)
+				assert.NotContains(t, sealed.GetAnnotations(), clusterWideAnnotation)
+			}
+			assert.Equal(t, "default/test-secret", sealed.GetAnnotations()["secrets.secret-santa.io/owner"])
+			labels, _, _ := unstructured.NestedStringMap(sealed.Object, "spec", "template", "metadata", "labels")
+			assert.Equal(t, map[string]string{"app": "web"}, labels)
+		})
+	}
+}
+
+func TestSealedSecretMediaSplitKeysAndMetadata(t *testing.T) {
+	key, certificate := newSealingKey(t)
+	c := newFakeClient()
+	media := &SealedSecretMedia{Client: c, Certificate: certificate, SecretName: "db", SplitKeys: true}
+	ss := testSecretSanta()
+	ss.Spec.SecretType = "kubernetes.io/basic-auth"
+
+	require.NoError(t, media.Store(context.Background(), ss, "username: admin\npassword: s3cret\n", true))
+
+	sealed := getSealedSecret(t, c, "db")
+	encryptedData, _, _ := unstructured.NestedStringMap(sealed.Object, "spec", "encryptedData")
+	require.Len(t, encryptedData, 2)
+	assert.Equal(t, "admin", hybridDecrypt(t, key, encryptedData["username"], "default/db"))
+	assert.Equal(t, "s3cret", hybridDecrypt(t, key, encryptedData["password"], "default/db"))
+	secretType, _, _ := unstructured.NestedString(sealed.Object, "spec", "template", "type")
+	assert.Equal(t, "kubernetes.io/basic-auth", secretType)
+	annotations, _, _ := unstructured.NestedStringMap(sealed.Object, "spec", "template", "metadata", "annotations")
+	assert.Equal(t, "default/test-secret", annotations["secrets.secret-santa.io/source-cr"])
+	assert.Equal(t, "random_password", annotations["secrets.secret-santa.io/generator-types"])
+}
+
+func TestSealedSecretMediaCreateOnceAndRotate(t *testing.T) {
+	key, certificate := newSealingKey(t)
+	c := newFakeClient()
+	media := &SealedSecretMedia{Client: c, Certificate: certificate}
+	ss := testSecretSanta()
+	decrypt := func() string {
+		encrypted, _, _ := unstructured.NestedString(getSealedSecret(t, c, "test-secret").Object, "spec", "encryptedData", "data")
+		return hybridDecrypt(t, key, encrypted, "default/test-secret")
+	}
+
+	require.NoError(t, media.Store(context.Background(), ss, "first", false))
+	require.NoError(t, media.Store(context.Background(), ss, "second", false))
+	assert.Equal(t, "first", decrypt())
+
+	require.NoError(t, media.Rotate(context.Background(), ss, "rotated", false))
+	assert.Equal(t, "rotated", decrypt())
+
+	// A SealedSecret of the same name created for another SecretSanta is neither reused nor replaced
+	other := testSecretSanta()
+	other.Name = "other"
+	other.Spec.SecretName = "test-secret"
+	err := media.Store(context.Background(), other, "foreign", false)
+	assert.ErrorContains(t, err, "SealedSecret default/test-secret already exists and is not managed by SecretSanta default/other")
+	err = media.Rotate(context.Background(), other, "foreign", false)
+	assert.ErrorContains(t, err, "is not managed by SecretSanta default/other")
+	assert.Equal(t, "rotated", decrypt())
+}
+
+func TestSealedSecretMediaLegacyOwner(t *testing.T) {
+	key, certificate := newSealingKey(t)
+	c := newFakeClient()
+	media := &SealedSecretMedia{Client: c, Certificate: certificate}
+	ss := testSecretSanta()
+
+	// SealedSecrets created before the owner annotation are recognized by their template source-cr
+	require.NoError(t, media.Store(context.Background(), ss, "first", true))
+	sealed := getSealedSecret(t, c, "test-secret")
+	sealed.SetAnnotations(nil)
+	require.NoError(t, c.Update(context.Background(), sealed))
+
+	require.NoError(t, media.Rotate(context.Background(), ss, "rotated", true))
+	encrypted, _, _ := unstructured.NestedString(getSealedSecret(t, c, "test-secret").Object, "spec", "encryptedData", "data")
+	assert.Equal(t, "rotated", hybridDecrypt(t, key, encrypted, "default/test-secret"))
+}
+
+// recordingMedia records the data stored by the sealed secret media
+type recordingMedia struct {
+	stored  string
+	rotated string
+}
+
+func (m *recordingMedia) Store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	m.stored = data
+	return nil
+}
+
+func (m *recordingMedia) Rotate(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	m.rotated = data
+	return nil
+}
+
+func (m *recordingMedia) GetType() string {
+	return "recording"
+}
+
+type storeOnlyMedia struct{}
+
+func (m *storeOnlyMedia) Store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	return nil
+}
+
+func (m *storeOnlyMedia) GetType() string {
+	return "store-only"
+}
+
+func TestSealedSecretMediaOutput(t *testing.T) {
+	key, certificate := newSealingKey(t)
+	output := &recordingMedia{}
+	media := &SealedSecretMedia{Certificate: certificate, Scope: ScopeNamespaceWide, Output: output}
+
+	require.NoError(t, media.Store(context.Background(), testSecretSanta(), "password123", false))
+	var manifest struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Metadata   metav1.ObjectMeta
+		Spec       struct {
+			EncryptedData map[string]string `json:"encryptedData"`
+		}
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(output.stored), &manifest))
+	assert.Equal(t, "bitnami.com/v1alpha1", manifest.APIVersion)
+	assert.Equal(t, "SealedSecret", manifest.Kind)
+	assert.Equal(t, "test-secret", manifest.Metadata.Name)
+	assert.Equal(t, "default", manifest.Metadata.Namespace)
+	assert.Equal(t, "password123", hybridDecrypt(t, key, manifest.Spec.EncryptedData["data"], "default"))
+
+	require.NoError(t, media.Rotate(context.Background(), testSecretSanta(), "rotated", false))
+	assert.Contains(t, output.rotated, "kind: SealedSecret")
+
+	media.Output = &storeOnlyMedia{}
+	err := media.Rotate(context.Background(), testSecretSanta(), "rotated", false)
+	assert.ErrorContains(t, err, "output media store-only does not support rotation")
+}
+
+func TestSealedSecretMediaErrors(t *testing.T) {
+	_, certificate := newSealingKey(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecCertificate := newCertificate(t, ecKey, &ecKey.PublicKey)
+
+	tests := []struct {
+		name    string
+		media   SealedSecretMedia
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "missing certificate",
+			media:   SealedSecretMedia{},
+			wantErr: "certificate is required",
+		},
+		{
+			name:    "invalid certificate",
+			media:   SealedSecretMedia{Certificate: "-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n"},
+			wantErr: "must be a PEM encoded certificate",
+		},
+		{
+			name:    "non-RSA certificate",
+			media:   SealedSecretMedia{Certificate: ecCertificate},
+			wantErr: "must have an RSA public key",
+		},
+		{
+			name:    "unsupported scope",
+			media:   SealedSecretMedia{Certificate: certificate, Scope: "global"},
+			wantErr: "unsupported scope",
+		},
+		{
+			name:    "invalid split keys",
+			media:   SealedSecretMedia{Certificate: certificate, SplitKeys: true},
+			data:    "not a map",
+			wantErr: "failed to parse template output",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			media := tt.media
+			media.Client = newFakeClient()
+			err := media.Store(context.Background(), testSecretSanta(), tt.data, false)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestSealedSecretMediaGetType(t *testing.T) {
+	assert.Equal(t, "sealed-secret", (&SealedSecretMedia{}).GetType())
+}
@@ -0,0 +1,433 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+	"github.com/logicIQ/secret-santa/pkg/media"
+)
+
+const (
+	tagKeyCreatedAt        = "secrets.secret-santa.io/created-at"
+	tagKeyGeneratorTypes   = "secrets.secret-santa.io/generator-types"
+	tagKeyTemplateChecksum = "secrets.secret-santa.io/template-checksum"
+	tagKeySourceCR         = "secrets.secret-santa.io/source-cr"
+
+	// DefaultPath stores secrets per namespace below the mount
+	DefaultPath = "{{ .Namespace }}/{{ .SecretName }}"
+	// DefaultAudience is the audience of the service account tokens used for Kubernetes auth
+	DefaultAudience = "vault"
+
+	requestTimeout = 30 * time.Second
+	// v1WriteLockCount is the number of locks KV v1 writes are spread over by path
+	v1WriteLockCount = 64
+)
+
+var (
+	// errCASMismatch is returned when a KV v2 check-and-set write finds an existing version
+	errCASMismatch = errors.New("check-and-set parameter did not match the current version")
+	errNotFound    = errors.New("not found")
+
+	// v1WriteLocks serialize KV v1 writes of the operator per path, so concurrent reconciles cannot
+	// both find a secret missing and write it. Writers outside the operator can still race.
+	v1WriteLocks [v1WriteLockCount]sync.Mutex
+)
+
+// VaultKVMedia stores secrets in a HashiCorp Vault KV v1 or v2 secrets engine
+type VaultKVMedia struct {
+	// Address of the Vault server, defaults to VAULT_ADDR
+	Address string
+	// Namespace is the Vault Enterprise namespace
+	Namespace string
+	// Mount is the path of the KV secrets engine (default: secret)
+	Mount string
+	// KVVersion is 1 or 2 (default: 2)
+	KVVersion int
+	// Path is a template for the secret path below the mount with .Namespace, .Name and .SecretName
+	Path string
+	// SplitKeys stores each top-level key of a YAML or JSON template output as a separate field
+	SplitKeys bool
+
+	// AuthMethod is token (default), approle or kubernetes
+	AuthMethod string
+	// AuthMount overrides the mount of the auth method (default: approle or kubernetes)
+	AuthMount string
+	// Token for token auth
+	Token    string
+	RoleID   string
+	SecretID string
+	// Role for Kubernetes auth
+	Role string
+	// ServiceAccountToken is the JWT used for Kubernetes auth. The controller requests it for a
+	// ServiceAccount of the SecretSanta namespace, never the operator's own.
+	ServiceAccountToken string
+	// CACert is a PEM bundle used to verify the Vault server certificate
+	CACert string
+
+	// HTTPClient overrides the client used for requests
+	HTTPClient *http.Client
+}
+
+func (m *VaultKVMedia) Store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	return m.store(ctx, secretSanta, data, enableMetadata, true)
+}
+
+// Rotate writes a new version of the secret (KV v2) or replaces it (KV v1)
+func (m *VaultKVMedia) Rotate(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool) error {
+	return m.store(ctx, secretSanta, data, enableMetadata, false)
+}
+
+func (m *VaultKVMedia) GetType() string {
+	return "vault-kv"
+}
+
+func (m *VaultKVMedia) store(ctx context.Context, secretSanta *secretsantav1alpha1.SecretSanta, data string, enableMetadata bool, createOnce bool) error {
+	kvVersion := m.KVVersion
+	if kvVersion == 0 {
+		kvVersion = 2
+	}
+	if kvVersion != 1 && kvVersion != 2 {
+		return fmt.Errorf("kv_version must be 1 or 2, got: %d", kvVersion)
+	}
+	mount := strings.Trim(m.Mount, "/")
+	if mount == "" {
+		mount = "secret"
+	}
+	secretPath, err := m.resolvePath(secretSanta)
+	if err != nil {
+		return err
+	}
+	fields, err := m.buildFields(data)
+	if err != nil {
+		return err
+	}
+
+	c, err := m.newClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.revoke(ctx)
+
+	if kvVersion == 1 {
+		return m.storeV1(ctx, c, mount, secretPath, fields, createOnce)
+	}
+
+	body := map[string]interface{}{"data": fields}
+	if createOnce {
+		// cas=0 only succeeds if the secret has no versions, so concurrent writers cannot overwrite it
+		body["options"] = map[string]interface{}{"cas": 0}
+	}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("%s/data/%s", mount, secretPath), body, nil); err != nil {
+		if createOnce && errors.Is(err, errCASMismatch) {
+			return nil // Secret already exists, which is fine for create-once policy
+		}
+		return fmt.Errorf("failed to write secret %s/%s: %w", mount, secretPath, err)
+	}
+
+	metadata := buildCustomMetadata(secretSanta, enableMetadata)
+	if len(metadata) > 0 {
+		body := map[string]interface{}{"custom_metadata": metadata}
+		if err := c.do(ctx, http.MethodPost, fmt.Sprintf("%s/metadata/%s", mount, secretPath), body, nil); err != nil {
+			return fmt.Errorf("failed to write metadata of secret %s/%s: %w", mount, secretPath, err)
+		}
+	}
+	return nil
+}
+
+// storeV1 writes a KV v1 secret. KV v1 has no versions or check-and-set, so create-once is a read
+// before the write and custom metadata is not supported.
+func (m *VaultKVMedia) storeV1(ctx context.Context, c *client, mount, secretPath string, fields map[string]string, createOnce bool) error {
+	lock := v1WriteLock(m.Address, m.Namespace, mount, secretPath)
+	lock.Lock()
+	defer lock.Unlock()
+	if createOnce {
+		found := true
+		if err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s/%s", mount, secretPath), nil, nil); err != nil {
+			if !errors.Is(err, errNotFound) {
+				return fmt.Errorf("failed to read secret %s/%s: %w", mount, secretPath, err)
+			}
+			found = false
+		}
+		if found {
+			return nil // Secret already exists, which is fine for create-once policy
+		}
+	}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("%s/%s", mount, secretPath), fields, nil); err != nil {
+		return fmt.Errorf("failed to write secret %s/%s: %w", mount, secretPath, err)
+	}
+	return nil
+}
+
+// v1WriteLock returns the lock of a KV v1 secret
+func v1WriteLock(address, namespace, mount, secretPath string) *sync.Mutex {
+	h := fnv.New32a()
+	for _, part := range []string{address, namespace, mount, secretPath} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return &v1WriteLocks[h.Sum32()%v1WriteLockCount]
+}
+
+// resolvePath renders the path template for the SecretSanta
+func (m *VaultKVMedia) resolvePath(secretSanta *secretsantav1alpha1.SecretSanta) (string, error) {
+	pathTemplate := m.Path
+	if pathTemplate == "" {
+		pathTemplate = DefaultPath
+	}
+	tmpl, err := template.New("path").Option("missingkey=error").Parse(pathTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse path template: %w", err)
+	}
+
+	secretName := secretSanta.Spec.SecretName
+	if secretName == "" {
+		secretName = secretSanta.Name
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct {
+		Namespace  string
+		Name       string
+		SecretName string
+	}{secretSanta.Namespace, secretSanta.Name, secretName})
+	if err != nil {
+		return "", fmt.Errorf("failed to render path template: %w", err)
+	}
+
+	secretPath := strings.Trim(buf.String(), "/")
+	if secretPath == "" {
+		return "", fmt.Errorf("path template rendered an empty path")
+	}
+	for _, segment := range strings.Split(secretPath, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid secret path %q", secretPath)
+		}
+	}
+	return secretPath, nil
+}
+
+// buildFields returns the fields of the KV secret for the template output
+func (m *VaultKVMedia) buildFields(data string) (map[string]string, error) {
+	if !m.SplitKeys {
+		return map[string]string{"data": data}, nil
+	}
+
+	return media.ParseSecretKeys(data)
+}
+
+// buildCustomMetadata returns the KV v2 custom metadata from labels, annotations and metadata tags
+func buildCustomMetadata(secretSanta *secretsantav1alpha1.SecretSanta, enableMetadata bool) map[string]string {
+	metadata := make(map[string]string)
+	for k, v := range secretSanta.Spec.Labels {
+		metadata[k] = v
+	}
+	for k, v := range secretSanta.Spec.Annotations {
+		metadata[k] = v
+	}
+	if enableMetadata {
+		metadata[tagKeyCreatedAt] = time.Now().UTC().Format(time.RFC3339)
+		metadata[tagKeyGeneratorTypes] = getGeneratorTypes(secretSanta.Spec.Generators)
+		metadata[tagKeyTemplateChecksum] = calculateTemplateChecksum(secretSanta.Spec.Template)
+		metadata[tagKeySourceCR] = fmt.Sprintf("%s/%s", secretSanta.Namespace, secretSanta.Name)
+	}
+	return metadata
+}
+
+// getGeneratorTypes extracts generator types from the configuration
+func getGeneratorTypes(generators []secretsantav1alpha1.GeneratorConfig) string {
+	types := make([]string, len(generators))
+	for i, gen := range generators {
+		types[i] = gen.Type
+	}
+	return strings.Join(types, ",")
+}
+
+// calculateTemplateChecksum creates a SHA256 checksum of the template
+func calculateTemplateChecksum(template string) string {
+	hash := sha256.Sum256([]byte(template))
+	return fmt.Sprintf("%x", hash)[:16]
+}
+
+// client is an authenticated connection to the Vault HTTP API
+type client struct {
+	http      *http.Client
+	address   string
+	namespace string
+	token     string
+	// revokable is set for tokens obtained by a login, which are revoked when the media is done
+	revokable bool
+}
+
+func (m *VaultKVMedia) newClient(ctx context.Context) (*client, error) {
+	address := m.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("vault address is required")
+	}
+	parsed, err := url.Parse(address)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid vault address %q", address)
+	}
+
+	httpClient := m.HTTPClient
+	if httpClient == nil {
+		httpClient, err = m.newHTTPClient()
+		if err != nil {
+			return nil, err
+		}
+	}
+	c := &client{
+		http:      httpClient,
+		address:   strings.TrimSuffix(address, "/"),
+		namespace: m.Namespace,
+	}
+
+	switch m.AuthMethod {
+	case "", "token":
+		c.token = m.Token
+		if c.token == "" {
+			return nil, fmt.Errorf("vault token is required for token auth")
+		}
+		return c, nil
+	case "approle":
+		if m.RoleID == "" || m.SecretID == "" {
+			return nil, fmt.Errorf("role_id and secret_id are required for approle auth")
+		}
+		return c, c.login(ctx, authMount(m.AuthMount, "approle"), map[string]interface{}{
+			"role_id":   m.RoleID,
+			"secret_id": m.SecretID,
+		})
+	case "kubernetes":
+		if m.Role == "" {
+			return nil, fmt.Errorf("role is required for kubernetes auth")
+		}
+		if m.ServiceAccountToken == "" {
+			return nil, fmt.Errorf("service account token is required for kubernetes auth")
+		}
+		return c, c.login(ctx, authMount(m.AuthMount, "kubernetes"), map[string]interface{}{
+			"role": m.Role,
+			"jwt":  m.ServiceAccountToken,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported vault auth method: %s", m.AuthMethod)
+	}
+}
+
+func (m *VaultKVMedia) newHTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if m.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(m.CACert)) {
+			return nil, fmt.Errorf("no certificates found in vault ca_cert")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return &http.Client{Transport: transport, Timeout: requestTimeout}, nil
+}
+
+func authMount(mount, defaultMount string) string {
+	mount = strings.Trim(mount, "/")
+	if mount == "" {
+		return defaultMount
+	}
+	return mount
+}
+
+func (c *client) login(ctx context.Context, mount string, body map[string]interface{}) error {
+	var response struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("auth/%s/login", mount), body, &response); err != nil {
+		return fmt.Errorf("failed to log in to vault: %w", err)
+	}
+	if response.Auth.ClientToken == "" {
+		return fmt.Errorf("failed to log in to vault: no client token returned")
+	}
+	c.token = response.Auth.ClientToken
+	c.revokable = true
+	return nil
+}
+
+// revoke revokes a token obtained by login. Errors are ignored, the token expires with its TTL.
+func (c *client) revoke(ctx context.Context) {
+	if c.revokable {
+		_ = c.do(ctx, http.MethodPost, "auth/token/revoke-self", nil, nil)
+	}
+}
+
+// do sends a request to the Vault API and decodes the response into out if it is not nil
+func (c *client) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.address+"/v1/"+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("X-Vault-Token", c.token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(payload, &vaultErr)
+		message := strings.Join(vaultErr.Errors, "; ")
+		switch {
+		case resp.StatusCode == http.StatusNotFound && message == "":
+			return errNotFound
+		case resp.StatusCode == http.StatusBadRequest && strings.Contains(message, "check-and-set"):
+			return errCASMismatch
+		case message == "":
+			message = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("vault returned %d: %s", resp.StatusCode, message)
+	}
+	if out != nil && len(payload) > 0 {
+		if err := json.Unmarshal(payload, out); err != nil {
+			return fmt.Errorf("failed to decode vault response: %w", err)
+		}
+	}
+	return nil
+}
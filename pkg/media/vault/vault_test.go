@@ -0,0 +1,270 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsantav1alpha1 "github.com/logicIQ/secret-santa/api/v1alpha1"
+)
+
+// fakeVault is an in-memory stand-in for the Vault HTTP API with a KV v2 engine at secret/, a KV v1
+// engine at kv/ and approle and kubernetes auth
+type fakeVault struct {
+	mu       sync.Mutex
+	tokens   map[string]bool
+	v2       map[string][]map[string]string
+	metadata map[string]map[string]string
+	v1       map[string]map[string]string
+	v1Writes int
+	revoked  int
+	lastNS   string
+}
+
+func newFakeVault(t *testing.T) (*fakeVault, *httptest.Server) {
+	v := &fakeVault{
+		tokens:   map[string]bool{"root": true},
+		v2:       map[string][]map[string]string{},
+		metadata: map[string]map[string]string{},
+		v1:       map[string]map[string]string{},
+	}
+	server := httptest.NewServer(v)
+	t.Cleanup(server.Close)
+	return v, server
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.lastNS = r.Header.Get("X-Vault-Namespace")
+
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	fail := func(code int, message string) {
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{message}})
+	}
+
+	switch path {
+	case "auth/approle/login":
+		if body["role_id"] != "role" || body["secret_id"] != "s3cret" {
+			fail(http.StatusBadRequest, "invalid role or secret ID")
+			return
+		}
+		v.tokens["approle-token"] = true
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"auth": map[string]string{"client_token": "approle-token"}})
+		return
+	case "auth/k8s/login":
+		if body["role"] != "operator" || body["jwt"] != "sa-jwt" {
+			fail(http.StatusForbidden, "permission denied")
+			return
+		}
+		v.tokens["k8s-token"] = true
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"auth": map[string]string{"client_token": "k8s-token"}})
+		return
+	}
+
+	token := r.Header.Get("X-Vault-Token")
+	if !v.tokens[token] {
+		fail(http.StatusForbidden, "permission denied")
+		return
+	}
+
+	switch {
+	case path == "auth/token/revoke-self":
+		delete(v.tokens, token)
+		v.revoked++
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasPrefix(path, "secret/data/"):
+		key := strings.TrimPrefix(path, "secret/data/")
+		if options, ok := body["options"].(map[string]interface{}); ok {
+			if cas, ok := options["cas"].(float64); ok && int(cas) != len(v.v2[key]) {
+				fail(http.StatusBadRequest, "check-and-set parameter did not match the current version")
+				return
+			}
+		}
+		v.v2[key] = append(v.v2[key], toStrings(body["data"]))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]int{"version": len(v.v2[key])}})
+	case strings.HasPrefix(path, "secret/metadata/"):
+		v.metadata[strings.TrimPrefix(path, "secret/metadata/")] = toStrings(body["custom_metadata"])
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasPrefix(path, "kv/"):
+		key := strings.TrimPrefix(path, "kv/")
+		if r.Method == http.MethodGet {
+			data, ok := v.v1[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[]}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+			return
+		}
+		v.v1[key] = toStrings(body)
+		v.v1Writes++
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		fail(http.StatusNotFound, "no handler for route")
+	}
+}
+
+func toStrings(value interface{}) map[string]string {
+	result := map[string]string{}
+	m, _ := value.(map[string]interface{})
+	for k, v := range m {
+		result[k], _ = v.(string)
+	}
+	return result
+}
+
+func newSecretSanta() *secretsantav1alpha1.SecretSanta {
+	return &secretsantav1alpha1.SecretSanta{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "prod"},
+		Spec: secretsantav1alpha1.SecretSantaSpec{
+			Template:   "{{ .pass.value }}",
+			Labels:     map[string]string{"team": "payments"},
+			Generators: []secretsantav1alpha1.GeneratorConfig{{Name: "pass", Type: "random_password"}},
+		},
+	}
+}
+
+func TestVaultKVMedia_GetType(t *testing.T) {
+	media := &VaultKVMedia{}
+	assert.Equal(t, "vault-kv", media.GetType())
+}
+
+func TestVaultKVMedia_StoreV2(t *testing.T) {
+	vault, server := newFakeVault(t)
+	media := &VaultKVMedia{Address: server.URL, Token: "root", Namespace: "team-a"}
+	ctx := context.Background()
+
+	require.NoError(t, media.Store(ctx, newSecretSanta(), "first", true))
+	require.Len(t, vault.v2["prod/db"], 1)
+	assert.Equal(t, map[string]string{"data": "first"}, vault.v2["prod/db"][0])
+	assert.Equal(t, "team-a", vault.lastNS)
+
+	metadata := vault.metadata["prod/db"]
+	assert.Equal(t, "payments", metadata["team"])
+	assert.Equal(t, "prod/db", metadata[tagKeySourceCR])
+	assert.Equal(t, "random_password", metadata[tagKeyGeneratorTypes])
+	assert.Len(t, metadata[tagKeyTemplateChecksum], 16)
+
+	// Create-once: a second Store leaves the first version in place
+	require.NoError(t, media.Store(ctx, newSecretSanta(), "second", true))
+	assert.Len(t, vault.v2["prod/db"], 1)
+
+	// Rotate adds a version
+	require.NoError(t, media.Rotate(ctx, newSecretSanta(), "rotated", true))
+	require.Len(t, vault.v2["prod/db"], 2)
+	assert.Equal(t, "rotated", vault.v2["prod/db"][1]["data"])
+
+	// Tokens passed in are not revoked
+	assert.Equal(t, 0, vault.revoked)
+}
+
+func TestVaultKVMedia_StoreV1(t *testing.T) {
+	vault, server := newFakeVault(t)
+	media := &VaultKVMedia{Address: server.URL, Token: "root", Mount: "kv", KVVersion: 1, Path: "apps/{{ .Name }}", SplitKeys: true}
+	ctx := context.Background()
+
+	require.NoError(t, media.Store(ctx, newSecretSanta(), "username: app\npassword: one\nport: 5432", false))
+	assert.Equal(t, map[string]string{"username": "app", "password": "one", "port": "5432"}, vault.v1["apps/db"])
+
+	require.NoError(t, media.Store(ctx, newSecretSanta(), "username: app\npassword: two", false))
+	assert.Equal(t, "one", vault.v1["apps/db"]["password"])
+
+	require.NoError(t, media.Rotate(ctx, newSecretSanta(), "username: app\npassword: three", false))
+	assert.Equal(t, "three", vault.v1["apps/db"]["password"])
+}
+
+func TestVaultKVMedia_StoreV1Concurrent(t *testing.T) {
+	vault, server := newFakeVault(t)
+	media := &VaultKVMedia{Address: server.URL, Token: "root", Mount: "kv", KVVersion: 1}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, media.Store(ctx, newSecretSanta(), fmt.Sprintf("password-%d", i), false))
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 1, vault.v1Writes)
+}
+
+func TestVaultKVMedia_Auth(t *testing.T) {
+	tests := []struct {
+		name    string
+		media   VaultKVMedia
+		wantErr string
+	}{
+		{name: "token", media: VaultKVMedia{Token: "root"}},
+		{name: "approle", media: VaultKVMedia{AuthMethod: "approle", RoleID: "role", SecretID: "s3cret"}},
+		{name: "kubernetes", media: VaultKVMedia{AuthMethod: "kubernetes", AuthMount: "k8s", Role: "operator", ServiceAccountToken: "sa-jwt"}},
+		{name: "invalid token", media: VaultKVMedia{Token: "wrong"}, wantErr: "permission denied"},
+		{name: "invalid secret id", media: VaultKVMedia{AuthMethod: "approle", RoleID: "role", SecretID: "wrong"}, wantErr: "failed to log in"},
+		{name: "missing role", media: VaultKVMedia{AuthMethod: "kubernetes"}, wantErr: "role is required"},
+		{name: "missing service account token", media: VaultKVMedia{AuthMethod: "kubernetes", Role: "operator"}, wantErr: "service account token is required"},
+		{name: "missing token", media: VaultKVMedia{}, wantErr: "vault token is required"},
+		{name: "unknown method", media: VaultKVMedia{AuthMethod: "ldap"}, wantErr: "unsupported vault auth method"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault, server := newFakeVault(t)
+			tt.media.Address = server.URL
+			err := tt.media.Store(context.Background(), newSecretSanta(), "value", false)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, vault.v2["prod/db"], 1)
+			if tt.media.AuthMethod != "" {
+				assert.Equal(t, 1, vault.revoked, "login tokens are revoked after use")
+			}
+		})
+	}
+}
+
+func TestVaultKVMedia_ResolvePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", want: "prod/db-credentials"},
+		{name: "custom", path: "/clusters/east/{{ .Namespace }}/{{ .Name }}/", want: "clusters/east/prod/db"},
+		{name: "traversal", path: "{{ .Namespace }}/../other", wantErr: true},
+		{name: "unknown field", path: "{{ .Cluster }}", wantErr: true},
+		{name: "empty", path: "{{ \"\" }}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secretSanta := newSecretSanta()
+			secretSanta.Spec.SecretName = "db-credentials"
+			got, err := (&VaultKVMedia{Path: tt.path}).resolvePath(secretSanta)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
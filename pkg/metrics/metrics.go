@@ -90,6 +90,36 @@ var (
 		[]string{"generator_type"},
 	)
 
+	GeneratorPluginUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: GeneratorSubsystem,
+			Name:      "plugin_up",
+			Help:      "Generator plugin health (1=serving, 0=unavailable)",
+		},
+		[]string{"plugin"},
+	)
+
+	GeneratorPluginTypes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: GeneratorSubsystem,
+			Name:      "plugin_types",
+			Help:      "Generator types registered by a plugin",
+		},
+		[]string{"plugin"},
+	)
+
+	GeneratorPluginRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: GeneratorSubsystem,
+			Name:      "plugin_requests_total",
+			Help:      "Total generator plugin calls",
+		},
+		[]string{"plugin", "method", "status"},
+	)
+
 	KubernetesClientFailTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: Namespace,
@@ -110,6 +140,16 @@ var (
 		[]string{"operation", "status"},
 	)
 
+	RemoteClusterUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: KubernetesClientSubsystem,
+			Name:      "remote_cluster_up",
+			Help:      "Remote cluster health per SecretSanta namespace (1=reachable, 0=failing)",
+		},
+		[]string{"namespace", "cluster"},
+	)
+
 	LastReconciliationTime = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Namespace: Namespace,
@@ -221,8 +261,32 @@ func RecordGeneratorExecution(generatorType, status string) {
 const (
 	StatusSuccess = "success"
 	StatusFailed  = "failed"
+	StatusTimeout = "timeout"
 )
 
+func SetGeneratorPluginUp(plugin string, up bool) {
+	if up {
+		GeneratorPluginUp.WithLabelValues(plugin).Set(1)
+	} else {
+		GeneratorPluginUp.WithLabelValues(plugin).Set(0)
+	}
+}
+
+func SetGeneratorPluginTypes(plugin string, count float64) {
+	GeneratorPluginTypes.WithLabelValues(plugin).Set(count)
+}
+
+func RecordGeneratorPluginRequest(plugin, method, status string) {
+	GeneratorPluginRequestsTotal.WithLabelValues(plugin, method, status).Inc()
+}
+
+// DeleteGeneratorPlugin removes the series of a plugin that is no longer configured
+func DeleteGeneratorPlugin(plugin string) {
+	GeneratorPluginUp.DeleteLabelValues(plugin)
+	GeneratorPluginTypes.DeleteLabelValues(plugin)
+	GeneratorPluginRequestsTotal.DeletePartialMatch(prometheus.Labels{"plugin": plugin})
+}
+
 func RecordKubernetesClientRequest(operation, status string) {
 	KubernetesClientRequestsTotal.WithLabelValues(operation, status).Inc()
 	if status == StatusFailed {
@@ -230,6 +294,19 @@ func RecordKubernetesClientRequest(operation, status string) {
 	}
 }
 
+func SetRemoteClusterUp(namespace, cluster string, up bool) {
+	if up {
+		RemoteClusterUp.WithLabelValues(namespace, cluster).Set(1)
+	} else {
+		RemoteClusterUp.WithLabelValues(namespace, cluster).Set(0)
+	}
+}
+
+// DeleteRemoteCluster removes the health of a cluster that is no longer tracked
+func DeleteRemoteCluster(namespace, cluster string) {
+	RemoteClusterUp.DeleteLabelValues(namespace, cluster)
+}
+
 func RecordLoopDuration(seconds float64) {
 	LoopSecondsTotal.Add(seconds)
 }
@@ -289,8 +366,12 @@ func init() {
 			TemplateValidationFailedTotal,
 			GeneratorExecutionsTotal,
 			GeneratorResponseTime,
+			GeneratorPluginUp,
+			GeneratorPluginTypes,
+			GeneratorPluginRequestsTotal,
 			KubernetesClientFailTotal,
 			KubernetesClientRequestsTotal,
+			RemoteClusterUp,
 			LastReconciliationTime,
 			ReconciliationStatus,
 			ManagedSecretsTotal,
@@ -0,0 +1,130 @@
+// Package shamir implements Shamir's secret sharing over GF(2^8).
+//
+// Each share is the secret-length list of polynomial evaluations followed by a single byte holding
+// the x coordinate, the same layout HashiCorp Vault uses for unseal keys.
+package shamir
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
+const (
+	// MaxShares is the largest number of shares, limited by the non-zero elements of GF(2^8)
+	MaxShares = 255
+)
+
+// Split divides secret into parts shares, any threshold of which recombine to the secret. Polynomial
+// coefficients are read from random, or crypto/rand when random is nil.
+func Split(secret []byte, parts, threshold int, random io.Reader) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("secret cannot be empty")
+	}
+	if parts < 2 || parts > MaxShares {
+		return nil, fmt.Errorf("parts must be between 2 and %d, got: %d", MaxShares, parts)
+	}
+	if threshold < 2 || threshold > parts {
+		return nil, fmt.Errorf("threshold must be between 2 and parts (%d), got: %d", parts, threshold)
+	}
+	if random == nil {
+		random = rand.Reader
+	}
+
+	shares := make([][]byte, parts)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = byte(i + 1)
+	}
+
+	coefficients := make([]byte, threshold)
+	for idx, value := range secret {
+		coefficients[0] = value
+		if _, err := io.ReadFull(random, coefficients[1:]); err != nil {
+			return nil, fmt.Errorf("failed to read random coefficients: %w", err)
+		}
+		for i := range shares {
+			shares[i][idx] = evaluate(coefficients, byte(i+1))
+		}
+	}
+	return shares, nil
+}
+
+// Combine recovers the secret from at least threshold shares produced by Split. Combining fewer
+// shares than the threshold returns a wrong secret rather than an error.
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, fmt.Errorf("at least 2 shares are required, got: %d", len(shares))
+	}
+	length := len(shares[0])
+	if length < 2 {
+		return nil, fmt.Errorf("shares must be at least 2 bytes")
+	}
+
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool, len(shares))
+	for i, share := range shares {
+		if len(share) != length {
+			return nil, fmt.Errorf("all shares must have the same length")
+		}
+		x := share[length-1]
+		if x == 0 {
+			return nil, fmt.Errorf("share %d has an invalid x coordinate", i+1)
+		}
+		if seen[x] {
+			return nil, fmt.Errorf("duplicate share with x coordinate %d", x)
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, length-1)
+	for idx := range secret {
+		var value byte
+		for i := range shares {
+			// Lagrange basis polynomial for share i evaluated at x = 0
+			basis := byte(1)
+			for j := range shares {
+				if i == j {
+					continue
+				}
+				basis = mul(basis, div(xs[j], xs[i]^xs[j]))
+			}
+			value ^= mul(shares[i][idx], basis)
+		}
+		secret[idx] = value
+	}
+	return secret, nil
+}
+
+// evaluate computes the polynomial at x using Horner's method
+func evaluate(coefficients []byte, x byte) byte {
+	result := coefficients[len(coefficients)-1]
+	for i := len(coefficients) - 2; i >= 0; i-- {
+		result = mul(result, x) ^ coefficients[i]
+	}
+	return result
+}
+
+// mul multiplies in GF(2^8) with the AES polynomial x^8 + x^4 + x^3 + x + 1 without branching on
+// secret data
+func mul(a, b byte) byte {
+	var result byte
+	for i := 0; i < 8; i++ {
+		result ^= -(b & 1) & a
+		carry := -(a >> 7) & 0x1b
+		a = a<<1 ^ carry
+		b >>= 1
+	}
+	return result
+}
+
+// div divides in GF(2^8), b must be non-zero
+func div(a, b byte) byte {
+	// b^254 is the multiplicative inverse of b
+	inverse := b
+	for i := 0; i < 6; i++ {
+		inverse = mul(mul(inverse, inverse), b)
+	}
+	return mul(a, mul(inverse, inverse))
+}
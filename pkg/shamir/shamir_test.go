@@ -0,0 +1,138 @@
+package shamir
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("root CA private key material")
+
+	tests := []struct {
+		name      string
+		parts     int
+		threshold int
+	}{
+		{name: "2 of 2", parts: 2, threshold: 2},
+		{name: "3 of 5", parts: 5, threshold: 3},
+		{name: "5 of 5", parts: 5, threshold: 5},
+		{name: "2 of 255", parts: 255, threshold: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, err := Split(secret, tt.parts, tt.threshold, nil)
+			if err != nil {
+				t.Fatalf("Split() error = %v", err)
+			}
+			if len(shares) != tt.parts {
+				t.Fatalf("Split() returned %d shares, want %d", len(shares), tt.parts)
+			}
+
+			// Any threshold consecutive shares recover the secret, in any order
+			for start := 0; start+tt.threshold <= tt.parts; start++ {
+				subset := shares[start : start+tt.threshold]
+				got, err := Combine(subset)
+				if err != nil {
+					t.Fatalf("Combine() error = %v", err)
+				}
+				if !bytes.Equal(got, secret) {
+					t.Fatalf("Combine(shares[%d:%d]) = %q, want %q", start, start+tt.threshold, got, secret)
+				}
+			}
+			reversed := make([][]byte, tt.threshold)
+			for i := range reversed {
+				reversed[i] = shares[tt.parts-1-i]
+			}
+			if got, err := Combine(reversed); err != nil || !bytes.Equal(got, secret) {
+				t.Errorf("Combine(reversed) = %q, %v", got, err)
+			}
+
+			if tt.threshold > 2 {
+				got, err := Combine(shares[:tt.threshold-1])
+				if err == nil && bytes.Equal(got, secret) {
+					t.Error("Combine() recovered the secret below the threshold")
+				}
+			}
+		})
+	}
+}
+
+func TestSplitDeterministic(t *testing.T) {
+	secret := []byte("unseal")
+	first, err := Split(secret, 3, 2, bytes.NewReader(bytes.Repeat([]byte{7}, 64)))
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+	second, err := Split(secret, 3, 2, bytes.NewReader(bytes.Repeat([]byte{7}, 64)))
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+	for i := range first {
+		if !bytes.Equal(first[i], second[i]) {
+			t.Errorf("share %d differs for the same random input", i+1)
+		}
+	}
+
+	if _, err := Split(secret, 3, 2, bytes.NewReader(nil)); err == nil {
+		t.Error("Split() expected error for exhausted random reader")
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    []byte
+		parts     int
+		threshold int
+	}{
+		{name: "empty secret", secret: nil, parts: 3, threshold: 2},
+		{name: "one part", secret: []byte("s"), parts: 1, threshold: 1},
+		{name: "too many parts", secret: []byte("s"), parts: 256, threshold: 2},
+		{name: "threshold above parts", secret: []byte("s"), parts: 3, threshold: 4},
+		{name: "threshold of one", secret: []byte("s"), parts: 3, threshold: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Split(tt.secret, tt.parts, tt.threshold, nil); err == nil {
+				t.Error("Split() expected error")
+			}
+		})
+	}
+}
+
+func TestCombineErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		shares [][]byte
+	}{
+		{name: "single share", shares: [][]byte{{1, 1}}},
+		{name: "length mismatch", shares: [][]byte{{1, 2, 1}, {1, 2}}},
+		{name: "duplicate x", shares: [][]byte{{1, 1}, {2, 1}}},
+		{name: "zero x", shares: [][]byte{{1, 0}, {2, 1}}},
+		{name: "too short", shares: [][]byte{{1}, {2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Combine(tt.shares); err == nil {
+				t.Error("Combine() expected error")
+			}
+		})
+	}
+}
+
+func TestFieldArithmetic(t *testing.T) {
+	for a := 0; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			if got := div(mul(byte(a), byte(b)), byte(b)); got != byte(a) {
+				t.Fatalf("div(mul(%d, %d), %d) = %d", a, b, b, got)
+			}
+		}
+	}
+	// 0x53 * 0xca = 0x01 in the AES field
+	if got := mul(0x53, 0xca); got != 0x01 {
+		t.Errorf("mul(0x53, 0xca) = %#x, want 0x01", got)
+	}
+}
@@ -26,6 +26,8 @@ func FuncMap() template.FuncMap {
 	funcMap["compact"] = RemoveHyphens // Deprecated: use removeHyphens instead
 	funcMap["toBinary"] = ToBinary
 	funcMap["toHex"] = ToHex
+	funcMap["wgInterface"] = WireGuardInterface
+	funcMap["wgPeer"] = WireGuardPeer
 	return funcMap
 }
 
@@ -91,3 +93,89 @@ func ToHex(i interface{}) (string, error) {
 	}
 	return fmt.Sprintf("%x", num), nil
 }
+
+// wireGuardField maps a template data key to a WireGuard config key
+type wireGuardField struct {
+	key  string
+	name string
+}
+
+var (
+	wireGuardInterfaceFields = []wireGuardField{
+		{"private_key", "PrivateKey"},
+		{"address", "Address"},
+		{"listen_port", "ListenPort"},
+		{"dns", "DNS"},
+		{"mtu", "MTU"},
+		{"table", "Table"},
+		{"fw_mark", "FwMark"},
+	}
+	wireGuardPeerFields = []wireGuardField{
+		{"public_key", "PublicKey"},
+		{"preshared_key", "PresharedKey"},
+		{"allowed_ips", "AllowedIPs"},
+		{"endpoint", "Endpoint"},
+		{"persistent_keepalive", "PersistentKeepalive"},
+	}
+)
+
+// WireGuardInterface renders an [Interface] section from a map such as a wireguard_keypair output merged with
+// address/listen_port/dns settings. Keys that are not WireGuard settings are ignored.
+func WireGuardInterface(values interface{}) (string, error) {
+	return renderWireGuardSection("Interface", wireGuardInterfaceFields, "private_key", values)
+}
+
+// WireGuardPeer renders a [Peer] section from a map with public_key, preshared_key, allowed_ips,
+// endpoint and persistent_keepalive. Keys that are not WireGuard settings are ignored.
+func WireGuardPeer(values interface{}) (string, error) {
+	return renderWireGuardSection("Peer", wireGuardPeerFields, "public_key", values)
+}
+
+func renderWireGuardSection(section string, fields []wireGuardField, required string, values interface{}) (string, error) {
+	var data map[string]interface{}
+	switch v := values.(type) {
+	case map[string]interface{}:
+		data = v
+	case map[string]string:
+		data = make(map[string]interface{}, len(v))
+		for k, val := range v {
+			data[k] = val
+		}
+	default:
+		return "", fmt.Errorf("wireguard %s values must be a map, got %T", section, values)
+	}
+
+	if s, _ := data[required].(string); s == "" {
+		return "", fmt.Errorf("wireguard %s requires %s", section, required)
+	}
+
+	var b strings.Builder
+	b.WriteString("[" + section + "]\n")
+	for _, field := range fields {
+		raw, ok := data[field.key]
+		if !ok || raw == nil {
+			continue
+		}
+		var value string
+		switch v := raw.(type) {
+		case []interface{}:
+			parts := make([]string, len(v))
+			for i, item := range v {
+				parts[i] = fmt.Sprint(item)
+			}
+			value = strings.Join(parts, ", ")
+		case []string:
+			value = strings.Join(v, ", ")
+		default:
+			value = fmt.Sprint(v)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return "", fmt.Errorf("wireguard %s value for %s must not contain newlines", section, field.key)
+		}
+		if value == "" {
+			continue
+		}
+		b.WriteString(field.name + " = " + value + "\n")
+	}
+	return b.String(), nil
+}
@@ -114,3 +114,43 @@ func TestToHex(t *testing.T) {
 		}
 	}
 }
+
+func TestWireGuardSections(t *testing.T) {
+	iface, err := WireGuardInterface(map[string]interface{}{
+		"private_key": "cHJpdmF0ZQ==",
+		"public_key":  "ignored",
+		"address":     []interface{}{"10.0.0.1/24", "fd00::1/64"},
+		"listen_port": 51820,
+	})
+	if err != nil {
+		t.Fatalf("WireGuardInterface() error = %v", err)
+	}
+	expected := "[Interface]\nPrivateKey = cHJpdmF0ZQ==\nAddress = 10.0.0.1/24, fd00::1/64\nListenPort = 51820\n"
+	if iface != expected {
+		t.Errorf("WireGuardInterface() = %q, want %q", iface, expected)
+	}
+
+	peer, err := WireGuardPeer(map[string]string{
+		"public_key":    "cHVibGlj",
+		"preshared_key": "cHNr",
+		"allowed_ips":   "10.0.0.2/32",
+		"endpoint":      "vpn.example.com:51820",
+	})
+	if err != nil {
+		t.Fatalf("WireGuardPeer() error = %v", err)
+	}
+	expected = "[Peer]\nPublicKey = cHVibGlj\nPresharedKey = cHNr\nAllowedIPs = 10.0.0.2/32\nEndpoint = vpn.example.com:51820\n"
+	if peer != expected {
+		t.Errorf("WireGuardPeer() = %q, want %q", peer, expected)
+	}
+
+	if _, err := WireGuardPeer(map[string]interface{}{"endpoint": "vpn.example.com:51820"}); err == nil {
+		t.Error("WireGuardPeer() expected error without public_key")
+	}
+	if _, err := WireGuardInterface(map[string]interface{}{"private_key": "a\n[Peer]"}); err == nil {
+		t.Error("WireGuardInterface() expected error for newline in value")
+	}
+	if _, err := WireGuardInterface("not a map"); err == nil {
+		t.Error("WireGuardInterface() expected error for non-map values")
+	}
+}
@@ -21,6 +21,12 @@ var (
 	separatorPattern = regexp.MustCompile(`[:=]`)
 	yamlPattern      = regexp.MustCompile(`^\s*[a-zA-Z_][a-zA-Z0-9_-]*\s*:\s*.+$`)
 
+	// Key material that spans lines or has no key/value separator is masked before format detection
+	secretBlockPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]+-----.*?-----END [A-Z0-9 ]+-----`),
+		regexp.MustCompile(`AGE-SECRET-KEY-1[0-9A-Z]+`),
+	}
+
 	dangerousPatterns = []*regexp.Regexp{
 		regexp.MustCompile(`{{\.}}`),
 		regexp.MustCompile(`{{\s*range\s+\.\s*}}`),
@@ -90,6 +96,10 @@ func ValidateGeneratorConfigs(configs []secretsantav1alpha1.GeneratorConfig) err
 }
 
 func MaskSensitiveData(data string) string {
+	for _, re := range secretBlockPatterns {
+		data = re.ReplaceAllString(data, "<MASKED>")
+	}
+
 	if strings.HasPrefix(strings.TrimSpace(data), "{") {
 		return maskJSONData(data)
 	}
@@ -133,13 +143,32 @@ func maskJSONData(data string) string {
 
 func maskYAMLData(data string) string {
 	lines := strings.Split(data, "\n")
+	blockIndent := -1
 	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		indent := len(line) - len(trimmed)
+
+		// Mask continuation lines of block scalars (key: | or key: >)
+		if blockIndent >= 0 {
+			if trimmed == "" {
+				continue
+			}
+			if indent > blockIndent {
+				lines[i] = line[:indent] + "<MASKED>"
+				continue
+			}
+			blockIndent = -1
+		}
+
 		if strings.Contains(line, ":") && !strings.HasPrefix(strings.TrimSpace(line), "#") {
 			parts := strings.SplitN(line, ":", 2)
 			if len(parts) == 2 && strings.TrimSpace(parts[1]) != "" {
-				trimmed := strings.TrimLeft(line, " \t")
-				indent := line[:len(line)-len(trimmed)]
-				lines[i] = fmt.Sprintf("%s%s: <MASKED>", indent, strings.TrimSpace(parts[0]))
+				value := strings.TrimSpace(parts[1])
+				if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+					blockIndent = indent
+					continue
+				}
+				lines[i] = fmt.Sprintf("%s%s: <MASKED>", line[:indent], strings.TrimSpace(parts[0]))
 			}
 		}
 	}